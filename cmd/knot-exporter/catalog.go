@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/collector"
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot/scripted"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Output formats of the catalog subcommand
+const (
+	catalogFormatMarkdown = "markdown"
+	catalogFormatJSON     = "json"
+)
+
+// catalogOptions are the flags of the catalog subcommand
+type catalogOptions struct {
+	format string
+	discoveryOptions
+}
+
+// register adds the catalog flags to fs, resetting earlier values
+func (d *catalogOptions) register(fs *flag.FlagSet) {
+	*d = catalogOptions{}
+	fs.StringVar(&d.format, "format", catalogFormatMarkdown, "output format: markdown or json")
+	d.discoveryOptions.register(fs)
+}
+
+// discoveryOptions are the flags selecting where metrics that are only known
+// once Knot DNS reported them are discovered from
+type discoveryOptions struct {
+	discover bool
+	recorded string
+}
+
+// register adds the discovery flags to fs, resetting earlier values
+func (d *discoveryOptions) register(fs *flag.FlagSet) {
+	*d = discoveryOptions{}
+	fs.BoolVar(&d.discover, "discover", false, "add the metrics a collection from Knot DNS exports")
+	fs.StringVar(&d.recorded, "recorded", "", "add the metrics a collection exports from control records saved by dump -raw -format json")
+}
+
+// validate checks that the discovery flags don't conflict
+func (d *discoveryOptions) validate() error {
+	if d.discover && d.recorded != "" {
+		return errors.New("-discover and -recorded are mutually exclusive")
+	}
+	return nil
+}
+
+// enabled reports whether metrics are discovered
+func (d *discoveryOptions) enabled() bool {
+	return d.discover || d.recorded != ""
+}
+
+// controlFactory returns the control connections discovery uses, nil for
+// connections to Knot DNS
+func (d *discoveryOptions) controlFactory() (func() collector.KnotCtlInterface, error) {
+	if d.recorded == "" {
+		return nil, nil
+	}
+	records, err := loadRecords(d.recorded)
+	if err != nil {
+		return nil, err
+	}
+	server := replayServer(records)
+	return func() collector.KnotCtlInterface { return server.NewCtl() }, nil
+}
+
+// runCatalog prints the metrics the exporter knows up front, and optionally
+// those discovered from Knot DNS or a recording of its responses. It returns
+// the exit status.
+func runCatalog(args []string, stdout, stderr io.Writer) int {
+	var d catalogOptions
+	o, status := parseSubcommand("catalog", args, stderr, d.register)
+	if o == nil {
+		return status
+	}
+	if d.format != catalogFormatMarkdown && d.format != catalogFormatJSON {
+		fmt.Fprintf(stderr, "catalog: unknown format %q (expected %q or %q)\n", d.format, catalogFormatMarkdown, catalogFormatJSON)
+		return 2
+	}
+	if err := d.validate(); err != nil {
+		fmt.Fprintf(stderr, "catalog: %v\n", err)
+		return 2
+	}
+
+	newCtl, err := d.controlFactory()
+	if err != nil {
+		fmt.Fprintf(stderr, "catalog: %v\n", err)
+		return 1
+	}
+	infos, err := buildCatalog(&o.collectorOptions, d.enabled(), newCtl)
+	if err == nil {
+		err = writeCatalog(stdout, infos, d.format)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "catalog: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// buildCatalog returns the static metrics of the options and, with discover,
+// those exported by a collection of each enabled collector. Collections use
+// control connections from newCtl, if set.
+func buildCatalog(o *collectorOptions, discover bool, newCtl func() collector.KnotCtlInterface) ([]collector.MetricInfo, error) {
+	var extra []collector.Option
+	if newCtl != nil {
+		extra = append(extra, collector.WithControlFactory(newCtl))
+	}
+
+	c, err := o.newCollector(extra...)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := c.StaticCatalog()
+	if err != nil {
+		return nil, err
+	}
+	if !discover {
+		return infos, nil
+	}
+
+	// Collectors run one at a time so that metrics can be attributed
+	catalogs := [][]collector.MetricInfo{infos}
+	for _, name := range o.enabledCollectors() {
+		single := *o
+		if err := single.selectCollectors([]string{name}); err != nil {
+			return nil, err
+		}
+		c, err := single.newCollector(extra...)
+		if err != nil {
+			return nil, err
+		}
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(c)
+		families, err := registry.Gather()
+		if err != nil {
+			return nil, err
+		}
+		if err := c.LastError(); err != nil {
+			return nil, fmt.Errorf("collection failed: %v", err)
+		}
+		catalogs = append(catalogs, collector.FamilyCatalog(families, name))
+	}
+	return collector.MergeCatalogs(catalogs...), nil
+}
+
+// writeCatalog prints a catalog as a markdown table or a JSON array
+func writeCatalog(w io.Writer, infos []collector.MetricInfo, format string) error {
+	if format == catalogFormatJSON {
+		return writeJSON(w, infos)
+	}
+
+	var b strings.Builder
+	b.WriteString("| Metric | Type | Labels | Collector | Help |\n")
+	b.WriteString("|--------|------|--------|-----------|------|\n")
+	for _, info := range infos {
+		labels := make([]string, len(info.Labels))
+		for i, label := range info.Labels {
+			labels[i] = "`" + label + "`"
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s |\n", info.Name, info.Type,
+			strings.Join(labels, ", "), info.Collector, strings.ReplaceAll(info.Help, "|", `\|`))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// loadRecords reads control records saved by dump -raw -format json
+func loadRecords(path string) ([]rawRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %v", err)
+	}
+	defer f.Close()
+
+	var records []rawRecord
+	dec := json.NewDecoder(f)
+	for {
+		var record rawRecord
+		err := dec.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse recording %s: %v", path, err)
+		}
+		records = append(records, record)
+	}
+}
+
+// replayServer returns a control server answering commands with the
+// recorded records of the command
+func replayServer(records []rawRecord) *scripted.Server {
+	server := &scripted.Server{Responses: make(map[string][]scripted.Record)}
+	for _, record := range records {
+		server.Responses[record.Command] = append(server.Responses[record.Command], scripted.Record{
+			Unit:    record.Type,
+			Zone:    record.Zone,
+			Section: record.Section,
+			Item:    record.Item,
+			ID:      record.ID,
+			Type:    record.RType,
+			Data:    record.Data,
+		})
+	}
+	return server
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/collector"
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRecording is a recording in the format of dump -raw -format json
+const testRecording = `{"command":"stats","type":"data","section":"server","item":"zone-count","data":"2"}
+{"command":"zone-stats","type":"data","zone":"example.com.","section":"mod-stats","item":"request-protocol","id":"udp4","data":"7"}
+{"command":"zone-status","type":"data","zone":"example.com."}
+{"command":"zone-status","type":"extra","zone":"example.com.","rtype":"serial","data":"2024010101"}
+{"command":"zone-status","type":"data","zone":"example.org."}
+{"command":"zone-status","type":"extra","zone":"example.org.","rtype":"serial","data":"2024020202"}
+`
+
+// TestRunCatalogUsage tests rejection of invalid catalog flags
+func TestRunCatalogUsage(t *testing.T) {
+	restoreLogging(t)
+	for _, args := range [][]string{
+		{"-format", "text"},
+		{"-discover", "-recorded", "knot.json"},
+		{"-no-such-flag"},
+	} {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, runCatalog(args, &stdout, &stderr), args)
+		assert.Contains(t, stderr.String(), "catalog: ")
+		assert.Empty(t, stdout.String())
+	}
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 1, runCatalog([]string{"-recorded", "/nonexistent/knot.json"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "failed to open recording")
+}
+
+// TestRunCatalog tests the static catalog in markdown
+func TestRunCatalog(t *testing.T) {
+	restoreLogging(t)
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, runCatalog(nil, &stdout, &stderr), stderr.String())
+	assert.Contains(t, stdout.String(), "| Metric | Type | Labels | Collector | Help |\n")
+	assert.Contains(t, stdout.String(), "| `knot_zone_serial` | gauge | `zone` | zone-serial | Zone serial number from Knot DNS |\n")
+	assert.NotContains(t, stdout.String(), "knot_stats_")
+}
+
+// TestRunCatalogRecorded tests discovery of metrics from a recording
+func TestRunCatalogRecorded(t *testing.T) {
+	restoreLogging(t)
+	path := filepath.Join(t.TempDir(), "knot.json")
+	require.NoError(t, os.WriteFile(path, []byte(testRecording), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	status := runCatalog([]string{"-recorded", path, "-format", "json", "-no-meminfo"}, &stdout, &stderr)
+	require.Equal(t, 0, status, stderr.String())
+
+	var infos []collector.MetricInfo
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &infos))
+	byName := make(map[string]collector.MetricInfo)
+	for _, info := range infos {
+		byName[info.Name] = info
+	}
+	assert.Equal(t, collector.MetricInfo{
+		Name:      "knot_zone_stats_request_protocol_total",
+		Type:      "counter",
+		Help:      "Zone statistic: request-protocol",
+		Labels:    []string{"module", "protocol", "zone"},
+		Collector: "zone-stats",
+		Dynamic:   true,
+	}, byName["knot_zone_stats_request_protocol_total"])
+	assert.Equal(t, "global-stats", byName["knot_stats_zone_count"].Collector)
+	assert.False(t, byName["knot_zone_serial"].Dynamic)
+}
+
+// TestReplayCtl tests replaying recorded records
+func TestReplayCtl(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "knot.json")
+	require.NoError(t, os.WriteFile(path, []byte(testRecording), 0o644))
+	records, err := loadRecords(path)
+	require.NoError(t, err)
+	require.Len(t, records, 6)
+
+	server := replayServer(records)
+	ctl := server.NewCtl()
+	require.NoError(t, ctl.SendZoneCommand("zone-status", "", []string{"Example.ORG"}))
+	dataType, data, err := ctl.ReceiveResponse()
+	require.NoError(t, err)
+	assert.Equal(t, libknot.CtlTypeData, dataType)
+	assert.Equal(t, "example.org.", data.Zone)
+	dataType, data, err = ctl.ReceiveResponse()
+	require.NoError(t, err)
+	assert.Equal(t, libknot.CtlTypeExtra, dataType)
+	assert.Equal(t, &libknot.CtlData{Zone: "example.org.", Type: "serial", Data: "2024020202"}, data)
+	dataType, _, err = ctl.ReceiveResponse()
+	require.NoError(t, err)
+	assert.Equal(t, libknot.CtlTypeBlock, dataType)
+
+	ctl = server.NewCtl()
+	require.NoError(t, ctl.SendCommand("stats"))
+	_, data, err = ctl.ReceiveResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "zone-count", data.Item)
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+	_, err = loadRecords(path)
+	assert.ErrorContains(t, err, "failed to parse recording")
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/collector"
+	"github.com/CZ-NIC/knot-exporter/pkg/web"
+)
+
+// checkResult is the outcome of a single check
+type checkResult struct {
+	name    string
+	detail  string // Shown on success
+	warning string // Problem that doesn't fail the check
+	err     error
+}
+
+// runCheck validates the configuration, tests the connection to Knot DNS
+// and runs every enabled collector once, reporting each step. It returns the
+// exit status.
+func runCheck(args []string, stdout, stderr io.Writer) int {
+	o, status := parseSubcommand("check", args, stderr)
+	if o == nil {
+		return status
+	}
+
+	for _, result := range runChecks(o) {
+		if result.err != nil {
+			fmt.Fprintf(stdout, "FAIL  %s: %v\n", result.name, result.err)
+			status = 1
+		} else if result.warning != "" {
+			fmt.Fprintf(stdout, "WARN  %s: %s\n", result.name, result.warning)
+		} else if result.detail != "" {
+			fmt.Fprintf(stdout, "OK    %s: %s\n", result.name, result.detail)
+		} else {
+			fmt.Fprintf(stdout, "OK    %s\n", result.name)
+		}
+	}
+	return status
+}
+
+// runChecks runs the checks of the options. The collectors only run when
+// Knot DNS is reachable.
+func runChecks(o *options) []checkResult {
+	var results []checkResult
+
+	// The listen addresses are only used when serving HTTP
+	if o.textfilePath == "" && o.pushURL == "" && !o.otlpOnly && !o.webSystemdSocket {
+		for _, addr := range o.webListenAddrs {
+			name := "listen address " + addr
+			if _, ok := unixSocketPath(addr); !ok {
+				name = "listen address " + net.JoinHostPort(addr, strconv.Itoa(o.webListenPort))
+			}
+			results = append(results, checkListenAddress(name, o.knotSocketPath, addr, o.webListenPort))
+		}
+	}
+
+	if o.webConfigFile != "" {
+		_, err := web.LoadConfig(o.webConfigFile)
+		results = append(results, checkResult{name: "web config", detail: o.webConfigFile, err: err})
+	}
+
+	c, err := o.newCollector()
+	results = append(results, checkResult{name: "collector configuration", err: err})
+
+	err = testKnotConnection(o.knotSocketPath, o.knotSocketTimeout)
+	results = append(results, checkResult{name: "knot connection", detail: o.knotSocketPath, err: err})
+	if err != nil || c == nil {
+		return results
+	}
+
+	c.Warm()
+	for _, status := range c.CollectorStatus() {
+		result := checkResult{
+			name:   "collector " + status.Name,
+			detail: fmt.Sprintf("%v", time.Duration(status.Duration*float64(time.Second)).Round(time.Millisecond)),
+		}
+		if !status.Healthy {
+			result.err = fmt.Errorf("%s", status.LastError)
+		}
+		results = append(results, result)
+	}
+	if !o.noMeminfo {
+		results = append(results, checkMemInfo(c))
+	}
+	return results
+}
+
+// checkMemInfo checks that the meminfo collector finds knotd, which it only
+// logs during collections
+func checkMemInfo(c *collector.KnotCollector) checkResult {
+	pid, err := c.CheckMemInfo()
+	return checkResult{name: "collector meminfo", detail: fmt.Sprintf("knotd PID %d", pid), err: err}
+}
+
+// checkListenAddress validates a listen address like the exporter does at
+// start. An address in use is only a warning, as it's most likely taken by
+// the running exporter.
+func checkListenAddress(name, sockPath, addr string, port int) checkResult {
+	err := validateConfig(sockPath, addr, port)
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return checkResult{name: name, warning: fmt.Sprintf("%v, the exporter may already be running", err)}
+	}
+	return checkResult{name: name, err: err}
+}
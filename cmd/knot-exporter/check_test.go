@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRunChecks tests the checks without a running Knot DNS
+func TestRunChecks(t *testing.T) {
+	o, err := parseOptions([]string{
+		"-knot-socket-path", "/nonexistent/knot.sock",
+		"-web-listen-addr", "127.0.0.1",
+		"-web-listen-addr", "unix:/nonexistent/exporter.sock",
+	}, flag.ContinueOnError)
+	require.NoError(t, err)
+
+	results := runChecks(o)
+	var names []string
+	for _, result := range results {
+		names = append(names, result.name)
+	}
+	// Collectors don't run without a connection
+	assert.Equal(t, []string{
+		"listen address 127.0.0.1:9433",
+		"listen address unix:/nonexistent/exporter.sock",
+		"collector configuration",
+		"knot connection",
+	}, names)
+	assert.ErrorContains(t, results[0].err, "knot socket does not exist")
+	assert.NoError(t, results[2].err)
+	assert.ErrorContains(t, results[3].err, "failed to connect to knot socket")
+
+	// The listen addresses don't matter without HTTP
+	o.textfilePath = "/tmp/knot.prom"
+	o.zoneInclude = stringList{"regex:("}
+	results = runChecks(o)
+	require.Len(t, results, 2)
+	assert.ErrorContains(t, results[0].err, "invalid zone filter")
+}
+
+// TestRunCheck tests the report and exit status of check
+func TestRunCheck(t *testing.T) {
+	restoreLogging(t)
+
+	var stdout, stderr bytes.Buffer
+	status := runCheck([]string{"-knot-socket-path", "/nonexistent/knot.sock", "-web-systemd-socket"}, &stdout, &stderr)
+	assert.Equal(t, 1, status)
+	assert.Equal(t, "OK    collector configuration\n"+
+		"FAIL  knot connection: failed to connect to knot socket: "+connectError(t)+"\n", stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, 2, runCheck([]string{"-log-format", "xml"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "check: unknown log format")
+}
+
+// TestCheckListenAddressInUse tests that an address in use is a warning
+func TestCheckListenAddressInUse(t *testing.T) {
+	restoreLogging(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+	sockPath := filepath.Join(t.TempDir(), "knot.sock")
+	require.NoError(t, os.WriteFile(sockPath, nil, 0o644))
+
+	result := checkListenAddress("listen address", sockPath, "127.0.0.1", port)
+	assert.NoError(t, result.err)
+	assert.Contains(t, result.warning, "the exporter may already be running")
+
+	var stdout, stderr bytes.Buffer
+	runCheck([]string{"-knot-socket-path", sockPath, "-web-listen-addr", "127.0.0.1",
+		"-web-listen-port", strconv.Itoa(port)}, &stdout, &stderr)
+	assert.Contains(t, stdout.String(), fmt.Sprintf("WARN  listen address 127.0.0.1:%d: cannot bind to 127.0.0.1:%d", port, port))
+
+	// Other failures still fail the check
+	result = checkListenAddress("listen address", sockPath, "192.0.2.1", port)
+	assert.Error(t, result.err)
+	assert.Empty(t, result.warning)
+}
+
+// TestCheckMemInfo tests that the check reports whether meminfo finds knotd
+func TestCheckMemInfo(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "knot.pid")
+	require.NoError(t, os.WriteFile(pidFile, []byte("1234\n"), 0o644))
+	procRoot := filepath.Join("..", "..", "pkg", "collector", "testdata", "proc")
+
+	o, err := parseOptions([]string{"-knot-pid-file", pidFile, "-proc-root", procRoot}, flag.ContinueOnError)
+	require.NoError(t, err)
+	c, err := o.newCollector()
+	require.NoError(t, err)
+	result := checkMemInfo(c)
+	assert.NoError(t, result.err)
+	assert.Equal(t, "knotd PID 1234", result.detail)
+
+	require.NoError(t, os.WriteFile(pidFile, []byte("99999\n"), 0o644))
+	assert.ErrorContains(t, checkMemInfo(c).err, "no memory usage of process 99999")
+
+	require.NoError(t, os.Remove(pidFile))
+	assert.ErrorContains(t, checkMemInfo(c).err, "failed to read PID file")
+}
+
+// connectError returns the error of connecting to a missing socket
+func connectError(t *testing.T) string {
+	err := testKnotConnection("/nonexistent/knot.sock", 1000)
+	require.Error(t, err)
+	const prefix = "failed to connect to knot socket: "
+	return err.Error()[len(prefix):]
+}
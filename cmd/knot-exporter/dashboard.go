@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/collector"
+	"github.com/CZ-NIC/knot-exporter/pkg/dashboard"
+)
+
+// dashboardOptions are the flags of the dashboard subcommand
+type dashboardOptions struct {
+	title string
+	uid   string
+	discoveryOptions
+}
+
+// register adds the dashboard flags to fs, resetting earlier values
+func (d *dashboardOptions) register(fs *flag.FlagSet) {
+	*d = dashboardOptions{}
+	fs.StringVar(&d.title, "title", "Knot DNS", "title of the dashboard")
+	fs.StringVar(&d.uid, "uid", "knot-exporter", "unique identifier of the dashboard in Grafana")
+	d.discoveryOptions.register(fs)
+}
+
+// runDashboard prints a Grafana dashboard for the metrics of the enabled
+// collectors. It returns the exit status.
+func runDashboard(args []string, stdout, stderr io.Writer) int {
+	var d dashboardOptions
+	o, status := parseSubcommand("dashboard", args, stderr, d.register)
+	if o == nil {
+		return status
+	}
+	if err := d.validate(); err != nil {
+		fmt.Fprintf(stderr, "dashboard: %v\n", err)
+		return 2
+	}
+
+	newCtl, err := d.controlFactory()
+	if err != nil {
+		fmt.Fprintf(stderr, "dashboard: %v\n", err)
+		return 1
+	}
+	infos, err := buildCatalog(&o.collectorOptions, d.enabled(), newCtl)
+	if err == nil {
+		infos = enabledMetrics(infos, o.enabledCollectors())
+		err = writeJSON(stdout, dashboard.Generate(infos, dashboard.Options{Title: d.title, UID: d.uid}))
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "dashboard: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// enabledMetrics returns the metrics of the exporter itself and of the given
+// collectors
+func enabledMetrics(infos []collector.MetricInfo, collectors []string) []collector.MetricInfo {
+	enabled := map[string]bool{"": true}
+	for _, name := range collectors {
+		enabled[name] = true
+	}
+	var out []collector.MetricInfo
+	for _, info := range infos {
+		if enabled[info.Collector] {
+			out = append(out, info)
+		}
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/dashboard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRunDashboardUsage tests rejection of invalid dashboard flags
+func TestRunDashboardUsage(t *testing.T) {
+	restoreLogging(t)
+	for _, args := range [][]string{
+		{"-discover", "-recorded", "knot.json"},
+		{"-no-such-flag"},
+	} {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, runDashboard(args, &stdout, &stderr), args)
+		assert.Contains(t, stderr.String(), "dashboard: ")
+		assert.Empty(t, stdout.String())
+	}
+}
+
+// TestRunDashboard tests that the dashboard only shows the metrics of the
+// enabled collectors
+func TestRunDashboard(t *testing.T) {
+	restoreLogging(t)
+	path := filepath.Join(t.TempDir(), "knot.json")
+	require.NoError(t, os.WriteFile(path, []byte(testRecording), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	status := runDashboard([]string{"-recorded", path, "-no-meminfo", "-no-zone-stats", "-title", "ns1"}, &stdout, &stderr)
+	require.Equal(t, 0, status, stderr.String())
+
+	var d dashboard.Dashboard
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &d))
+	assert.Equal(t, "ns1", d.Title)
+	var exprs []string
+	for _, p := range d.Panels {
+		for _, target := range p.Targets {
+			exprs = append(exprs, target.Expr)
+		}
+	}
+	all := strings.Join(exprs, "\n")
+	assert.Contains(t, all, "knot_stats_zone_count{")
+	assert.Contains(t, all, "knot_zone_serial{")
+	assert.NotContains(t, all, "knot_memory_usage_bytes")
+	assert.NotContains(t, all, "knot_zone_stats_")
+	assert.NotContains(t, all, "knot_zone_refresh_seconds")
+}
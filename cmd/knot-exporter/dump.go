@@ -0,0 +1,348 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/collector"
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot"
+	"github.com/CZ-NIC/knot-exporter/pkg/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Output formats of the dump subcommand
+const (
+	dumpFormatText = "text"
+	dumpFormatJSON = "json"
+)
+
+// collectorNames are the collectors selectable by -collector
+var collectorNames = []string{"meminfo", "global-stats", "zone-status", "zone-serial", "zone-stats", "zone-timers"}
+
+// rawCommand is the control command a collector runs
+type rawCommand struct {
+	cmd   string
+	rtype string
+	zone  bool // Whether the command takes zones
+}
+
+// rawCommands are the control commands of the collectors, meminfo has none
+var rawCommands = map[string]rawCommand{
+	"global-stats": {cmd: "stats"},
+	"zone-status":  {cmd: "zone-status", zone: true},
+	"zone-serial":  {cmd: "zone-status", zone: true},
+	"zone-stats":   {cmd: "zone-stats", zone: true},
+	"zone-timers":  {cmd: "zone-read", rtype: "SOA", zone: true},
+}
+
+// dumpOptions are the flags of the dump subcommand
+type dumpOptions struct {
+	collectors stringList
+	format     string
+	raw        bool
+}
+
+// register adds the dump flags to fs, resetting earlier values
+func (d *dumpOptions) register(fs *flag.FlagSet) {
+	*d = dumpOptions{}
+	fs.Var(&d.collectors, "collector", "run only this collector: "+strings.Join(collectorNames, ", ")+" (repeatable, default the enabled ones)")
+	fs.StringVar(&d.format, "format", dumpFormatText, "output format: text or json")
+	fs.BoolVar(&d.raw, "raw", false, "print the control records of Knot DNS instead of metrics")
+}
+
+// selectCollectors enables only the named collectors
+func (o *collectorOptions) selectCollectors(names []string) error {
+	o.noMeminfo, o.noGlobalStats, o.noZoneStatus, o.noZoneSerial, o.noZoneStats = true, true, true, true, true
+	o.zoneTimers = false
+	for _, name := range names {
+		switch name {
+		case "meminfo":
+			o.noMeminfo = false
+		case "global-stats":
+			o.noGlobalStats = false
+		case "zone-status":
+			o.noZoneStatus = false
+		case "zone-serial":
+			o.noZoneSerial = false
+		case "zone-stats":
+			o.noZoneStats = false
+		case "zone-timers":
+			o.zoneTimers = true
+		default:
+			return fmt.Errorf("unknown collector %q (expected one of %s)", name, strings.Join(collectorNames, ", "))
+		}
+	}
+	return nil
+}
+
+// enabledCollectors returns the names of the enabled collectors
+func (o *collectorOptions) enabledCollectors() []string {
+	enabled := map[string]bool{
+		"meminfo":      !o.noMeminfo,
+		"global-stats": !o.noGlobalStats,
+		"zone-status":  !o.noZoneStatus,
+		"zone-serial":  !o.noZoneSerial,
+		"zone-stats":   !o.noZoneStats,
+		"zone-timers":  o.zoneTimers,
+	}
+	var names []string
+	for _, name := range collectorNames {
+		if enabled[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseSubcommand parses the flags of a subcommand and sets up logging to
+// stderr. On failure, or when help was requested, the options are nil and the
+// exit status is returned.
+func parseSubcommand(name string, args []string, stderr io.Writer, extra ...func(*flag.FlagSet)) (*options, int) {
+	o, err := parseOptions(args, flag.ContinueOnError, extra...)
+	if errors.Is(err, flag.ErrHelp) {
+		fs := newFlagSet(&options{}, flag.ContinueOnError)
+		for _, register := range extra {
+			register(fs)
+		}
+		fs.SetOutput(stderr)
+		fmt.Fprintf(stderr, "Usage: %s %s [flags]\n", os.Args[0], name)
+		fs.PrintDefaults()
+		return nil, 0
+	}
+	if err == nil {
+		err = logging.Setup(stderr, o.logFormat)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", name, err)
+		return nil, 2
+	}
+	logging.Level.Set(o.level())
+	return o, 0
+}
+
+// runDump runs the collectors once and prints their metrics or control
+// records. It returns the exit status.
+func runDump(args []string, stdout, stderr io.Writer) int {
+	var d dumpOptions
+	o, status := parseSubcommand("dump", args, stderr, d.register)
+	if o == nil {
+		return status
+	}
+	if d.format != dumpFormatText && d.format != dumpFormatJSON {
+		fmt.Fprintf(stderr, "dump: unknown format %q (expected %q or %q)\n", d.format, dumpFormatText, dumpFormatJSON)
+		return 2
+	}
+	if len(d.collectors) > 0 {
+		if err := o.selectCollectors(d.collectors); err != nil {
+			fmt.Fprintf(stderr, "dump: %v\n", err)
+			return 2
+		}
+	}
+
+	var err error
+	if d.raw {
+		err = dumpRaw(stdout, &o.collectorOptions, d.format)
+	} else {
+		err = dumpMetrics(stdout, &o.collectorOptions, d.format)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "dump: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// dumpMetrics runs a collection and prints the gathered metrics. The metrics
+// are printed even when some collectors failed.
+func dumpMetrics(w io.Writer, o *collectorOptions, format string) error {
+	c, err := o.newCollector()
+	if err != nil {
+		return err
+	}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	families, err := registry.Gather()
+	if err != nil {
+		return err
+	}
+	if err := writeMetrics(w, families, format); err != nil {
+		return err
+	}
+	if err := c.LastError(); err != nil {
+		return fmt.Errorf("collection failed: %v", err)
+	}
+	return nil
+}
+
+// writeMetrics prints metric families in the text exposition format, or as a
+// JSON array of metric families
+func writeMetrics(w io.Writer, families []*dto.MetricFamily, format string) error {
+	if format == dumpFormatText {
+		for _, family := range families {
+			if _, err := expfmt.MetricFamilyToText(w, family); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	out := make([]json.RawMessage, 0, len(families))
+	for _, family := range families {
+		data, err := protojson.Marshal(family)
+		if err != nil {
+			return err
+		}
+		out = append(out, data)
+	}
+	return writeJSON(w, out)
+}
+
+// writeJSON prints v as indented JSON
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// dumpRaw prints the control records of the commands of the enabled
+// collectors. Zone commands are restricted to the zones named by
+// -zone-include, if it only names zones.
+func dumpRaw(w io.Writer, o *collectorOptions, format string) error {
+	var zones []string
+	if len(o.zoneInclude) > 0 || len(o.zoneExclude) > 0 {
+		filter, err := collector.NewZoneFilter(o.zoneInclude, o.zoneExclude)
+		if err != nil {
+			return fmt.Errorf("invalid zone filter: %v", err)
+		}
+		zones, _ = filter.ExactZones()
+	}
+
+	done := make(map[rawCommand]bool)
+	for _, name := range o.enabledCollectors() {
+		command, ok := rawCommands[name]
+		if !ok || done[command] {
+			continue
+		}
+		done[command] = true
+
+		ctl, err := connectKnot(o.knotSocketPath, o.knotSocketTimeout)
+		if err != nil {
+			return err
+		}
+		err = dumpRecords(w, ctl, command, zones, format)
+		ctl.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", command.cmd, err)
+		}
+	}
+	return nil
+}
+
+// connectKnot opens a control connection to Knot DNS
+func connectKnot(sockPath string, timeout int) (collector.KnotCtlInterface, error) {
+	ctl := libknot.New()
+	if ctl == nil {
+		return nil, fmt.Errorf("failed to allocate knot control object")
+	}
+	ctl.SetTimeout(timeout)
+	if err := ctl.Connect(sockPath); err != nil {
+		ctl.Close()
+		return nil, fmt.Errorf("failed to connect to knot socket: %v", err)
+	}
+	return ctl, nil
+}
+
+// rawRecord is a control record as printed by dump -raw -format json
+type rawRecord struct {
+	Command string `json:"command"`
+	Type    string `json:"type"`
+	Zone    string `json:"zone,omitempty"`
+	Section string `json:"section,omitempty"`
+	Item    string `json:"item,omitempty"`
+	ID      string `json:"id,omitempty"`
+	RType   string `json:"rtype,omitempty"`
+	Data    string `json:"data,omitempty"`
+}
+
+// ctlTypeNames are the names of the control record types
+var ctlTypeNames = map[libknot.CtlType]string{
+	libknot.CtlTypeEnd:   "end",
+	libknot.CtlTypeData:  "data",
+	libknot.CtlTypeExtra: "extra",
+	libknot.CtlTypeBlock: "block",
+}
+
+// dumpRecords sends a command and prints the records of its response, one
+// per line
+func dumpRecords(w io.Writer, ctl collector.KnotCtlInterface, command rawCommand, zones []string, format string) error {
+	var err error
+	switch {
+	case command.zone && len(zones) > 0:
+		err = ctl.SendZoneCommand(command.cmd, command.rtype, zones)
+	case command.rtype != "":
+		err = ctl.SendCommandWithType(command.cmd, command.rtype)
+	default:
+		err = ctl.SendCommand(command.cmd)
+	}
+	if err != nil {
+		return err
+	}
+
+	for {
+		dataType, data, err := ctl.ReceiveResponse()
+		if err != nil {
+			return err
+		}
+		if dataType == libknot.CtlTypeBlock || dataType == libknot.CtlTypeEnd {
+			return nil
+		}
+
+		record := rawRecord{
+			Command: command.cmd,
+			Type:    ctlTypeNames[dataType],
+			Zone:    data.Zone,
+			Section: data.Section,
+			Item:    data.Item,
+			ID:      data.ID,
+			RType:   data.Type,
+			Data:    data.Data,
+		}
+		if format == dumpFormatJSON {
+			err = json.NewEncoder(w).Encode(record)
+		} else {
+			_, err = fmt.Fprintln(w, record.String())
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// String formats the record as logfmt-style fields
+func (r rawRecord) String() string {
+	fields := []string{r.Command, r.Type}
+	for _, field := range []struct{ key, value string }{
+		{"zone", r.Zone}, {"section", r.Section}, {"item", r.Item},
+		{"id", r.ID}, {"rtype", r.RType}, {"data", r.Data},
+	} {
+		if field.value == "" {
+			continue
+		}
+		value := field.value
+		if strings.ContainsAny(value, " \t\"=") {
+			value = strconv.Quote(value)
+		}
+		fields = append(fields, field.key+"="+value)
+	}
+	return strings.Join(fields, " ")
+}
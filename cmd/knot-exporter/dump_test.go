@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot/scripted"
+	"github.com/CZ-NIC/knot-exporter/pkg/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// restoreLogging restores the default logger changed by a subcommand
+func restoreLogging(t *testing.T) {
+	logger, level := slog.Default(), logging.Level.Level()
+	t.Cleanup(func() {
+		slog.SetDefault(logger)
+		logging.Level.Set(level)
+	})
+}
+
+// TestSelectCollectors tests the -collector flag of dump
+func TestSelectCollectors(t *testing.T) {
+	o, err := parseOptions([]string{"-no-zone-stats"}, flag.ContinueOnError)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"meminfo", "global-stats", "zone-status", "zone-serial"}, o.enabledCollectors())
+
+	require.NoError(t, o.selectCollectors([]string{"zone-stats", "zone-timers"}))
+	assert.Equal(t, []string{"zone-stats", "zone-timers"}, o.enabledCollectors())
+
+	assert.ErrorContains(t, o.selectCollectors([]string{"zone-list"}), `unknown collector "zone-list"`)
+}
+
+// TestRunDumpUsage tests rejection of invalid dump flags
+func TestRunDumpUsage(t *testing.T) {
+	restoreLogging(t)
+	for _, args := range [][]string{
+		{"-format", "xml"},
+		{"-collector", "zone-list"},
+		{"-no-such-flag"},
+	} {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, runDump(args, &stdout, &stderr), args)
+		assert.Contains(t, stderr.String(), "dump: ")
+		assert.Empty(t, stdout.String())
+	}
+}
+
+// TestRunDumpHelp tests the usage of dump
+func TestRunDumpHelp(t *testing.T) {
+	restoreLogging(t)
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, runDump([]string{"-h"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), " dump [flags]")
+	assert.Contains(t, stderr.String(), "-collector")
+	assert.Contains(t, stderr.String(), "-knot-socket-path")
+}
+
+// TestRunDumpConnectionFailure tests that a failed collection is reported
+func TestRunDumpConnectionFailure(t *testing.T) {
+	restoreLogging(t)
+	var stdout, stderr bytes.Buffer
+	status := runDump([]string{"-knot-socket-path", "/nonexistent/knot.sock", "-raw", "-collector", "zone-status"}, &stdout, &stderr)
+	assert.Equal(t, 1, status)
+	assert.Contains(t, stderr.String(), "failed to connect to knot socket")
+
+	stdout.Reset()
+	stderr.Reset()
+	status = runDump([]string{"-knot-socket-path", "/nonexistent/knot.sock", "-collector", "global-stats"}, &stdout, &stderr)
+	assert.Equal(t, 1, status)
+	assert.Contains(t, stderr.String(), "collection failed")
+	assert.Contains(t, stdout.String(), "knot_build_info")
+}
+
+// TestWriteMetrics tests the output formats of metrics
+func TestWriteMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "knot_zone_serial", Help: "Zone serial number"}, []string{"zone"})
+	gauge.WithLabelValues("example.com").Set(2024010101)
+	registry.MustRegister(gauge)
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeMetrics(&buf, families, dumpFormatText))
+	assert.Equal(t, `# HELP knot_zone_serial Zone serial number
+# TYPE knot_zone_serial gauge
+knot_zone_serial{zone="example.com"} 2.024010101e+09
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, writeMetrics(&buf, families, dumpFormatJSON))
+	var out []struct {
+		Name   string `json:"name"`
+		Type   string `json:"type"`
+		Metric []struct {
+			Gauge struct {
+				Value float64 `json:"value"`
+			} `json:"gauge"`
+		} `json:"metric"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	require.Len(t, out, 1)
+	assert.Equal(t, "knot_zone_serial", out[0].Name)
+	assert.Equal(t, "GAUGE", out[0].Type)
+	assert.Equal(t, 2024010101.0, out[0].Metric[0].Gauge.Value)
+}
+
+// TestDumpRecords tests printing of raw control records
+func TestDumpRecords(t *testing.T) {
+	server := &scripted.Server{Responses: map[string][]scripted.Record{
+		"zone-status": {
+			{Zone: "example.com."},
+			{Unit: "extra", Zone: "example.com.", Type: "serial", Data: "2024010101"},
+			{Unit: "extra", Zone: "example.com.", Type: "refresh", Data: "+1h28m44s"},
+			{Unit: "extra", Zone: "example.com.", Type: "freeze", Data: "no freeze"},
+		},
+		"stats": {{Unit: "extra", Section: "server", Item: "query.total", ID: "udp", Data: "10"}},
+	}}
+	var buf bytes.Buffer
+	require.NoError(t, dumpRecords(&buf, server.NewCtl(), rawCommands["zone-status"], nil, dumpFormatText))
+	assert.Equal(t, []scripted.Command{{Cmd: "zone-status"}}, server.Sent(""))
+	assert.Equal(t, `zone-status data zone=example.com.
+zone-status extra zone=example.com. rtype=serial data=2024010101
+zone-status extra zone=example.com. rtype=refresh data=+1h28m44s
+zone-status extra zone=example.com. rtype=freeze data="no freeze"
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, dumpRecords(&buf, server.NewCtl(), rawCommands["global-stats"], []string{"example.com."}, dumpFormatJSON))
+	assert.Equal(t, []scripted.Command{{Cmd: "stats"}}, server.Sent("stats"))
+	assert.JSONEq(t, `{"command":"stats","type":"extra","section":"server","item":"query.total","id":"udp","data":"10"}`, buf.String())
+
+	require.NoError(t, dumpRecords(&buf, server.NewCtl(), rawCommands["zone-timers"], []string{"example.com."}, dumpFormatJSON))
+	require.NoError(t, dumpRecords(&buf, server.NewCtl(), rawCommands["zone-timers"], nil, dumpFormatJSON))
+	assert.Equal(t, []scripted.Command{
+		{Cmd: "zone-read", Type: "SOA", Zones: []string{"example.com."}},
+		{Cmd: "zone-read", Type: "SOA"},
+	}, server.Sent("zone-read"))
+	assert.True(t, strings.HasSuffix(buf.String(), "\n"))
+}
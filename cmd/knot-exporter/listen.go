@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/web"
+	"github.com/coreos/go-systemd/v22/activation"
+)
+
+// unixPrefix marks listen addresses that are UNIX socket paths
+const unixPrefix = "unix:"
+
+// unixSocketPath returns the socket path of a unix:<path> listen address
+func unixSocketPath(addr string) (string, bool) {
+	return strings.CutPrefix(addr, unixPrefix)
+}
+
+// validateUnixSocket checks that a UNIX socket can be created at path
+func validateUnixSocket(path string) error {
+	if path == "" {
+		return errors.New("empty UNIX socket path")
+	}
+	dir, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("cannot create UNIX socket %s: %v", path, err)
+	}
+	if !dir.IsDir() {
+		return fmt.Errorf("cannot create UNIX socket %s: parent is not a directory", path)
+	}
+	return nil
+}
+
+// listenUnix listens on a UNIX socket at path with the given permissions. A
+// stale socket left by a previous run is replaced, other files are not.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("cannot listen on %s: file exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("cannot remove stale socket %s: %v", path, err)
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("cannot set permissions of %s: %v", path, err)
+	}
+	return l, nil
+}
+
+// listen opens a listener for every address: an IP address or host name
+// listened on at port, or unix:<path> for a UNIX socket. Already opened
+// listeners are closed when one fails.
+func listen(addrs []string, port int, socketMode os.FileMode) ([]net.Listener, error) {
+	var listeners []net.Listener
+	for _, addr := range addrs {
+		var l net.Listener
+		var err error
+		if path, ok := unixSocketPath(addr); ok {
+			l, err = listenUnix(path, socketMode)
+		} else {
+			l, err = net.Listen("tcp", net.JoinHostPort(addr, strconv.Itoa(port)))
+		}
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// systemdListeners returns the sockets passed by systemd socket activation
+func systemdListeners() ([]net.Listener, error) {
+	activated, err := activation.Listeners()
+	if err != nil {
+		return nil, err
+	}
+
+	// Sockets that aren't stream listeners are nil
+	var listeners []net.Listener
+	for _, l := range activated {
+		if l != nil {
+			listeners = append(listeners, l)
+		}
+	}
+	if len(listeners) == 0 {
+		return nil, errors.New("no listening sockets passed by systemd (is the service socket activated?)")
+	}
+	return listeners, nil
+}
+
+func closeListeners(listeners []net.Listener) {
+	for _, l := range listeners {
+		l.Close()
+	}
+}
+
+// listenerURL returns the base URL of the server on a listener, for logs
+func listenerURL(scheme string, l net.Listener) string {
+	if l.Addr().Network() == "unix" {
+		return fmt.Sprintf("%s+unix://%s", scheme, l.Addr().String())
+	}
+	return fmt.Sprintf("%s://%s", scheme, l.Addr().String())
+}
+
+// serve serves the server on all listeners until it is shut down or one of
+// them fails
+func serve(server *web.Server, listeners []net.Listener) error {
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			errs <- server.Serve(l)
+		}(l)
+	}
+
+	err := <-errs
+	if err != nil && err != http.ErrServerClosed {
+		slog.Error("Serving failed, stopping all listeners", "err", err)
+		server.Close()
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unixClient returns an HTTP client connecting to a UNIX socket
+func unixClient(path string) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+}
+
+// TestServeMultipleListeners tests serving on TCP and UNIX sockets at once
+func TestServeMultipleListeners(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "metrics.sock")
+	addrs := []string{"127.0.0.1", "unix:" + socket}
+	if l, err := net.Listen("tcp", "[::1]:0"); err == nil {
+		l.Close()
+		addrs = append(addrs, "::1")
+	}
+
+	listeners, err := listen(addrs, 0, 0o660)
+	require.NoError(t, err)
+	require.Len(t, listeners, len(addrs))
+
+	info, err := os.Stat(socket)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o660), info.Mode().Perm())
+
+	server, err := web.NewServer(&http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})}, "")
+	require.NoError(t, err)
+	done := make(chan error)
+	go func() { done <- serve(server, listeners) }()
+
+	for _, l := range listeners {
+		client, url := http.DefaultClient, "http://"+l.Addr().String()+"/"
+		if l.Addr().Network() == "unix" {
+			client, url = unixClient(socket), "http://localhost/"
+		}
+		resp, err := client.Get(url)
+		require.NoError(t, err, l.Addr())
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "ok", string(body))
+	}
+
+	require.NoError(t, server.Close())
+	assert.ErrorIs(t, <-done, http.ErrServerClosed)
+	_, err = os.Stat(socket)
+	assert.True(t, os.IsNotExist(err), "socket removed on close")
+}
+
+// TestListenUnixStale tests that a stale socket is replaced but a regular
+// file is left alone
+func TestListenUnixStale(t *testing.T) {
+	dir := t.TempDir()
+
+	stale := filepath.Join(dir, "stale.sock")
+	l, err := net.Listen("unix", stale)
+	require.NoError(t, err)
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+
+	l, err = listenUnix(stale, 0o600)
+	require.NoError(t, err)
+	l.Close()
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+	_, err = listenUnix(file, 0o600)
+	assert.ErrorContains(t, err, "not a socket")
+}
+
+// TestListenFailure tests that listeners are closed when one fails
+func TestListenFailure(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "metrics.sock")
+	_, err := listen([]string{"unix:" + socket, "unix:/nonexistent/metrics.sock"}, 0, 0o660)
+	assert.Error(t, err)
+	_, err = os.Stat(socket)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// TestValidateConfigUnixSocket tests validation of UNIX socket addresses
+func TestValidateConfigUnixSocket(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, validateConfig(dir, "unix:"+filepath.Join(dir, "metrics.sock"), 0))
+	assert.ErrorContains(t, validateConfig(dir, "unix:/nonexistent/metrics.sock", 0), "cannot create UNIX socket")
+	assert.ErrorContains(t, validateConfig(dir, "unix:", 0), "empty UNIX socket path")
+}
+
+// TestSystemdListenersNone tests the error without socket activation
+func TestSystemdListenersNone(t *testing.T) {
+	t.Setenv("LISTEN_PID", "")
+	t.Setenv("LISTEN_FDS", "")
+	_, err := systemdListeners()
+	assert.ErrorContains(t, err, "no listening sockets")
+}
@@ -4,19 +4,26 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"runtime"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/CZ-NIC/knot-exporter/pkg/api"
 	"github.com/CZ-NIC/knot-exporter/pkg/collector"
 	"github.com/CZ-NIC/knot-exporter/pkg/libknot"
-	"github.com/CZ-NIC/knot-exporter/pkg/utils"
+	"github.com/CZ-NIC/knot-exporter/pkg/logging"
+	"github.com/CZ-NIC/knot-exporter/pkg/otlp"
+	"github.com/CZ-NIC/knot-exporter/pkg/push"
+	"github.com/CZ-NIC/knot-exporter/pkg/web"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -29,6 +36,18 @@ var (
 	goVersion = runtime.Version()
 )
 
+// stringList is a flag.Value collecting every occurrence of a repeated flag
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 // Version information
 func printVersion() {
 	libknotVersion := libknot.GetVersion()
@@ -41,15 +60,28 @@ func printVersion() {
 	fmt.Printf("  Platform:     %s/%s\n", runtime.GOOS, runtime.GOARCH)
 }
 
-// validateConfig performs basic validation of configuration
-func validateConfig(sockPath string, addr string, port int) error {
-	// Check if socket path exists and is accessible
+// validateSocketPath checks that the knot control socket exists and is accessible
+func validateSocketPath(sockPath string) error {
 	if _, err := os.Stat(sockPath); err != nil {
 		if os.IsNotExist(err) {
 			return fmt.Errorf("knot socket does not exist: %s (is Knot DNS running?)", sockPath)
 		}
 		return fmt.Errorf("cannot access knot socket %s: %v", sockPath, err)
 	}
+	return nil
+}
+
+// validateConfig performs basic validation of configuration
+func validateConfig(sockPath string, addr string, port int) error {
+	// Check if socket path exists and is accessible
+	if err := validateSocketPath(sockPath); err != nil {
+		return err
+	}
+
+	// A UNIX socket needs no port
+	if path, ok := unixSocketPath(addr); ok {
+		return validateUnixSocket(path)
+	}
 
 	// Validate network address
 	if net.ParseIP(addr) == nil && addr != "localhost" {
@@ -64,7 +96,7 @@ func validateConfig(sockPath string, addr string, port int) error {
 	// Check if port is available
 	listener, err := net.Listen("tcp", net.JoinHostPort(addr, strconv.Itoa(port)))
 	if err != nil {
-		return fmt.Errorf("cannot bind to %s:%d: %v", addr, port, err)
+		return fmt.Errorf("cannot bind to %s:%d: %w", addr, port, err)
 	}
 	if err := listener.Close(); err != nil {
 		return fmt.Errorf("error closing listener: %v", err)
@@ -75,7 +107,7 @@ func validateConfig(sockPath string, addr string, port int) error {
 
 // testKnotConnection tests if we can connect to Knot DNS
 func testKnotConnection(sockPath string, timeout int) error {
-	utils.DebugLog("Testing connection to Knot DNS at %s", sockPath)
+	slog.Debug("Testing connection to Knot DNS", "socket", sockPath)
 
 	ctl := libknot.New()
 	if ctl == nil {
@@ -99,18 +131,19 @@ func testKnotConnection(sockPath string, timeout int) error {
 		return fmt.Errorf("failed to receive response from knot: %v", err)
 	}
 
-	utils.DebugLog("Successfully connected to Knot DNS")
+	slog.Debug("Successfully connected to Knot DNS", "socket", sockPath)
 	return nil
 }
 
-// setupGracefulShutdown sets up graceful shutdown handling
-func setupGracefulShutdown(server *http.Server) {
+// setupGracefulShutdown sets up graceful shutdown handling, running the
+// cleanup functions after the server stopped
+func setupGracefulShutdown(server *http.Server, cleanups ...func(context.Context) error) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
 		sig := <-sigChan
-		log.Printf("Received signal %v, initiating graceful shutdown...", sig)
+		slog.Info("Received signal, initiating graceful shutdown", "signal", sig)
 
 		// Create a context with timeout for shutdown
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -118,11 +151,15 @@ func setupGracefulShutdown(server *http.Server) {
 
 		// Attempt graceful shutdown
 		if err := server.Shutdown(ctx); err != nil {
-			log.Printf("Error during shutdown: %v", err)
-			os.Exit(1)
+			fatal("Error during shutdown", "err", err)
+		}
+		for _, cleanup := range cleanups {
+			if err := cleanup(ctx); err != nil {
+				slog.Error("Error during shutdown", "err", err)
+			}
 		}
 
-		log.Printf("Server stopped gracefully")
+		slog.Info("Server stopped gracefully")
 		os.Exit(0)
 	}()
 }
@@ -139,41 +176,52 @@ func healthCheck(sockPath string, timeout int) http.HandlerFunc {
 		w.WriteHeader(http.StatusOK)
 		_, err := fmt.Fprintf(w, "OK")
 		if err != nil {
-			log.Printf("Error writing health check response: %v", err)
+			slog.Error("Error writing health check response", "err", err)
 		}
 	}
 }
 
+// fatal logs an error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// subcommands run instead of the exporter when named by the first argument
+var subcommands = map[string]func(args []string, stdout, stderr io.Writer) int{
+	"dump":      runDump,
+	"check":     runCheck,
+	"catalog":   runCatalog,
+	"dashboard": runDashboard,
+	"rules":     runRules,
+}
+
 func main() {
-	webListenAddr := flag.String("web-listen-addr", "127.0.0.1", "address on which to expose metrics")
-	webListenPort := flag.Int("web-listen-port", 9433, "port on which to expose metrics")
-	knotSocketPath := flag.String("knot-socket-path", "/run/knot/knot.sock", "path to knot control socket")
-	knotSocketTimeout := flag.Int("knot-socket-timeout", 2000, "timeout for Knot control socket operations")
-	noMeminfo := flag.Bool("no-meminfo", false, "disable collection of memory usage")
-	noGlobalStats := flag.Bool("no-global-stats", false, "disable collection of global statistics")
-	noZoneStats := flag.Bool("no-zone-stats", false, "disable collection of zone statistics")
-	noZoneStatus := flag.Bool("no-zone-status", false, "disable collection of zone status")
-	noZoneSerial := flag.Bool("no-zone-serial", false, "disable collection of zone serial")
-	zoneTimers := flag.Bool("zone-timers", false, "enables collection of zone SOA timer values")
-	debug := flag.Bool("debug", false, "enable debug logging")
-	showVersion := flag.Bool("version", false, "show version information and exit")
-	skipValidation := flag.Bool("skip-validation", false, "skip initial validation checks (useful for testing)")
-
-	flag.Parse()
-
-	// Set global debug flag
-	utils.DebugMode = *debug
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(run(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
+	opts, err := parseOptions(os.Args[1:], flag.ExitOnError)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	started := time.Now()
+
+	// Set up logging, the level may change on a reload
+	if err := logging.Setup(os.Stderr, opts.logFormat); err != nil {
+		fatal("Invalid logging configuration", "err", err)
+	}
+	logging.Level.Set(opts.level())
 
 	// Show version and exit
-	if *showVersion {
+	if opts.showVersion {
 		printVersion()
 		os.Exit(0)
 	}
 
-	log.Printf("Starting Knot DNS Exporter %s", version)
-	if utils.DebugMode {
-		log.Printf("Debug mode enabled")
-	}
+	slog.Info("Starting Knot DNS Exporter", "version", version, "log_level", logging.Level.Level())
 
 	// Set collector build info
 	collector.Version = version
@@ -182,44 +230,161 @@ func main() {
 	collector.GoVersion = goVersion
 
 	// Validate configuration unless skipped
-	if !*skipValidation {
-		log.Printf("Validating configuration...")
-		if err := validateConfig(*knotSocketPath, *webListenAddr, *webListenPort); err != nil {
-			log.Fatalf("Configuration validation failed: %v", err)
+	if !opts.skipValidation {
+		slog.Info("Validating configuration")
+		if opts.textfilePath != "" || opts.pushURL != "" || opts.otlpOnly {
+			// No HTTP server in textfile, push and OTLP only modes, only the socket matters
+			if err := validateSocketPath(opts.knotSocketPath); err != nil {
+				fatal("Configuration validation failed", "err", err)
+			}
+		} else if opts.webSystemdSocket {
+			// The sockets are bound by systemd
+			if err := validateSocketPath(opts.knotSocketPath); err != nil {
+				fatal("Configuration validation failed", "err", err)
+			}
+		} else {
+			for _, addr := range opts.webListenAddrs {
+				if err := validateConfig(opts.knotSocketPath, addr, opts.webListenPort); err != nil {
+					fatal("Configuration validation failed", "err", err)
+				}
+			}
 		}
 
 		// Test Knot connection
-		log.Printf("Testing connection to Knot DNS...")
-		if err := testKnotConnection(*knotSocketPath, *knotSocketTimeout); err != nil {
-			log.Fatalf("Knot DNS connection test failed: %v", err)
+		slog.Info("Testing connection to Knot DNS")
+		if err := testKnotConnection(opts.knotSocketPath, opts.knotSocketTimeout); err != nil {
+			fatal("Knot DNS connection test failed", "err", err)
 		}
-		log.Printf("Configuration validation passed")
+		slog.Info("Configuration validation passed")
 	} else {
-		log.Printf("Skipping validation checks")
+		slog.Info("Skipping validation checks")
+	}
+
+	// Create collector with error handling. A reload swaps in a new one.
+	slog.Info("Initializing metrics collector")
+	initialCollector, err := opts.newCollector()
+	if err != nil {
+		fatal("Invalid collector configuration", "err", err)
+	}
+	knotCollector := collector.NewReloadableCollector(initialCollector)
+
+	// Reload the configuration on SIGHUP
+	reload := newReloader(os.Args[1:], opts, knotCollector)
+	watchReload(context.Background(), reload)
+
+	// Write metrics for the node_exporter textfile collector instead of
+	// serving them. The registry holds only the Knot DNS metrics, node_exporter
+	// exports its own process and Go runtime metrics.
+	if opts.textfilePath != "" {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(knotCollector)
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		slog.Info("Writing metrics to a textfile", "path", opts.textfilePath)
+		if err := runTextfile(ctx, registry, knotCollector, opts.textfilePath, opts.textfileInterval); err != nil {
+			fatal("Textfile output failed", "err", err)
+		}
+		return
+	}
+
+	// Push metrics instead of serving them, for nodes that can't be scraped
+	if opts.pushURL != "" {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(knotCollector)
+
+		instance := opts.pushInstance
+		if instance == "" {
+			if instance, err = os.Hostname(); err != nil {
+				fatal("Failed to determine instance label", "err", err)
+			}
+		}
+
+		pusher, err := newPusher(opts.pushMode, opts.pushURL, opts.pushJob, instance, registry, opts.pushTimeout)
+		if err != nil {
+			fatal("Invalid push configuration", "err", err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		slog.Info("Pushing metrics", "url", opts.pushURL, "mode", opts.pushMode)
+		retry := push.Retry{Retries: opts.pushRetries, Backoff: opts.pushRetryBackoff}
+		if err := runPush(ctx, pusher, knotCollector, opts.pushInterval, retry); err != nil {
+			fatal("Push failed", "err", err)
+		}
+		return
 	}
 
-	// Create collector with error handling
-	log.Printf("Initializing metrics collector...")
-	knotCollector := collector.NewKnotCollector(
-		*knotSocketPath,
-		*knotSocketTimeout,
-		!*noMeminfo,
-		!*noGlobalStats,
-		!*noZoneStats,
-		!*noZoneStatus,
-		!*noZoneSerial,
-		*zoneTimers,
-	)
+	// Export over OTLP, alongside the HTTP endpoint or instead of it. Like the
+	// push modes, the export holds only the Knot DNS metrics.
+	var cleanups []func(context.Context) error
+	if opts.otlpEndpoint != "" {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(knotCollector)
+
+		hostname, _ := os.Hostname()
+		instance := opts.otlpInstance
+		if instance == "" {
+			instance = hostname
+		}
+
+		exporter, err := otlp.Start(context.Background(), otlp.Config{
+			Endpoint:   opts.otlpEndpoint,
+			Protocol:   opts.otlpProtocol,
+			Interval:   opts.otlpInterval,
+			Timeout:    opts.otlpTimeout,
+			HostName:   hostname,
+			Instance:   instance,
+			SocketPath: opts.knotSocketPath,
+			Version:    version,
+		}, registry)
+		if err != nil {
+			fatal("Failed to start OTLP export", "err", err)
+		}
+		slog.Info("Exporting metrics over OTLP", "endpoint", opts.otlpEndpoint, "protocol", opts.otlpProtocol, "interval", opts.otlpInterval)
+
+		if opts.otlpOnly {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			<-ctx.Done()
+
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := exporter.Shutdown(shutdownCtx); err != nil {
+				slog.Error("Error during shutdown", "err", err)
+			}
+			return
+		}
+		cleanups = append(cleanups, exporter.Shutdown)
+	} else if opts.otlpOnly {
+		fatal("-otlp-only requires -otlp-endpoint")
+	}
 
 	// Register collector with Prometheus
 	if err := prometheus.Register(knotCollector); err != nil {
-		log.Fatalf("Failed to register Prometheus collector: %v", err)
+		fatal("Failed to register Prometheus collector", "err", err)
 	}
 
 	// Setup HTTP routes
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
-	mux.HandleFunc("/health", healthCheck(*knotSocketPath, *knotSocketTimeout))
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		o := reload.collectorOptions()
+		healthCheck(o.knotSocketPath, o.knotSocketTimeout)(w, r)
+	})
+	mux.Handle("GET /-/healthy", api.HealthyHandler(version, started))
+	mux.Handle("GET /-/ready", api.ReadyHandler(knotCollector, func() error {
+		o := reload.collectorOptions()
+		return testKnotConnection(o.knotSocketPath, o.knotSocketTimeout)
+	}))
+	if opts.webEnableReload {
+		mux.Handle("POST /-/reload", reloadHandler(reload))
+	}
+	if !opts.noZonesAPI {
+		api.RegisterZoneHandlers(mux, knotCollector)
+	}
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
 		_, err := fmt.Fprintf(w, `<!DOCTYPE html>
@@ -230,31 +395,57 @@ func main() {
 <p>Version: %s</p>
 <p><a href="/metrics">Metrics</a></p>
 <p><a href="/health">Health Check</a></p>
+<p><a href="/-/ready">Readiness</a></p>
 </body>
 </html>`, version)
 		if err != nil {
-			log.Printf("Error writing index page: %v", err)
+			slog.Error("Error writing index page", "err", err)
 		}
 	})
 
-	// Create server with timeouts
-	server := &http.Server{
-		Addr:         net.JoinHostPort(*webListenAddr, strconv.Itoa(*webListenPort)),
+	// Create server with timeouts, protected by the web config if any
+	server, err := web.NewServer(&http.Server{
 		Handler:      mux,
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
+	}, opts.webConfigFile)
+	if err != nil {
+		fatal("Failed to load web config", "err", err)
+	}
+	scheme := "http"
+	if server.TLS() {
+		scheme = "https"
+	}
+
+	// Open the listening sockets
+	var listeners []net.Listener
+	if opts.webSystemdSocket {
+		listeners, err = systemdListeners()
+	} else {
+		socketMode, parseErr := strconv.ParseUint(opts.webSocketMode, 8, 32)
+		if parseErr != nil {
+			fatal("Invalid UNIX socket mode", "mode", opts.webSocketMode, "err", parseErr)
+		}
+		listeners, err = listen(opts.webListenAddrs, opts.webListenPort, os.FileMode(socketMode))
+	}
+	if err != nil {
+		fatal("Failed to listen", "err", err)
 	}
 
 	// Setup graceful shutdown
-	setupGracefulShutdown(server)
+	setupGracefulShutdown(server.Server, cleanups...)
 
-	log.Printf("Starting HTTP server on %s", server.Addr)
-	log.Printf("Metrics available at http://%s/metrics", server.Addr)
-	log.Printf("Health check available at http://%s/health", server.Addr)
+	// Tell systemd when the first collection succeeded
+	startSystemdNotify(context.Background(), knotCollector)
+
+	for _, l := range listeners {
+		slog.Info("Starting HTTP server", "url", listenerURL(scheme, l))
+	}
+	slog.Info("Metrics available at /metrics, health check at /health, liveness and readiness at /-/healthy and /-/ready")
 
 	// Start server with error handling
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("HTTP server failed: %v", err)
+	if err := serve(server, listeners); err != nil && err != http.ErrServerClosed {
+		fatal("HTTP server failed", "err", err)
 	}
 }
@@ -3,13 +3,14 @@ package main
 import (
 	"bytes"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
 
-	"github.com/CZ-NIC/knot-exporter/pkg/utils"
+	"github.com/CZ-NIC/knot-exporter/pkg/logging"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -190,10 +191,9 @@ func TestTestKnotConnection(t *testing.T) {
 
 // TestTestKnotConnectionDebugMode tests testKnotConnection with debug mode
 func TestTestKnotConnectionDebugMode(t *testing.T) {
-	// Import utils to set debug mode
-	oldDebugMode := utils.DebugMode
-	utils.DebugMode = true
-	defer func() { utils.DebugMode = oldDebugMode }()
+	oldLevel := logging.Level.Level()
+	logging.Level.Set(slog.LevelDebug)
+	defer logging.Level.Set(oldLevel)
 
 	err := testKnotConnection("/nonexistent/socket.sock", 1000)
 	assert.Error(t, err)
@@ -465,3 +465,15 @@ func TestValidateConfigPortBoundaries(t *testing.T) {
 		})
 	}
 }
+
+// TestStringList tests the repeatable flag value
+func TestStringList(t *testing.T) {
+	var list stringList
+	assert.Equal(t, "", list.String())
+
+	require.NoError(t, list.Set("example.com"))
+	require.NoError(t, list.Set("suffix:example.org"))
+
+	assert.Equal(t, stringList{"example.com", "suffix:example.org"}, list)
+	assert.Equal(t, "example.com,suffix:example.org", list.String())
+}
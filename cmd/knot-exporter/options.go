@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/collector"
+	"github.com/CZ-NIC/knot-exporter/pkg/logging"
+	"github.com/CZ-NIC/knot-exporter/pkg/otlp"
+)
+
+// collectorOptions are the settings of the Knot DNS collector, applied again
+// on a reload
+type collectorOptions struct {
+	knotSocketPath     string
+	knotSocketTimeout  int
+	knotMaxConnections int
+	knotPIDFile        string
+	procRoot           string
+	noMeminfo          bool
+	noGlobalStats      bool
+	noZoneStats        bool
+	noZoneStatus       bool
+	noZoneSerial       bool
+	zoneTimers         bool
+	zoneBatchSize      int
+	zoneListTTL        time.Duration
+	maxZones           int
+	maxSeries          int
+	maxDescriptors     int
+	zoneInclude        stringList
+	zoneExclude        stringList
+	legacyMetricTypes  bool
+	namingSchemeName   string
+	relabelConfigPath  string
+	nativeHistograms   bool
+	logLevel           slog.Level
+	debug              bool
+}
+
+// options are all settings of the exporter. Those outside collectorOptions
+// only take effect on a restart.
+type options struct {
+	collectorOptions
+
+	configFile       string
+	webListenAddrs   stringList
+	webListenPort    int
+	webSystemdSocket bool
+	webSocketMode    string
+	webConfigFile    string
+	webEnableReload  bool
+	logFormat        string
+	noZonesAPI       bool
+	textfilePath     string
+	textfileInterval time.Duration
+	pushURL          string
+	pushMode         string
+	pushInterval     time.Duration
+	pushJob          string
+	pushInstance     string
+	pushTimeout      time.Duration
+	pushRetries      int
+	pushRetryBackoff time.Duration
+	otlpEndpoint     string
+	otlpProtocol     string
+	otlpInterval     time.Duration
+	otlpTimeout      time.Duration
+	otlpInstance     string
+	otlpOnly         bool
+	showVersion      bool
+	skipValidation   bool
+}
+
+// newFlagSet returns the command line flags of the exporter, parsed into o
+func newFlagSet(o *options, errorHandling flag.ErrorHandling) *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], errorHandling)
+	if errorHandling == flag.ContinueOnError {
+		// Errors are returned, without printing the usage
+		fs.SetOutput(io.Discard)
+	}
+
+	fs.StringVar(&o.configFile, "config-file", "", "read further flags from this file, one -flag=value per line, again on every reload")
+	fs.Var(&o.webListenAddrs, "web-listen-addr", "address on which to expose metrics, or unix:<path> for a UNIX socket (repeatable, default 127.0.0.1)")
+	fs.IntVar(&o.webListenPort, "web-listen-port", 9433, "port on which to expose metrics")
+	fs.BoolVar(&o.webSystemdSocket, "web-systemd-socket", false, "serve on the sockets passed by systemd socket activation instead of -web-listen-addr")
+	fs.StringVar(&o.webSocketMode, "web-socket-mode", "0660", "permissions of UNIX sockets created for -web-listen-addr")
+	fs.StringVar(&o.knotSocketPath, "knot-socket-path", "/run/knot/knot.sock", "path to knot control socket")
+	fs.IntVar(&o.knotSocketTimeout, "knot-socket-timeout", 2000, "timeout for Knot control socket operations")
+	fs.IntVar(&o.knotMaxConnections, "knot-max-connections", 1, "maximum number of parallel control connections used during a collection")
+	fs.BoolVar(&o.noMeminfo, "no-meminfo", false, "disable collection of memory usage")
+	fs.StringVar(&o.knotPIDFile, "knot-pid-file", "", "read the PID of knotd for memory usage from this file instead of the peer credentials of the control socket")
+	fs.StringVar(&o.procRoot, "proc-root", "/proc", "procfs mount of the PID namespace of knotd")
+	fs.BoolVar(&o.noGlobalStats, "no-global-stats", false, "disable collection of global statistics")
+	fs.BoolVar(&o.noZoneStats, "no-zone-stats", false, "disable collection of zone statistics")
+	fs.BoolVar(&o.noZoneStatus, "no-zone-status", false, "disable collection of zone status")
+	fs.BoolVar(&o.noZoneSerial, "no-zone-serial", false, "disable collection of zone serial")
+	fs.BoolVar(&o.noZonesAPI, "no-zones-api", false, "disable the /api/v1/zones JSON API")
+	fs.BoolVar(&o.zoneTimers, "zone-timers", false, "enables collection of zone SOA timer values")
+	fs.IntVar(&o.zoneBatchSize, "zone-batch-size", 0, "query per-zone data in batches of this many zones, each over its own connection (0 queries all zones at once)")
+	fs.DurationVar(&o.zoneListTTL, "zone-list-ttl", 0, "how long the enumerated zone list is reused when batching or filtering by catalog (0 enumerates on every scrape)")
+	fs.IntVar(&o.maxZones, "max-zones", 0, "maximum number of zones with per-zone series, further zone statistics are summed into zone=\"other\" (0 means unlimited)")
+	fs.IntVar(&o.maxSeries, "max-series-per-metric", 0, "maximum number of series per statistics metric, further series are summed into \"other\" (0 means unlimited)")
+	fs.IntVar(&o.maxDescriptors, "max-stats-metrics", 0, "maximum number of distinct global and zone statistics metrics (0 means unlimited)")
+	fs.Var(&o.zoneInclude, "zone-include", "collect per-zone metrics only for matching zones: name, suffix:<zone>, regex:<expr> or catalog:<zone> (repeatable)")
+	fs.Var(&o.zoneExclude, "zone-exclude", "skip per-zone metrics for matching zones, same rule syntax as -zone-include (repeatable)")
+	fs.BoolVar(&o.legacyMetricTypes, "legacy-metric-types", false, "send every value both as a gauge and as a _total counter, as older versions did")
+	fs.StringVar(&o.namingSchemeName, "naming-scheme", "default", "metric names and labels to export: default, or python for those of the Python knot_exporter")
+	fs.StringVar(&o.relabelConfigPath, "relabel-config", "", "path to a YAML file with static labels and metric relabeling rules")
+	fs.BoolVar(&o.nativeHistograms, "native-histograms", false, "add native histogram buckets to histograms of bucket-style statistics such as query and reply sizes")
+	fs.StringVar(&o.textfilePath, "textfile-path", "", "write metrics to this file for the node_exporter textfile collector instead of serving HTTP")
+	fs.DurationVar(&o.textfileInterval, "textfile-interval", 0, "rewrite the textfile at this interval (0 writes it once and exits)")
+	fs.StringVar(&o.pushURL, "push-url", "", "push metrics to this Pushgateway or remote-write URL instead of serving HTTP")
+	fs.StringVar(&o.pushMode, "push-mode", pushModePushgateway, "push protocol: pushgateway or remote-write")
+	fs.DurationVar(&o.pushInterval, "push-interval", 0, "push metrics at this interval (0 pushes once and exits)")
+	fs.StringVar(&o.pushJob, "push-job", "knot", "job label of pushed metrics")
+	fs.StringVar(&o.pushInstance, "push-instance", "", "instance label of pushed metrics (defaults to the hostname)")
+	fs.DurationVar(&o.pushTimeout, "push-timeout", 10*time.Second, "timeout of a single push request")
+	fs.IntVar(&o.pushRetries, "push-retries", 3, "number of retries of a failed push")
+	fs.DurationVar(&o.pushRetryBackoff, "push-retry-backoff", time.Second, "delay before the first retry of a failed push, doubled for every further retry")
+	fs.StringVar(&o.otlpEndpoint, "otlp-endpoint", "", "export metrics over OTLP to this URL, e.g. http://collector:4317 for gRPC")
+	fs.StringVar(&o.otlpProtocol, "otlp-protocol", otlp.ProtocolGRPC, "OTLP transport: grpc or http")
+	fs.DurationVar(&o.otlpInterval, "otlp-interval", 30*time.Second, "interval between OTLP exports")
+	fs.DurationVar(&o.otlpTimeout, "otlp-timeout", 10*time.Second, "timeout of a single OTLP export")
+	fs.StringVar(&o.otlpInstance, "otlp-instance", "", "service.instance.id resource attribute of the knotd instance (defaults to the hostname)")
+	fs.BoolVar(&o.otlpOnly, "otlp-only", false, "export over OTLP only, without serving HTTP")
+	fs.StringVar(&o.webConfigFile, "web-config-file", "", "path to a web config file enabling TLS and authentication, in the Prometheus exporter-toolkit format")
+	fs.BoolVar(&o.webEnableReload, "web-enable-reload", false, "enable the POST /-/reload endpoint, reloading like SIGHUP")
+	fs.TextVar(&o.logLevel, "log-level", slog.LevelInfo, "minimum level of logged messages: debug, info, warn or error")
+	fs.StringVar(&o.logFormat, "log-format", logging.FormatLogfmt, "log output format: logfmt or json")
+	fs.BoolVar(&o.debug, "debug", false, "enable debug logging, same as -log-level=debug")
+	fs.BoolVar(&o.showVersion, "version", false, "show version information and exit")
+	fs.BoolVar(&o.skipValidation, "skip-validation", false, "skip initial validation checks (useful for testing)")
+
+	return fs
+}
+
+// readConfigFile returns the flags of a config file: one -flag=value per
+// line, blank lines and lines starting with # are ignored
+func readConfigFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var args []string
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		arg := strings.TrimSpace(scanner.Text())
+		if arg == "" || strings.HasPrefix(arg, "#") {
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") {
+			return nil, fmt.Errorf("%s:%d: expected -flag=value, got %q", path, line, arg)
+		}
+		args = append(args, arg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// parseOptions parses the command line arguments. Flags of the config file,
+// if one is given, are parsed first so that the command line overrides them;
+// repeatable flags collect the values of both. The extra functions register
+// further flags, such as those of a subcommand.
+func parseOptions(args []string, errorHandling flag.ErrorHandling, extra ...func(*flag.FlagSet)) (*options, error) {
+	o := &options{}
+	fs := newFlagSet(o, errorHandling)
+	for _, register := range extra {
+		register(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if o.configFile != "" {
+		fileArgs, err := readConfigFile(o.configFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %v", err)
+		}
+
+		configFile := o.configFile
+		o = &options{}
+		fs = newFlagSet(o, flag.ContinueOnError)
+		for _, register := range extra {
+			register(fs)
+		}
+		if err := fs.Parse(fileArgs); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %v", configFile, err)
+		}
+		if fs.NArg() > 0 {
+			return nil, fmt.Errorf("invalid config file %s: unexpected argument %q", configFile, fs.Arg(0))
+		}
+		// The arguments were parsed successfully above
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(o.webListenAddrs) == 0 {
+		o.webListenAddrs = stringList{"127.0.0.1"}
+	}
+	return o, nil
+}
+
+// level returns the log level of the options
+func (o *collectorOptions) level() slog.Level {
+	if o.debug {
+		return slog.LevelDebug
+	}
+	return o.logLevel
+}
+
+// newCollector builds the Knot DNS collector of the options, with extra
+// options applied last
+func (o *collectorOptions) newCollector(extra ...collector.Option) (*collector.KnotCollector, error) {
+	// Build zone filter, if any rules were given
+	var zoneFilter *collector.ZoneFilter
+	if len(o.zoneInclude) > 0 || len(o.zoneExclude) > 0 {
+		var err error
+		zoneFilter, err = collector.NewZoneFilter(o.zoneInclude, o.zoneExclude)
+		if err != nil {
+			return nil, fmt.Errorf("invalid zone filter: %v", err)
+		}
+	}
+
+	namingScheme, err := collector.ParseNamingScheme(o.namingSchemeName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid naming scheme: %v", err)
+	}
+
+	// Load relabeling rules, if configured
+	var relabelConfig *collector.RelabelConfig
+	if o.relabelConfigPath != "" {
+		relabelConfig, err = collector.LoadRelabelConfig(o.relabelConfigPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load relabel config: %v", err)
+		}
+	}
+
+	opts := []collector.Option{
+		collector.WithMaxConcurrency(o.knotMaxConnections),
+		collector.WithZoneFilter(zoneFilter),
+		collector.WithZoneBatchSize(o.zoneBatchSize),
+		collector.WithZoneListTTL(o.zoneListTTL),
+		collector.WithLimits(collector.Limits{
+			MaxZones:       o.maxZones,
+			MaxSeries:      o.maxSeries,
+			MaxDescriptors: o.maxDescriptors,
+		}),
+		collector.WithLegacyMetricTypes(o.legacyMetricTypes),
+		collector.WithNativeHistograms(o.nativeHistograms),
+		collector.WithNamingScheme(namingScheme),
+		collector.WithRelabelConfig(relabelConfig),
+		collector.WithPIDFile(o.knotPIDFile),
+		collector.WithProcRoot(o.procRoot),
+	}
+	return collector.NewKnotCollector(
+		o.knotSocketPath,
+		o.knotSocketTimeout,
+		!o.noMeminfo,
+		!o.noGlobalStats,
+		!o.noZoneStats,
+		!o.noZoneStatus,
+		!o.noZoneSerial,
+		o.zoneTimers,
+		append(opts, extra...)...,
+	), nil
+}
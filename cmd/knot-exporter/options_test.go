@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfigFile writes a config file and returns its path
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "knot-exporter.conf")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// TestParseOptionsDefaults tests the defaults of the options
+func TestParseOptionsDefaults(t *testing.T) {
+	o, err := parseOptions(nil, flag.ContinueOnError)
+	require.NoError(t, err)
+	assert.Equal(t, stringList{"127.0.0.1"}, o.webListenAddrs)
+	assert.Equal(t, 9433, o.webListenPort)
+	assert.Equal(t, "/run/knot/knot.sock", o.knotSocketPath)
+	assert.Equal(t, 10*time.Second, o.pushTimeout)
+	assert.False(t, o.webEnableReload)
+}
+
+// TestParseOptionsConfigFile tests that the command line overrides the config
+// file and repeatable flags collect both
+func TestParseOptionsConfigFile(t *testing.T) {
+	path := writeConfigFile(t, `
+# Knot DNS
+-knot-socket-path=/run/knot/other.sock
+-knot-socket-timeout=500
+-no-zone-stats
+
+-zone-include=suffix:example.com
+`)
+
+	o, err := parseOptions([]string{
+		"-config-file", path,
+		"-knot-socket-timeout=1000",
+		"-zone-include=example.org",
+	}, flag.ContinueOnError)
+	require.NoError(t, err)
+	assert.Equal(t, path, o.configFile)
+	assert.Equal(t, "/run/knot/other.sock", o.knotSocketPath)
+	assert.Equal(t, 1000, o.knotSocketTimeout)
+	assert.True(t, o.noZoneStats)
+	assert.Equal(t, stringList{"suffix:example.com", "example.org"}, o.zoneInclude)
+}
+
+// TestParseOptionsInvalid tests rejection of invalid flags and config files
+func TestParseOptionsInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown flag":  "-no-such-flag\n",
+		"invalid value": "-knot-socket-timeout=soon\n",
+		"not a flag":    "knot-socket-path /run/knot/knot.sock\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseOptions([]string{"-config-file", writeConfigFile(t, content)}, flag.ContinueOnError)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := parseOptions([]string{"-config-file", "/nonexistent.conf"}, flag.ContinueOnError)
+	assert.ErrorContains(t, err, "failed to read config file")
+	_, err = parseOptions([]string{"-knot-socket-timeout=soon"}, flag.ContinueOnError)
+	assert.Error(t, err)
+}
+
+// TestNewCollectorInvalid tests errors of the collector options
+func TestNewCollectorInvalid(t *testing.T) {
+	_, err := (&collectorOptions{namingSchemeName: "default", zoneInclude: stringList{"regex:("}}).newCollector()
+	assert.ErrorContains(t, err, "invalid zone filter")
+	_, err = (&collectorOptions{namingSchemeName: "unknown"}).newCollector()
+	assert.ErrorContains(t, err, "invalid naming scheme")
+	_, err = (&collectorOptions{namingSchemeName: "default", relabelConfigPath: "/nonexistent.yml"}).newCollector()
+	assert.ErrorContains(t, err, "failed to load relabel config")
+}
+
+// TestParseOptionsLogLevel tests the log level flags
+func TestParseOptionsLogLevel(t *testing.T) {
+	o, err := parseOptions(nil, flag.ContinueOnError)
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelInfo, o.level())
+	assert.Equal(t, "logfmt", o.logFormat)
+
+	o, err = parseOptions([]string{"-log-level=warn", "-log-format=json"}, flag.ContinueOnError)
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, o.level())
+	assert.Equal(t, "json", o.logFormat)
+
+	o, err = parseOptions([]string{"-log-level=error", "-debug"}, flag.ContinueOnError)
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, o.level())
+
+	_, err = parseOptions([]string{"-log-level=verbose"}, flag.ContinueOnError)
+	assert.Error(t, err)
+}
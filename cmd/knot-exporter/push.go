@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/push"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Push modes
+const (
+	pushModePushgateway = "pushgateway"
+	pushModeRemoteWrite = "remote-write"
+)
+
+// newPusher creates the pusher of a push mode
+func newPusher(mode, url, job, instance string, gatherer prometheus.Gatherer, timeout time.Duration) (push.Pusher, error) {
+	client := &http.Client{Timeout: timeout}
+
+	switch mode {
+	case pushModePushgateway:
+		return push.NewPushgateway(url, job, instance, gatherer, client), nil
+	case pushModeRemoteWrite:
+		labels := map[string]string{"job": job, "instance": instance}
+		return push.NewRemoteWrite(url, labels, gatherer, client), nil
+	}
+	return nil, fmt.Errorf("unknown push mode %q (expected %q or %q)", mode, pushModePushgateway, pushModeRemoteWrite)
+}
+
+// pushMetrics runs a collection and pushes its metrics. The metrics are
+// pushed even when some collectors failed, the returned error reports those
+// failures.
+func pushMetrics(ctx context.Context, pusher push.Pusher, result collectionResult, retry push.Retry) error {
+	if err := push.PushWithRetry(ctx, pusher, retry); err != nil {
+		return fmt.Errorf("failed to push metrics: %v", err)
+	}
+	if err := result.LastError(); err != nil {
+		return fmt.Errorf("collection failed: %v", err)
+	}
+	return nil
+}
+
+// runPush pushes metrics once, or every interval until ctx is done when
+// interval is positive. In one-shot mode the error of the push is returned,
+// periodic failures are only logged.
+func runPush(ctx context.Context, pusher push.Pusher, result collectionResult, interval time.Duration, retry push.Retry) error {
+	if interval <= 0 {
+		return pushMetrics(ctx, pusher, result, retry)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := pushMetrics(ctx, pusher, result, retry); err != nil {
+			slog.Error("Push failed", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
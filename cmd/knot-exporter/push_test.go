@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/push"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewPusher tests selecting the push protocol
+func TestNewPusher(t *testing.T) {
+	registry := newTextfileRegistry(t)
+
+	pusher, err := newPusher(pushModePushgateway, "http://localhost:9091", "knot", "ns1", registry, time.Second)
+	require.NoError(t, err)
+	assert.IsType(t, &push.Pushgateway{}, pusher)
+
+	pusher, err = newPusher(pushModeRemoteWrite, "http://localhost:9090/api/v1/write", "knot", "ns1", registry, time.Second)
+	require.NoError(t, err)
+	assert.IsType(t, &push.RemoteWrite{}, pusher)
+
+	_, err = newPusher("carrier-pigeon", "http://localhost", "knot", "ns1", registry, time.Second)
+	assert.Error(t, err)
+}
+
+// TestRunPushOnce tests a one-shot push with retries against a stand-in
+// receiver
+func TestRunPushOnce(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first attempt fails, the retry succeeds
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	pusher, err := newPusher(pushModeRemoteWrite, server.URL, "knot", "ns1", newTextfileRegistry(t), time.Second)
+	require.NoError(t, err)
+
+	retry := push.Retry{Retries: 1, Backoff: time.Millisecond}
+	require.NoError(t, runPush(context.Background(), pusher, staticResult{}, 0, retry))
+	assert.Equal(t, int32(2), requests.Load())
+
+	// Collection failures are reported after pushing
+	err = runPush(context.Background(), pusher, staticResult{errors.New("zone status: timeout")}, 0, retry)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout")
+	assert.Equal(t, int32(3), requests.Load())
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/collector"
+	"github.com/CZ-NIC/knot-exporter/pkg/logging"
+)
+
+// reloader re-reads the command line and config file and swaps in a
+// collector built from them
+type reloader struct {
+	args   []string
+	target *collector.ReloadableCollector
+
+	mu      sync.Mutex
+	current *options
+}
+
+func newReloader(args []string, current *options, target *collector.ReloadableCollector) *reloader {
+	running := *current
+	return &reloader{args: args, target: target, current: &running}
+}
+
+// reload applies the current options and warms the new collector in the
+// background. On failure the running collector is kept and the error is
+// returned.
+func (r *reloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var next *options
+	err := r.target.Reload(func() (*collector.KnotCollector, error) {
+		o, err := parseOptions(r.args, flag.ContinueOnError)
+		if err != nil {
+			return nil, err
+		}
+		if o.knotSocketPath != r.current.knotSocketPath && !o.skipValidation {
+			if err := validateSocketPath(o.knotSocketPath); err != nil {
+				return nil, err
+			}
+		}
+		c, err := o.collectorOptions.newCollector()
+		if err != nil {
+			return nil, err
+		}
+		next = o
+		return c, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if restartNeeded(r.current, next) {
+		slog.Warn("Only collector and logging settings were reloaded, other changes take effect after a restart")
+	}
+	r.current.collectorOptions = next.collectorOptions
+	logging.Level.Set(next.level())
+
+	// The new collector has no collection yet, /-/ready would fail until
+	// the next scrape
+	go r.target.Warm()
+	return nil
+}
+
+// collectorOptions returns the collector options in effect
+func (r *reloader) collectorOptions() collectorOptions {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current.collectorOptions
+}
+
+// restartNeeded reports whether options outside collectorOptions differ
+func restartNeeded(running, loaded *options) bool {
+	a, b := *running, *loaded
+	a.collectorOptions, b.collectorOptions = collectorOptions{}, collectorOptions{}
+	return !reflect.DeepEqual(a, b)
+}
+
+// logReload reloads and logs the outcome
+func (r *reloader) logReload(trigger string) error {
+	slog.Info("Reloading configuration", "trigger", trigger)
+	if err := r.reload(); err != nil {
+		slog.Error("Configuration reload failed, keeping the previous configuration", "err", err)
+		return err
+	}
+	slog.Info("Configuration reloaded")
+	return nil
+}
+
+// watchReload reloads on every SIGHUP until ctx is done
+func watchReload(ctx context.Context, r *reloader) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(sigChan)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigChan:
+				_ = r.logReload("SIGHUP")
+			}
+		}
+	}()
+}
+
+// reloadHandler reloads on POST /-/reload
+func reloadHandler(r *reloader) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		var err error
+		if reloadErr := r.logReload("HTTP"); reloadErr != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, err = fmt.Fprintf(w, "Reload failed: %v\n", reloadErr)
+		} else {
+			_, err = fmt.Fprintln(w, "Configuration reloaded")
+		}
+		if err != nil {
+			slog.Error("Error writing reload response", "err", err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/collector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestReloader returns a reloader of a collector started with args
+func newTestReloader(t *testing.T, args ...string) (*reloader, *collector.ReloadableCollector) {
+	o, err := parseOptions(args, flag.ContinueOnError)
+	require.NoError(t, err)
+	c, err := o.newCollector()
+	require.NoError(t, err)
+	target := collector.NewReloadableCollector(c)
+	return newReloader(args, o, target), target
+}
+
+// TestReload tests applying a changed config file
+func TestReload(t *testing.T) {
+	path := writeConfigFile(t, "-knot-socket-timeout=500\n")
+	r, target := newTestReloader(t, "-config-file", path)
+	first := target.Collector()
+
+	require.NoError(t, os.WriteFile(path, []byte("-knot-socket-timeout=800\n-zone-exclude=example.com\n"), 0o600))
+	require.NoError(t, r.reload())
+	assert.NotSame(t, first, target.Collector())
+	assert.Equal(t, 800, r.collectorOptions().knotSocketTimeout)
+	assert.Equal(t, stringList{"example.com"}, r.collectorOptions().zoneExclude)
+	assert.Eventually(t, func() bool { return !target.LastCollection().IsZero() }, 5*time.Second, time.Millisecond,
+		"The new collector should be warmed")
+
+	// An invalid change keeps the running collector and options
+	second := target.Collector()
+	require.NoError(t, os.WriteFile(path, []byte("-zone-exclude=regex:(\n"), 0o600))
+	assert.ErrorContains(t, r.reload(), "invalid zone filter")
+	assert.Same(t, second, target.Collector())
+	assert.Equal(t, 800, r.collectorOptions().knotSocketTimeout)
+
+	// A socket path that doesn't exist is rejected
+	require.NoError(t, os.WriteFile(path, []byte("-knot-socket-path=/nonexistent/knot.sock\n"), 0o600))
+	assert.ErrorContains(t, r.reload(), "does not exist")
+	assert.Same(t, second, target.Collector())
+}
+
+// TestRestartNeeded tests detection of changes a reload can't apply
+func TestRestartNeeded(t *testing.T) {
+	running, err := parseOptions([]string{"-knot-socket-path=/a"}, flag.ContinueOnError)
+	require.NoError(t, err)
+
+	loaded, err := parseOptions([]string{"-knot-socket-path=/b", "-zone-timers"}, flag.ContinueOnError)
+	require.NoError(t, err)
+	assert.False(t, restartNeeded(running, loaded))
+
+	loaded, err = parseOptions([]string{"-web-listen-port=9999"}, flag.ContinueOnError)
+	require.NoError(t, err)
+	assert.True(t, restartNeeded(running, loaded))
+}
+
+// TestReloadHandler tests the reload endpoint
+func TestReloadHandler(t *testing.T) {
+	path := writeConfigFile(t, "")
+	r, _ := newTestReloader(t, "-config-file", path)
+
+	rec := httptest.NewRecorder()
+	reloadHandler(r).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/-/reload", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "Configuration reloaded\n", rec.Body.String())
+
+	require.NoError(t, os.WriteFile(path, []byte("-naming-scheme=unknown\n"), 0o600))
+	rec = httptest.NewRecorder()
+	reloadHandler(r).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/-/reload", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Reload failed: invalid naming scheme")
+}
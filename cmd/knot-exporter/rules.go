@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/rules"
+)
+
+// runRules prints Prometheus alerting and recording rules for the metrics of
+// the exporter. It returns the exit status.
+func runRules(args []string, stdout, stderr io.Writer) int {
+	c := rules.Default()
+	fs := flag.NewFlagSet("rules", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s rules [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&c.Selector, "selector", c.Selector, "label matchers added to every selector, e.g. job=\"knot\"")
+	fs.DurationVar(&c.KnotDownFor, "knot-down-for", c.KnotDownFor, "how long Knot DNS may be unreachable")
+	fs.DurationVar(&c.ZoneExpiry, "zone-expiry", c.ZoneExpiry, "time left until a secondary zone expires to alert at")
+	fs.DurationVar(&c.SerialStaleWindow, "serial-stale-window", c.SerialStaleWindow, "how long the serial of a signed zone may stay the same")
+	fs.DurationVar(&c.ResignOverdueFor, "resign-overdue-for", c.ResignOverdueFor, "how long the DNSSEC re-sign of a zone may be overdue")
+	fs.Float64Var(&c.ServfailRatio, "servfail-ratio", c.ServfailRatio, "share of SERVFAIL responses to alert at")
+	fs.DurationVar(&c.ServfailFor, "servfail-for", c.ServfailFor, "how long the SERVFAIL ratio may stay high")
+	fs.Float64Var(&c.MemoryGrowthRatio, "memory-growth-ratio", c.MemoryGrowthRatio, "factor of memory growth within the window to alert at")
+	fs.DurationVar(&c.MemoryGrowthWindow, "memory-growth-window", c.MemoryGrowthWindow, "period memory growth is measured over")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "rules: unexpected arguments %v\n", fs.Args())
+		return 2
+	}
+
+	file, err := rules.Generate(c)
+	if err != nil {
+		fmt.Fprintf(stderr, "rules: %v\n", err)
+		return 2
+	}
+	if err := file.Write(stdout); err != nil {
+		fmt.Fprintf(stderr, "rules: %v\n", err)
+		return 1
+	}
+	return 0
+}
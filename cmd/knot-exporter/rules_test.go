@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRunRules tests the rules subcommand and its flags
+func TestRunRules(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, runRules([]string{"-selector", `job="knot"`, "-servfail-ratio", "0.1", "-knot-down-for", "2m"}, &stdout, &stderr))
+	assert.Empty(t, stderr.String())
+	assert.Contains(t, stdout.String(), "expr: knot_up{job=\"knot\"} == 0\n        for: 2m\n")
+	assert.Contains(t, stdout.String(), "expr: instance:knot_servfail_responses:ratio_rate5m > 0.1\n")
+
+	for _, args := range [][]string{
+		{"-servfail-ratio", "2"},
+		{"-selector", "knot"},
+		{"-no-such-flag"},
+		{"extra"},
+	} {
+		stdout.Reset()
+		stderr.Reset()
+		assert.Equal(t, 2, runRules(args, &stdout, &stderr), args)
+		assert.NotEmpty(t, stderr.String(), args)
+		assert.Empty(t, stdout.String(), args)
+	}
+
+	stderr.Reset()
+	assert.Equal(t, 0, runRules([]string{"-h"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), " rules [flags]")
+	assert.Contains(t, stderr.String(), "-zone-expiry")
+}
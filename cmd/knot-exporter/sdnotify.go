@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/daemon"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// readyRetryInterval is the delay between collections while waiting for the
+// first successful one
+const readyRetryInterval = 5 * time.Second
+
+// notifiedCollection is the collector whose first success marks the service
+// ready
+type notifiedCollection interface {
+	prometheus.Collector
+	collectionResult
+}
+
+// sdNotify sends a state to the systemd notification socket, if any
+func sdNotify(state string) {
+	if _, err := daemon.SdNotify(false, state); err != nil {
+		slog.Warn("Failed to notify systemd", "err", err)
+	}
+}
+
+// collectOnce runs a collection of c, discarding its metrics
+func collectOnce(c prometheus.Collector) {
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+	c.Collect(ch)
+	close(ch)
+	<-done
+}
+
+// notifyReady collects until a collection succeeds, then tells systemd the
+// service is ready. Failed attempts are reported in the unit status and
+// retried every retry interval until ctx is done.
+func notifyReady(ctx context.Context, c notifiedCollection, notify func(string), retry time.Duration) {
+	for {
+		collectOnce(c)
+		err := c.LastError()
+		if err == nil {
+			notify(daemon.SdNotifyReady + "\nSTATUS=Serving metrics")
+			return
+		}
+
+		notify(fmt.Sprintf("STATUS=Waiting for a successful collection: %v", err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+// runWatchdog pings the systemd watchdog at half its timeout until ctx is
+// done. It reports liveness only: knotd or collection failures must not make
+// systemd restart the exporter.
+func runWatchdog(ctx context.Context, timeout time.Duration, notify func(string)) {
+	ticker := time.NewTicker(timeout / 2)
+	defer ticker.Stop()
+
+	for {
+		notify(daemon.SdNotifyWatchdog)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// startSystemdNotify reports readiness after the first successful collection
+// and keeps the watchdog fed, when running under systemd with Type=notify and
+// WatchdogSec respectively. It does nothing otherwise.
+func startSystemdNotify(ctx context.Context, c notifiedCollection) {
+	if sent, _ := daemon.SdNotify(false, "STATUS=Starting"); !sent {
+		return
+	}
+
+	go notifyReady(ctx, c, sdNotify, readyRetryInterval)
+
+	timeout, err := daemon.SdWatchdogEnabled(false)
+	if err != nil {
+		slog.Warn("Ignoring systemd watchdog", "err", err)
+	} else if timeout > 0 {
+		slog.Info("Feeding systemd watchdog", "interval", timeout/2)
+		go runWatchdog(ctx, timeout, sdNotify)
+	}
+}
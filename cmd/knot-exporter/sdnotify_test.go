@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyCollection fails its first collections
+type flakyCollection struct {
+	failures int
+	runs     int
+}
+
+func (f *flakyCollection) Describe(chan<- *prometheus.Desc) {}
+
+func (f *flakyCollection) Collect(chan<- prometheus.Metric) { f.runs++ }
+
+func (f *flakyCollection) LastError() error {
+	if f.runs <= f.failures {
+		return errors.New("failed to connect")
+	}
+	return nil
+}
+
+// TestNotifyReady tests that readiness waits for a successful collection
+func TestNotifyReady(t *testing.T) {
+	c := &flakyCollection{failures: 2}
+	var states []string
+	notifyReady(context.Background(), c, func(state string) { states = append(states, state) }, time.Millisecond)
+
+	assert.Equal(t, 3, c.runs)
+	assert.Equal(t, []string{
+		"STATUS=Waiting for a successful collection: failed to connect",
+		"STATUS=Waiting for a successful collection: failed to connect",
+		"READY=1\nSTATUS=Serving metrics",
+	}, states)
+}
+
+// TestNotifyReadyCancel tests giving up when the context is done
+func TestNotifyReadyCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := &flakyCollection{failures: 100}
+	var states []string
+	notifyReady(ctx, c, func(state string) { states = append(states, state) }, time.Hour)
+	assert.Equal(t, 1, c.runs)
+	assert.Len(t, states, 1)
+}
+
+// TestStartSystemdNotify tests the messages sent to the notification socket
+func TestStartSystemdNotify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", path)
+	t.Setenv("WATCHDOG_USEC", "20000")
+	t.Setenv("WATCHDOG_PID", "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	startSystemdNotify(ctx, &flakyCollection{})
+
+	received := make(map[string]int)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 1024)
+	for {
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		received[string(buf[:n])]++
+		if received["READY=1\nSTATUS=Serving metrics"] > 0 && received["WATCHDOG=1"] > 1 {
+			break
+		}
+	}
+	assert.Equal(t, 1, received["STATUS=Starting"])
+}
+
+// TestStartSystemdNotifyDisabled tests that nothing runs outside systemd
+func TestStartSystemdNotifyDisabled(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	c := &flakyCollection{}
+	startSystemdNotify(context.Background(), c)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 0, c.runs)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectionResult reports the outcome of the last collection
+type collectionResult interface {
+	LastError() error
+}
+
+// writeTextfile runs a collection and atomically replaces path with its
+// metrics in the text format read by the node_exporter textfile collector.
+// The metrics are written even when some collectors failed, the returned
+// error reports those failures.
+func writeTextfile(gatherer prometheus.Gatherer, result collectionResult, path string) error {
+	if err := prometheus.WriteToTextfile(path, gatherer); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	if err := result.LastError(); err != nil {
+		return fmt.Errorf("collection failed: %v", err)
+	}
+	return nil
+}
+
+// runTextfile writes the textfile once, or every interval until ctx is done
+// when interval is positive. In one-shot mode the error of the write is
+// returned, periodic failures are only logged.
+func runTextfile(ctx context.Context, gatherer prometheus.Gatherer, result collectionResult, path string, interval time.Duration) error {
+	if interval <= 0 {
+		return writeTextfile(gatherer, result, path)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := writeTextfile(gatherer, result, path); err != nil {
+			slog.Error("Textfile update failed", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
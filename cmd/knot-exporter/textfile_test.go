@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticResult is a collectionResult with a fixed error
+type staticResult struct {
+	err error
+}
+
+func (r staticResult) LastError() error {
+	return r.err
+}
+
+func newTextfileRegistry(t *testing.T) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "knot_test_metric", Help: "Test metric"})
+	gauge.Set(42)
+	require.NoError(t, registry.Register(gauge))
+	return registry
+}
+
+// TestWriteTextfile tests writing metrics to a textfile
+func TestWriteTextfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "knot.prom")
+
+	require.NoError(t, writeTextfile(newTextfileRegistry(t), staticResult{}, path))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "knot_test_metric 42")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+// TestWriteTextfileCollectionError tests that collection failures are
+// reported after writing the collected metrics
+func TestWriteTextfileCollectionError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "knot.prom")
+
+	err := writeTextfile(newTextfileRegistry(t), staticResult{errors.New("global stats: connection refused")}, path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "connection refused")
+	assert.FileExists(t, path)
+}
+
+// TestWriteTextfileMissingDirectory tests writing into a missing directory
+func TestWriteTextfileMissingDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "knot.prom")
+	assert.Error(t, writeTextfile(newTextfileRegistry(t), staticResult{}, path))
+}
+
+// TestRunTextfileOneShot tests that one-shot mode returns the write result
+func TestRunTextfileOneShot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "knot.prom")
+	err := runTextfile(context.Background(), newTextfileRegistry(t), staticResult{errors.New("failed")}, path, 0)
+	assert.Error(t, err)
+}
+
+// TestRunTextfilePeriodic tests that periodic mode rewrites the file until canceled
+func TestRunTextfilePeriodic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "knot.prom")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+	go func() {
+		done <- runTextfile(ctx, newTextfileRegistry(t), staticResult{errors.New("failed")}, path, 10*time.Millisecond)
+	}()
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("runTextfile did not stop")
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/collector"
+)
+
+// ReadinessSource reports the state of the collection
+type ReadinessSource interface {
+	LastCollection() time.Time
+	LastError() error
+	CollectorStatus() []collector.CollectorStatus
+	CachesWarm() bool
+	Warm()
+}
+
+// Check is the outcome of a single readiness check
+type Check struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// HealthStatus is the body of the liveness endpoint
+type HealthStatus struct {
+	Status  string  `json:"status"`
+	Version string  `json:"version"`
+	Uptime  float64 `json:"uptime_seconds"`
+}
+
+// ReadyStatus is the body of the readiness endpoint
+type ReadyStatus struct {
+	Status         string                      `json:"status"` // "ready" or "not ready"
+	Checks         map[string]Check            `json:"checks"`
+	LastCollection *time.Time                  `json:"last_collection,omitempty"`
+	Collectors     []collector.CollectorStatus `json:"collectors"`
+}
+
+// HealthyHandler reports that the process is alive, without contacting Knot
+// DNS. It always answers 200.
+func HealthyHandler(version string, started time.Time) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, HealthStatus{
+			Status:  "healthy",
+			Version: version,
+			Uptime:  time.Since(started).Seconds(),
+		})
+	})
+}
+
+// ReadyHandler reports whether the exporter can serve meaningful metrics:
+// knotd answers on the control socket (ping), the last collection succeeded
+// and the caches are warm. A collection is run first if none finished yet.
+// It answers 503 when any check fails.
+func ReadyHandler(src ReadinessSource, ping func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		src.Warm()
+
+		status := ReadyStatus{
+			Status:     "ready",
+			Checks:     make(map[string]Check),
+			Collectors: src.CollectorStatus(),
+		}
+		if status.Collectors == nil {
+			status.Collectors = []collector.CollectorStatus{}
+		}
+		if last := src.LastCollection(); !last.IsZero() {
+			status.LastCollection = &last
+		}
+
+		status.Checks["knotd"] = newCheck(ping())
+		status.Checks["collection"] = newCheck(src.LastError())
+		if src.CachesWarm() {
+			status.Checks["caches"] = Check{OK: true}
+		} else {
+			status.Checks["caches"] = Check{Error: "caches not loaded yet"}
+		}
+
+		code := http.StatusOK
+		for _, check := range status.Checks {
+			if !check.OK {
+				status.Status = "not ready"
+				code = http.StatusServiceUnavailable
+			}
+		}
+		writeJSON(w, code, status)
+	})
+}
+
+func newCheck(err error) Check {
+	if err != nil {
+		return Check{Error: err.Error()}
+	}
+	return Check{OK: true}
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/collector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticReadiness is a ReadinessSource with a fixed state
+type staticReadiness struct {
+	lastCollection time.Time
+	lastErr        error
+	statuses       []collector.CollectorStatus
+	warm           bool
+	warmed         int
+}
+
+func (s *staticReadiness) LastCollection() time.Time                    { return s.lastCollection }
+func (s *staticReadiness) LastError() error                             { return s.lastErr }
+func (s *staticReadiness) CollectorStatus() []collector.CollectorStatus { return s.statuses }
+func (s *staticReadiness) CachesWarm() bool                             { return s.warm }
+func (s *staticReadiness) Warm()                                        { s.warmed++ }
+
+func serve(t *testing.T, h http.Handler, out any) int {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), out))
+	return rec.Code
+}
+
+// TestHealthyHandler tests the liveness endpoint
+func TestHealthyHandler(t *testing.T) {
+	var status HealthStatus
+	code := serve(t, HealthyHandler("1.2.3", time.Now().Add(-time.Minute)), &status)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "healthy", status.Status)
+	assert.Equal(t, "1.2.3", status.Version)
+	assert.GreaterOrEqual(t, status.Uptime, float64(60))
+}
+
+// TestReadyHandler tests the readiness endpoint when all checks pass
+func TestReadyHandler(t *testing.T) {
+	success := time.Unix(1700000000, 0).UTC()
+	src := &staticReadiness{
+		lastCollection: success,
+		warm:           true,
+		statuses: []collector.CollectorStatus{
+			{Name: "global stats", Healthy: true, LastRun: success, LastSuccess: &success, Duration: 0.25},
+		},
+	}
+
+	var status ReadyStatus
+	code := serve(t, ReadyHandler(src, func() error { return nil }), &status)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", status.Status)
+	assert.Equal(t, map[string]Check{"knotd": {OK: true}, "collection": {OK: true}, "caches": {OK: true}}, status.Checks)
+	require.NotNil(t, status.LastCollection)
+	assert.True(t, success.Equal(*status.LastCollection))
+	require.Len(t, status.Collectors, 1)
+	assert.Equal(t, "global stats", status.Collectors[0].Name)
+	assert.Equal(t, 0.25, status.Collectors[0].Duration)
+	assert.Equal(t, 1, src.warmed)
+}
+
+// TestReadyHandlerNotReady tests that failed checks are reported with 503
+func TestReadyHandlerNotReady(t *testing.T) {
+	src := &staticReadiness{lastErr: errors.New("zone stats: timeout")}
+
+	var status ReadyStatus
+	code := serve(t, ReadyHandler(src, func() error { return errors.New("connection refused") }), &status)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "not ready", status.Status)
+	assert.Equal(t, "connection refused", status.Checks["knotd"].Error)
+	assert.Equal(t, "zone stats: timeout", status.Checks["collection"].Error)
+	assert.False(t, status.Checks["caches"].OK)
+	assert.Nil(t, status.LastCollection)
+	assert.NotNil(t, status.Collectors)
+}
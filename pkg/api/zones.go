@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/collector"
+)
+
+// Page size limits of the zone list
+const (
+	defaultZoneLimit = 100
+	maxZoneLimit     = 1000
+)
+
+// ZoneSource provides the list of zones and the status of zones, of all
+// zones when zones is empty
+type ZoneSource interface {
+	ZoneList() ([]collector.ZoneEntry, error)
+	Zones(zones []string) ([]collector.ZoneInfo, error)
+}
+
+// ZoneList is a page of the zone list
+type ZoneList struct {
+	Total      int                  `json:"total"` // Matching zones on all pages
+	Offset     int                  `json:"offset"`
+	Limit      int                  `json:"limit"`
+	NextOffset *int                 `json:"next_offset,omitempty"` // Offset of the next page, if any
+	Zones      []collector.ZoneInfo `json:"zones"`
+}
+
+// errorResponse is the body of failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// RegisterZoneHandlers adds the zone endpoints to mux:
+//
+//	GET /api/v1/zones         zone list, see ZonesHandler
+//	GET /api/v1/zones/{name}  a single zone, see ZoneHandler
+func RegisterZoneHandlers(mux *http.ServeMux, src ZoneSource) {
+	mux.Handle("GET /api/v1/zones", ZonesHandler(src))
+	mux.Handle("GET /api/v1/zones/{name}", ZoneHandler(src))
+}
+
+// ZonesHandler serves a page of the zones sorted by name. The list is
+// filtered by the zone and exclude query parameters, taking the zone filter
+// rules of the exporter (name, suffix:, regex: and catalog:), and by role.
+// Pages are selected with the limit and offset parameters. Zones are selected
+// from the zone list, the status is only queried for the zones of the page.
+func ZonesHandler(src ZoneSource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		filter, err := collector.NewZoneFilter(query["zone"], query["exclude"])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		limit, err := intParam(query.Get("limit"), defaultZoneLimit, 1, maxZoneLimit)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: %v", err))
+			return
+		}
+		offset, err := intParam(query.Get("offset"), 0, 0, -1)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid offset: %v", err))
+			return
+		}
+		role := query.Get("role")
+
+		entries, err := src.ZoneList()
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+
+		var matching []string
+		for _, entry := range entries {
+			if filter.Match(entry.Name, entry.Catalog) && (role == "" || strings.EqualFold(entry.Role, role)) {
+				matching = append(matching, entry.Name)
+			}
+		}
+
+		page := ZoneList{
+			Total:  len(matching),
+			Offset: offset,
+			Limit:  limit,
+			Zones:  []collector.ZoneInfo{},
+		}
+		if offset < len(matching) {
+			end := min(offset+limit, len(matching))
+			zones, err := src.Zones(matching[offset:end])
+			if err != nil {
+				writeError(w, http.StatusBadGateway, err)
+				return
+			}
+			page.Zones = zones
+			if end < len(matching) {
+				page.NextOffset = &end
+			}
+		}
+
+		writeJSON(w, http.StatusOK, page)
+	})
+}
+
+// ZoneHandler serves the status of the zone named by the name path value
+func ZoneHandler(src ZoneSource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if !strings.HasSuffix(name, ".") {
+			name += "."
+		}
+
+		zones, err := src.Zones([]string{name})
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+
+		for _, zone := range zones {
+			if strings.EqualFold(zone.Name, name) {
+				writeJSON(w, http.StatusOK, zone)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, fmt.Errorf("zone %s not found", name))
+	})
+}
+
+// intParam parses an integer query parameter, a negative max means unbounded
+func intParam(value string, def, minValue, maxValue int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if n < minValue || (maxValue >= 0 && n > maxValue) {
+		if maxValue >= 0 {
+			return 0, fmt.Errorf("%d is not within %d-%d", n, minValue, maxValue)
+		}
+		return 0, fmt.Errorf("%d is below %d", n, minValue)
+	}
+	return n, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Error writing API response", "err", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
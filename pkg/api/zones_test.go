@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/collector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticZones is a ZoneSource with fixed zones, recording the requested names
+type staticZones struct {
+	zones     []collector.ZoneInfo
+	err       error
+	requested [][]string
+}
+
+func (s *staticZones) ZoneList() ([]collector.ZoneEntry, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	entries := make([]collector.ZoneEntry, 0, len(s.zones))
+	for _, zone := range s.zones {
+		entries = append(entries, collector.ZoneEntry{Name: zone.Name, Role: zone.Role, Catalog: zone.Catalog})
+	}
+	return entries, nil
+}
+
+func (s *staticZones) Zones(zones []string) ([]collector.ZoneInfo, error) {
+	s.requested = append(s.requested, zones)
+	if s.err != nil {
+		return nil, s.err
+	}
+	if len(zones) == 0 {
+		return s.zones, nil
+	}
+	var out []collector.ZoneInfo
+	for _, zone := range s.zones {
+		for _, name := range zones {
+			if zone.Name == name {
+				out = append(out, zone)
+			}
+		}
+	}
+	return out, nil
+}
+
+func testZones() *staticZones {
+	return &staticZones{zones: []collector.ZoneInfo{
+		{Name: "a.example.", Role: "master", Events: []collector.ZoneEvent{}},
+		{Name: "b.example.", Role: "slave", Catalog: "catz.example.", Events: []collector.ZoneEvent{}},
+		{Name: "c.example.", Role: "slave", Catalog: "catz.example.", Events: []collector.ZoneEvent{}},
+		{Name: "example.org.", Role: "master", Events: []collector.ZoneEvent{}},
+	}}
+}
+
+// get performs a request against the zone handlers and decodes the response
+func get(t *testing.T, src ZoneSource, url string, out any) int {
+	mux := http.NewServeMux()
+	RegisterZoneHandlers(mux, src)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), out))
+	return rec.Code
+}
+
+func zoneNames(zones []collector.ZoneInfo) []string {
+	names := make([]string, 0, len(zones))
+	for _, zone := range zones {
+		names = append(names, zone.Name)
+	}
+	return names
+}
+
+// TestZonesList tests listing and filtering zones
+func TestZonesList(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"a.example.", "b.example.", "c.example.", "example.org."}},
+		{"?role=slave", []string{"b.example.", "c.example."}},
+		{"?zone=suffix:example", []string{"a.example.", "b.example.", "c.example."}},
+		{"?zone=suffix:example&exclude=b.example", []string{"a.example.", "c.example."}},
+		{"?zone=catalog:catz.example&role=SLAVE", []string{"b.example.", "c.example."}},
+		{"?zone=regex:^example", []string{"example.org."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var page ZoneList
+			require.Equal(t, http.StatusOK, get(t, testZones(), "/api/v1/zones"+tt.query, &page))
+			assert.Equal(t, tt.want, zoneNames(page.Zones))
+			assert.Equal(t, len(tt.want), page.Total)
+		})
+	}
+}
+
+// TestZonesListExactNames tests that only the zones of the page are requested
+func TestZonesListExactNames(t *testing.T) {
+	src := testZones()
+	var page ZoneList
+	require.Equal(t, http.StatusOK, get(t, src, "/api/v1/zones?zone=a.example&zone=example.org", &page))
+	assert.Equal(t, []string{"a.example.", "example.org."}, zoneNames(page.Zones))
+	assert.Equal(t, [][]string{{"a.example.", "example.org."}}, src.requested)
+}
+
+// TestZonesListPagination tests paging through the zone list
+func TestZonesListPagination(t *testing.T) {
+	var page ZoneList
+	src := testZones()
+	require.Equal(t, http.StatusOK, get(t, src, "/api/v1/zones?limit=3", &page))
+	assert.Equal(t, []string{"a.example.", "b.example.", "c.example."}, zoneNames(page.Zones))
+	assert.Equal(t, [][]string{{"a.example.", "b.example.", "c.example."}}, src.requested)
+	assert.Equal(t, 4, page.Total)
+	require.NotNil(t, page.NextOffset)
+	assert.Equal(t, 3, *page.NextOffset)
+
+	page = ZoneList{}
+	require.Equal(t, http.StatusOK, get(t, testZones(), fmt.Sprintf("/api/v1/zones?limit=3&offset=%d", 3), &page))
+	assert.Equal(t, []string{"example.org."}, zoneNames(page.Zones))
+	assert.Nil(t, page.NextOffset)
+
+	page = ZoneList{}
+	src = testZones()
+	require.Equal(t, http.StatusOK, get(t, src, "/api/v1/zones?offset=10", &page))
+	assert.Empty(t, page.Zones)
+	assert.NotNil(t, page.Zones)
+	assert.Empty(t, src.requested)
+}
+
+// TestZonesListErrors tests rejected requests and failures of Knot DNS
+func TestZonesListErrors(t *testing.T) {
+	for _, query := range []string{"?limit=0", "?limit=5000", "?offset=-1", "?limit=x", "?zone=regex:("} {
+		var resp errorResponse
+		assert.Equal(t, http.StatusBadRequest, get(t, testZones(), "/api/v1/zones"+query, &resp), query)
+		assert.NotEmpty(t, resp.Error)
+	}
+
+	var resp errorResponse
+	src := &staticZones{err: errors.New("connection refused")}
+	assert.Equal(t, http.StatusBadGateway, get(t, src, "/api/v1/zones", &resp))
+	assert.Contains(t, resp.Error, "connection refused")
+}
+
+// TestZoneGet tests fetching a single zone
+func TestZoneGet(t *testing.T) {
+	src := testZones()
+	var zone collector.ZoneInfo
+	require.Equal(t, http.StatusOK, get(t, src, "/api/v1/zones/b.example", &zone))
+	assert.Equal(t, "b.example.", zone.Name)
+	assert.Equal(t, "catz.example.", zone.Catalog)
+	assert.Equal(t, [][]string{{"b.example."}}, src.requested)
+
+	var resp errorResponse
+	assert.Equal(t, http.StatusNotFound, get(t, src, "/api/v1/zones/missing.example.", &resp))
+	assert.Contains(t, resp.Error, "missing.example.")
+
+	src = &staticZones{err: errors.New("timeout")}
+	assert.Equal(t, http.StatusBadGateway, get(t, src, "/api/v1/zones/b.example", &resp))
+}
@@ -0,0 +1,153 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// MetricInfo describes an exported metric family
+type MetricInfo struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"` // gauge, counter, histogram or untyped
+	Help      string   `json:"help"`
+	Labels    []string `json:"labels"`
+	Collector string   `json:"collector,omitempty"` // Empty for metrics of the exporter itself
+	Dynamic   bool     `json:"dynamic"`             // Only known once Knot DNS reported it
+}
+
+// UpMetric is the name of the metric telling whether Knot DNS is reachable,
+// exported by every collection that queries Knot DNS
+const UpMetric = "knot_up"
+
+// catalogEntry is a metric family with a fixed descriptor
+type catalogEntry struct {
+	collector string
+	desc      [2]*prometheus.Desc
+	valueType prometheus.ValueType
+}
+
+// single makes a descriptor pair of a metric that has no %s_total variant
+func single(desc *prometheus.Desc) [2]*prometheus.Desc {
+	return [2]*prometheus.Desc{desc, desc}
+}
+
+// StaticCatalog returns the metric families with fixed names of every
+// collector, enabled or not, in the naming scheme and metric types of the
+// collector. Relabeling is not applied.
+func (c *KnotCollector) StaticCatalog() ([]MetricInfo, error) {
+	entries := []catalogEntry{
+		{"", single(buildInfoDesc), prometheus.GaugeValue},
+		{"", single(dedupScrapesDesc), prometheus.CounterValue},
+		{"", single(droppedSeriesDesc), prometheus.GaugeValue},
+		{"", single(knotUpDesc), prometheus.GaugeValue},
+		{"", single(reloadSuccessfulDesc), prometheus.GaugeValue},
+		{"", single(reloadTimestampDesc), prometheus.GaugeValue},
+		{"", single(reloadsDesc), prometheus.CounterValue},
+		{"meminfo", c.descs.memoryUsage, prometheus.GaugeValue},
+		{"meminfo", c.descs.memoryKinds, prometheus.GaugeValue},
+		{"zone-serial", c.descs.zoneSerial, prometheus.GaugeValue},
+		{"zone-status", c.descs.zoneStatusRefresh, prometheus.GaugeValue},
+		{"zone-status", c.descs.zoneStatusExpiration, prometheus.GaugeValue},
+		{"zone-status", c.descs.zoneStatusResign, prometheus.GaugeValue},
+		{"zone-timers", c.descs.zoneRefresh, prometheus.GaugeValue},
+		{"zone-timers", c.descs.zoneRetry, prometheus.GaugeValue},
+		{"zone-timers", c.descs.zoneExpiration, prometheus.GaugeValue},
+	}
+
+	var infos []MetricInfo
+	for _, entry := range entries {
+		legacy := c.legacyMetricTypes && entry.desc[0] != entry.desc[1]
+		if legacy || entry.valueType == prometheus.GaugeValue {
+			info, err := descInfo(entry.desc[0], "gauge", entry.collector)
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+		if legacy || entry.valueType == prometheus.CounterValue {
+			info, err := descInfo(entry.desc[1], "counter", entry.collector)
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+	}
+	return MergeCatalogs(infos), nil
+}
+
+// descInfo describes the metric family of a descriptor
+func descInfo(desc *prometheus.Desc, metricType, collector string) (MetricInfo, error) {
+	meta, exists := lookupDesc(desc)
+	if !exists {
+		return MetricInfo{}, fmt.Errorf("unknown descriptor %s", desc)
+	}
+	return MetricInfo{Name: meta.name, Type: metricType, Help: meta.help, Labels: append([]string{}, meta.labels...), Collector: collector}, nil
+}
+
+// FamilyCatalog describes gathered metric families, as discovered from the
+// collector with the given name
+func FamilyCatalog(families []*dto.MetricFamily, collector string) []MetricInfo {
+	infos := make([]MetricInfo, 0, len(families))
+	for _, family := range families {
+		info := MetricInfo{
+			Name:      family.GetName(),
+			Type:      strings.ToLower(family.GetType().String()),
+			Help:      family.GetHelp(),
+			Labels:    []string{},
+			Collector: collector,
+			Dynamic:   true,
+		}
+		for _, metric := range family.GetMetric() {
+			for _, pair := range metric.GetLabel() {
+				info.Labels = append(info.Labels, pair.GetName())
+			}
+		}
+		infos = append(infos, info)
+	}
+	return MergeCatalogs(infos)
+}
+
+// MergeCatalogs merges catalogs into one sorted by metric name. A family
+// listed more than once is described by its first occurrence, with the
+// labels of all of them.
+func MergeCatalogs(catalogs ...[]MetricInfo) []MetricInfo {
+	byName := make(map[string]*MetricInfo)
+	var names []string
+	for _, catalog := range catalogs {
+		for _, info := range catalog {
+			merged, exists := byName[info.Name]
+			if !exists {
+				info.Labels = append([]string{}, info.Labels...)
+				byName[info.Name] = &info
+				names = append(names, info.Name)
+				continue
+			}
+			merged.Labels = append(merged.Labels, info.Labels...)
+		}
+	}
+	sort.Strings(names)
+
+	infos := make([]MetricInfo, 0, len(names))
+	for _, name := range names {
+		info := byName[name]
+		sort.Strings(info.Labels)
+		info.Labels = compactStrings(info.Labels)
+		infos = append(infos, *info)
+	}
+	return infos
+}
+
+// compactStrings removes consecutive duplicates from a sorted slice
+func compactStrings(s []string) []string {
+	out := s[:0]
+	for _, v := range s {
+		if len(out) == 0 || v != out[len(out)-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
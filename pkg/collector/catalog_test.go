@@ -0,0 +1,114 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// findMetricInfo returns the catalog entry of a metric family
+func findMetricInfo(t *testing.T, infos []MetricInfo, name string) MetricInfo {
+	t.Helper()
+	for _, info := range infos {
+		if info.Name == name {
+			return info
+		}
+	}
+	t.Fatalf("metric %s not in catalog", name)
+	return MetricInfo{}
+}
+
+// TestStaticCatalog tests the catalog of fixed name metrics in each naming
+// scheme and metric type mode
+func TestStaticCatalog(t *testing.T) {
+	c := NewKnotCollector("/tmp/knot.sock", 1000, false, false, false, false, false, false)
+	infos, err := c.StaticCatalog()
+	require.NoError(t, err)
+
+	assert.Equal(t, MetricInfo{
+		Name:   "knot_build_info",
+		Type:   "gauge",
+		Help:   "Build information about the exporter and libknot",
+		Labels: []string{"build_time", "git_commit", "go_version", "libknot_version", "platform", "version"},
+	}, findMetricInfo(t, infos, "knot_build_info"))
+	assert.Equal(t, MetricInfo{
+		Name:      "knot_zone_refresh_seconds",
+		Type:      "gauge",
+		Help:      "Zone SOA refresh timer",
+		Labels:    []string{"zone"},
+		Collector: "zone-timers",
+	}, findMetricInfo(t, infos, "knot_zone_refresh_seconds"))
+	assert.Equal(t, "counter", findMetricInfo(t, infos, "knot_exporter_config_reloads_total").Type)
+	assert.Equal(t, []string{}, findMetricInfo(t, infos, "knot_exporter_deduplicated_scrapes_total").Labels)
+	assert.Len(t, infos, 16)
+	for i := 1; i < len(infos); i++ {
+		assert.Less(t, infos[i-1].Name, infos[i].Name)
+	}
+
+	// Legacy mode adds the %s_total variants
+	c = NewKnotCollector("/tmp/knot.sock", 1000, false, false, false, false, false, false, WithLegacyMetricTypes(true))
+	infos, err = c.StaticCatalog()
+	require.NoError(t, err)
+	assert.Equal(t, "counter", findMetricInfo(t, infos, "knot_zone_serial_total").Type)
+	assert.Equal(t, "gauge", findMetricInfo(t, infos, "knot_zone_serial").Type)
+	assert.Len(t, infos, 25)
+
+	// The Python scheme shares knot_zone_stats and has constant labels
+	c = NewKnotCollector("/tmp/knot.sock", 1000, false, false, false, false, false, false, WithNamingScheme(NamingSchemePython))
+	infos, err = c.StaticCatalog()
+	require.NoError(t, err)
+	zoneStats := findMetricInfo(t, infos, "knot_zone_stats")
+	assert.Equal(t, []string{"section", "type", "zone"}, zoneStats.Labels)
+	assert.Equal(t, "zone-serial", zoneStats.Collector)
+	assert.Equal(t, []string{"section", "type"}, findMetricInfo(t, infos, "knot_memory_usage").Labels)
+}
+
+// TestFamilyCatalog tests the catalog of gathered metric families merged with
+// the static one
+func TestFamilyCatalog(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "knot_stats_query_type_total", Help: "Global statistic: query-type"}, []string{"module", "type"})
+	counter.WithLabelValues("mod-stats", "A").Add(3)
+	registry.MustRegister(counter)
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "knot_zone_serial", Help: "Zone serial number from Knot DNS"}, []string{"zone", "catalog"})
+	gauge.WithLabelValues("example.com.", "").Set(1)
+	registry.MustRegister(gauge)
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	discovered := FamilyCatalog(families, "global-stats")
+	assert.Equal(t, []MetricInfo{
+		{
+			Name:      "knot_stats_query_type_total",
+			Type:      "counter",
+			Help:      "Global statistic: query-type",
+			Labels:    []string{"module", "type"},
+			Collector: "global-stats",
+			Dynamic:   true,
+		},
+		{
+			Name:      "knot_zone_serial",
+			Type:      "gauge",
+			Help:      "Zone serial number from Knot DNS",
+			Labels:    []string{"catalog", "zone"},
+			Collector: "global-stats",
+			Dynamic:   true,
+		},
+	}, discovered)
+
+	c := NewKnotCollector("/tmp/knot.sock", 1000, false, false, false, false, false, false)
+	static, err := c.StaticCatalog()
+	require.NoError(t, err)
+	merged := MergeCatalogs(static, discovered)
+	assert.Len(t, merged, len(static)+1)
+	assert.Equal(t, MetricInfo{
+		Name:      "knot_zone_serial",
+		Type:      "gauge",
+		Help:      "Zone serial number from Knot DNS",
+		Labels:    []string{"catalog", "zone"},
+		Collector: "zone-serial",
+	}, findMetricInfo(t, merged, "knot_zone_serial"))
+	assert.True(t, findMetricInfo(t, merged, "knot_stats_query_type_total").Dynamic)
+}
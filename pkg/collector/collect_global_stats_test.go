@@ -1,11 +1,15 @@
 package collector
 
 import (
+	"bytes"
+	"log/slog"
+	"strings"
 	"testing"
 
 	"github.com/CZ-NIC/knot-exporter/pkg/libknot"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestCollectGlobalStats tests the collectGlobalStats method
@@ -49,8 +53,8 @@ func TestCollectGlobalStats(t *testing.T) {
 		metricCount++
 	}
 
-	// Should have 4 metrics (2 for each value - gauge and counter)
-	assert.Equal(t, 4, metricCount)
+	// Should have 2 metrics (1 counter for each value)
+	assert.Equal(t, 2, metricCount)
 
 	// Verify all expectations
 	mockCtl.AssertExpectations(t)
@@ -126,3 +130,39 @@ func TestCollectGlobalStats_Error(t *testing.T) {
 	// Verify all expectations
 	mockCtl.AssertExpectations(t)
 }
+
+// TestCollectGlobalStats_Logging tests the attributes of debug records and the
+// rate limiting of parse warnings
+func TestCollectGlobalStats_Logging(t *testing.T) {
+	mockCtl := new(MockLibknotCtl)
+	mockCtl.On("SendCommand", "stats").Return(nil)
+	for i := 0; i < 3; i++ {
+		mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+			Section: "server",
+			Item:    "query.total",
+			ID:      "udp",
+			Data:    "not-a-number",
+		}, nil).Once()
+	}
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeBlock, nil, nil).Once()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	collector := NewKnotCollector("/test", 1000, true, true, true, true, true, true, WithLogger(logger))
+	ch := make(chan prometheus.Metric, 10)
+	assert.NoError(t, collector.collectGlobalStats(mockCtl, ch))
+
+	var debug, warn []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		assert.Contains(t, line, "command=stats")
+		if strings.Contains(line, "level=DEBUG") {
+			debug = append(debug, line)
+		} else {
+			warn = append(warn, line)
+		}
+	}
+	// Every response is logged, not just the first ones
+	assert.Len(t, debug, 5)
+	require.Len(t, warn, 1)
+	assert.Contains(t, warn[0], `level=WARN msg="Failed to parse statistic value" command=stats section=server item=query.total id=udp value=not-a-number`)
+}
@@ -1,11 +1,13 @@
 package collector
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/CZ-NIC/knot-exporter/pkg/libknot"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestCollectZoneStatusInfo tests the collectZoneStatusInfo method
@@ -65,8 +67,8 @@ func TestCollectZoneStatusInfo(t *testing.T) {
 		metricCount++
 	}
 
-	// Should have 6 metrics (2 for each value - gauge and counter for serial, refresh, and expiration)
-	assert.Equal(t, 6, metricCount)
+	// Should have 3 metrics (1 gauge for each of serial, refresh, and expiration)
+	assert.Equal(t, 3, metricCount)
 
 	// Verify expectations
 	mockCtl.AssertExpectations(t)
@@ -167,9 +169,26 @@ func TestCollectZoneStatistics(t *testing.T) {
 		metricCount++
 	}
 
-	// Should have 6 metrics (2 for each value - gauge and counter)
-	assert.Equal(t, 6, metricCount)
+	// Should have 3 metrics (1 counter for each value)
+	assert.Equal(t, 3, metricCount)
 
 	// Verify expectations
 	mockCtl.AssertExpectations(t)
 }
+
+// TestCollectZoneStatusResign tests the DNSSEC re-sign timer of signed zones
+func TestCollectZoneStatusResign(t *testing.T) {
+	server := loadFixtureServer(t, filepath.Join("testdata", "knotd", "responses.json"))
+	collector := NewKnotCollector("/test", 1000, false, false, false, true, true, false)
+	collector.newCtl = ctlFactory(server)
+
+	ch := make(chan prometheus.Metric, 100)
+	collector.collect(ch)
+	metrics := drainMetrics(t, ch)
+
+	require.Len(t, metrics["knot_zone_status_resign_seconds"], 1)
+	assert.Equal(t, metricSample{labels: map[string]string{"zone": "example.com."}, value: 6*86400 + 23*3600},
+		metrics["knot_zone_status_resign_seconds"][0])
+	assert.Len(t, metrics["knot_zone_serial"], 2)
+	assert.Len(t, metrics["knot_zone_status_expiration_seconds"], 1)
+}
@@ -45,8 +45,8 @@ func TestCollectZoneTimerInfo(t *testing.T) {
 		metricCount++
 	}
 
-	// Should have 12 metrics (2 zones × 3 timer types × 2 metric types (gauge/counter))
-	assert.Equal(t, 12, metricCount)
+	// Should have 6 metrics (2 zones × 3 timer types)
+	assert.Equal(t, 6, metricCount)
 
 	// Verify expectations
 	mockCtl.AssertExpectations(t)
@@ -137,8 +137,8 @@ func TestCollectZoneTimerInfo_ValidAndInvalidSOA(t *testing.T) {
 		metricCount++
 	}
 
-	// Should have 6 metrics (1 zone × 3 timer types × 2 metric types (gauge/counter))
-	assert.Equal(t, 6, metricCount)
+	// Should have 3 metrics (1 zone × 3 timer types)
+	assert.Equal(t, 3, metricCount)
 
 	// Verify expectations
 	mockCtl.AssertExpectations(t)
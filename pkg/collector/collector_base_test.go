@@ -99,13 +99,13 @@ func TestGetProcessMemory(t *testing.T) {
 
 	// Test with self (should return > 0 if running process)
 	pid := os.Getpid()
-	mem := getProcessMemory(pid)
+	mem := getProcessMemory("/proc", pid)
 
 	// On normal systems, the test process should use some memory
 	assert.Greater(t, mem, uint64(0), "Expected non-zero memory usage for test process")
 
 	// Test with non-existent PID
-	mem = getProcessMemory(-1)
+	mem = getProcessMemory("/proc", -1)
 	assert.Equal(t, uint64(0), mem, "Expected zero memory for invalid PID")
 }
 
@@ -113,8 +113,9 @@ func TestGetProcessMemory(t *testing.T) {
 func TestMemoryUsage(t *testing.T) {
 	// This is hard to test directly since it depends on having knotd running
 	// We'll just ensure it returns a map and doesn't panic
-	usage := memoryUsage()
-	assert.IsType(t, map[string]uint64{}, usage)
+	c := NewKnotCollector("/nonexistent/socket.sock", 1000, true, false, false, false, false, false)
+	usage := c.memoryUsage()
+	assert.IsType(t, map[int]uint64{}, usage)
 }
 
 // TestSendMetrics tests the sendMetrics function
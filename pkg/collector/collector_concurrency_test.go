@@ -0,0 +1,98 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot/scripted"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+// sentCommands returns the names of the commands a scripted server received
+func sentCommands(server *scripted.Server) []string {
+	var names []string
+	for _, sent := range server.Sent("") {
+		names = append(names, sent.Cmd)
+	}
+	return names
+}
+
+// TestCollectRunsCommandsOnSeparateConnections tests that every command gets
+// its own control connection
+func TestCollectRunsCommandsOnSeparateConnections(t *testing.T) {
+	server := &scripted.Server{}
+	collector := NewKnotCollector("/test", 1000, false, true, true, true, true, true)
+	collector.newCtl = ctlFactory(server)
+
+	ch := make(chan prometheus.Metric, 10)
+	collector.Collect(ch)
+	close(ch)
+
+	assert.ElementsMatch(t, []string{"stats", "zone-status", "zone-stats", "zone-read"}, sentCommands(server))
+	assert.Equal(t, 1, server.Peak(), "Default collection should be sequential")
+	assert.Equal(t, 0, server.Active(), "All connections should be closed")
+}
+
+// TestCollectConcurrencyLimit tests that no more than the configured number of
+// connections are open at once
+func TestCollectConcurrencyLimit(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxConcurrency int
+		expectedPeak   int
+	}{
+		{"sequential", 1, 1},
+		{"limited", 2, 2},
+		{"unlimited", 10, 4},
+		{"invalid treated as sequential", 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &scripted.Server{Release: make(chan struct{})}
+			collector := NewKnotCollector("/test", 1000, false, true, true, true, true, true,
+				WithMaxConcurrency(tt.maxConcurrency))
+			collector.newCtl = ctlFactory(server)
+
+			done := make(chan struct{})
+			go func() {
+				ch := make(chan prometheus.Metric, 10)
+				collector.Collect(ch)
+				close(done)
+			}()
+
+			// Wait until as many commands as allowed are blocked in flight
+			assert.Eventually(t, func() bool {
+				return len(server.Sent("")) == tt.expectedPeak
+			}, time.Second, time.Millisecond)
+			close(server.Release)
+			<-done
+
+			assert.Equal(t, tt.expectedPeak, server.Peak())
+			assert.Len(t, server.Sent(""), 4)
+		})
+	}
+}
+
+// TestCollectConnectFailure tests that a failed connection does not stop the
+// remaining commands
+func TestCollectConnectFailure(t *testing.T) {
+	server := &scripted.Server{}
+	calls := 0
+	collector := NewKnotCollector("/test", 1000, false, true, true, false, false, false)
+	collector.newCtl = func() KnotCtlInterface {
+		calls++
+		if calls == 1 {
+			return nil
+		}
+		return server.NewCtl()
+	}
+
+	ch := make(chan prometheus.Metric, 10)
+	assert.NotPanics(t, func() { collector.Collect(ch) })
+	close(ch)
+
+	assert.Equal(t, 2, calls)
+	assert.Len(t, server.Sent(""), 1)
+}
@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCollectDeduplicatesConcurrentScrapes tests that a scrape arriving while a
+// collection is in progress shares its result
+func TestCollectDeduplicatesConcurrentScrapes(t *testing.T) {
+	collector := NewKnotCollector("/nonexistent", 1000, false, false, false, false, false, false)
+
+	// Pretend another scrape has started a collection
+	call := &collection{done: make(chan struct{})}
+	collector.inflight = call
+
+	ch := make(chan prometheus.Metric, 10)
+	finished := make(chan struct{})
+	go func() {
+		collector.Collect(ch)
+		close(finished)
+	}()
+
+	// Wait until the scrape has joined the in-progress collection
+	require.Eventually(t, func() bool {
+		collector.mu.Lock()
+		defer collector.mu.Unlock()
+		return collector.dedupScrapes == 1
+	}, time.Second, time.Millisecond)
+
+	// Finish the shared collection with a single marker metric
+	marker := prometheus.MustNewConstMetric(buildInfoDesc, prometheus.GaugeValue, 1,
+		"v", "t", "c", "go", "knot", "linux/amd64")
+	call.metrics = []prometheus.Metric{marker}
+	close(call.done)
+	<-finished
+	close(ch)
+
+	var metrics []prometheus.Metric
+	for m := range ch {
+		metrics = append(metrics, m)
+	}
+
+	// Shared marker plus the deduplication counter
+	require.Len(t, metrics, 2)
+	assert.Equal(t, marker, metrics[0])
+
+	var m dto.Metric
+	require.NoError(t, metrics[1].Write(&m))
+	assert.Equal(t, float64(1), m.GetCounter().GetValue())
+}
+
+// TestCollectWithoutConcurrentScrapes tests that sequential scrapes each run
+// their own collection
+func TestCollectWithoutConcurrentScrapes(t *testing.T) {
+	collector := NewKnotCollector("/nonexistent", 1000, false, false, false, false, false, false)
+
+	for i := 0; i < 3; i++ {
+		ch := make(chan prometheus.Metric, 10)
+		collector.Collect(ch)
+		close(ch)
+	}
+
+	assert.Nil(t, collector.inflight)
+	assert.Equal(t, uint64(0), collector.dedupScrapes)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/CZ-NIC/knot-exporter/pkg/libknot"
 )
@@ -166,3 +167,61 @@ func TestCollectWithReceiveError(t *testing.T) {
 	// Verify expectations
 	mockCtl.AssertExpectations(t)
 }
+
+// TestLastError tests that the errors of the last collection are reported
+func TestLastError(t *testing.T) {
+	mockCtl := new(MockLibknotCtl)
+	mockCtl.On("Connect", "/test").Return(nil)
+	mockCtl.On("SetTimeout", 1000).Return()
+	mockCtl.On("Close").Return()
+	mockCtl.On("SendCommand", "stats").Return(CreateCtlErrorSend("test error")).Once()
+
+	collector := NewKnotCollector("/test", 1000, false, true, false, false, false, false)
+	collector.newCtl = func() KnotCtlInterface { return mockCtl }
+	assert.NoError(t, collector.LastError())
+
+	ch := make(chan prometheus.Metric, 10)
+	collector.Collect(ch)
+	err := collector.LastError()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "global stats")
+	}
+
+	// A successful collection clears the error
+	mockCtl.On("SendCommand", "stats").Return(nil).Once()
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeBlock, nil, nil).Once()
+	ch = make(chan prometheus.Metric, 10)
+	collector.Collect(ch)
+	assert.NoError(t, collector.LastError())
+}
+
+// TestKnotUp tests that knot_up reports whether Knot DNS could be reached
+func TestKnotUp(t *testing.T) {
+	mockCtl := new(MockLibknotCtl)
+	mockCtl.On("Connect", "/test").Return(CreateCtlErrorConnect("fake error")).Once()
+	mockCtl.On("Close").Return()
+
+	collector := NewKnotCollector("/test", 1000, false, true, false, false, false, false)
+	collector.newCtl = func() KnotCtlInterface { return mockCtl }
+	ch := make(chan prometheus.Metric, 10)
+	collector.collect(ch)
+	metrics := drainMetrics(t, ch)
+	require.Len(t, metrics["knot_up"], 1)
+	assert.Equal(t, 0.0, metrics["knot_up"][0].value)
+
+	mockCtl.On("Connect", "/test").Return(nil).Once()
+	mockCtl.On("SetTimeout", 1000).Return()
+	mockCtl.On("SendCommand", "stats").Return(nil).Once()
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeBlock, nil, nil).Once()
+	ch = make(chan prometheus.Metric, 10)
+	collector.collect(ch)
+	metrics = drainMetrics(t, ch)
+	require.Len(t, metrics["knot_up"], 1)
+	assert.Equal(t, 1.0, metrics["knot_up"][0].value)
+
+	// Without control commands, Knot DNS isn't queried
+	collector = NewKnotCollector("/test", 1000, false, false, false, false, false, false)
+	ch = make(chan prometheus.Metric, 10)
+	collector.collect(ch)
+	assert.NotContains(t, drainMetrics(t, ch), "knot_up")
+}
@@ -78,10 +78,11 @@ func TestCollectorOptions(t *testing.T) {
 // TestMemoryUsageWithNoProcess tests memoryUsage when no knotd process exists
 func TestMemoryUsageWithNoProcess(t *testing.T) {
 	// This should return an empty map when knotd is not running
-	usage := memoryUsage()
+	c := NewKnotCollector("/nonexistent/socket.sock", 1000, true, false, false, false, false, false)
+	usage := c.memoryUsage()
 	assert.NotNil(t, usage)
 	// Map should be empty or have no valid entries when knotd is not running
-	assert.IsType(t, map[string]uint64{}, usage)
+	assert.IsType(t, map[int]uint64{}, usage)
 }
 
 // TestGetProcessMemoryInvalidPID tests getProcessMemory with invalid PIDs
@@ -98,7 +99,7 @@ func TestGetProcessMemoryInvalidPID(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			memory := getProcessMemory(tt.pid)
+			memory := getProcessMemory("/proc", tt.pid)
 			assert.Equal(t, uint64(0), memory)
 		})
 	}
@@ -108,7 +109,7 @@ func TestGetProcessMemoryInvalidPID(t *testing.T) {
 func TestGetProcessMemorySelfProcess(t *testing.T) {
 	// Test with the current process PID (should have some memory usage)
 	pid := 1 // init process should always exist
-	memory := getProcessMemory(pid)
+	memory := getProcessMemory("/proc", pid)
 	// Memory could be 0 if we can't read /proc/1/status (permission issue)
 	// or > 0 if we can read it
 	assert.GreaterOrEqual(t, memory, uint64(0))
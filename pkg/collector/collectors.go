@@ -1,18 +1,17 @@
 package collector
 
 import (
-	"bufio"
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"os"
-	"os/exec"
+	"log/slog"
 	"runtime"
 	"strconv"
-	"strings"
 	"sync"
+	"time"
 
 	"github.com/CZ-NIC/knot-exporter/pkg/libknot"
+	"github.com/CZ-NIC/knot-exporter/pkg/logging"
 	"github.com/CZ-NIC/knot-exporter/pkg/utils"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -34,6 +33,13 @@ var (
 		nil,
 	)
 
+	memoryKindsDesc = makeDescPair(
+		"knot_memory_bytes",
+		"Memory of Knot DNS processes by kind: rss, pss, uss, swap, anon, file or virtual",
+		[]string{"pid", "kind"},
+		nil,
+	)
+
 	zoneSerialDesc = makeDescPair(
 		"knot_zone_serial",
 		"Zone serial number from Knot DNS",
@@ -78,13 +84,36 @@ var (
 		nil,
 	)
 
+	zoneStatusResignDesc = makeDescPair(
+		"knot_zone_status_resign_seconds",
+		"Zone DNSSEC re-sign timer from zone-status",
+		[]string{"zone"},
+		nil,
+	)
+
+	// Whether Knot DNS answered on the control socket
+	knotUpDesc = newDesc(
+		UpMetric,
+		"Whether the last collection could connect to Knot DNS",
+		nil,
+		nil,
+	)
+
 	// Build info metric
-	buildInfoDesc = prometheus.NewDesc(
+	buildInfoDesc = newDesc(
 		"knot_build_info",
 		"Build information about the exporter and libknot",
 		[]string{"version", "build_time", "git_commit", "go_version", "libknot_version", "platform"},
 		nil,
 	)
+
+	// Scrapes answered from a collection started by another concurrent scrape
+	dedupScrapesDesc = newDesc(
+		"knot_exporter_deduplicated_scrapes_total",
+		"Number of scrapes served from a collection already in progress",
+		nil,
+		nil,
+	)
 )
 
 // KnotCtlInterface defines an interface for Knot DNS control operations
@@ -94,13 +123,14 @@ type KnotCtlInterface interface {
 	SetTimeout(timeout int)
 	SendCommand(cmd string) error
 	SendCommandWithType(cmd string, rtype string) error
+	SendZoneCommand(cmd string, rtype string, zones []string) error
 	ReceiveResponse() (libknot.CtlType, *libknot.CtlData, error)
 }
 
 func makeDescPair(fqName, help string, variableLabels []string, constLabels prometheus.Labels) [2]*prometheus.Desc {
 	return [2]*prometheus.Desc{
-		prometheus.NewDesc(fqName, help, variableLabels, constLabels),
-		prometheus.NewDesc(fqName+"_total", help, variableLabels, constLabels),
+		newDesc(fqName, help, variableLabels, constLabels),
+		newDesc(fqName+"_total", help, variableLabels, constLabels),
 	}
 }
 
@@ -114,51 +144,29 @@ var (
 	zoneStatsDescMutex   = sync.RWMutex{}
 )
 
-func memoryUsage() map[string]uint64 {
-	out := make(map[string]uint64)
-	cmd := exec.Command("pidof", "knotd")
-	output, err := cmd.Output()
+// memoryUsage returns the resident memory of the knotd process of the control
+// socket by PID
+func (c *KnotCollector) memoryUsage() map[int]uint64 {
+	out := make(map[int]uint64)
+	pid, err := c.knotdPID()
 	if err != nil {
+		c.parseWarnings.Log(context.Background(), c.logger, slog.LevelWarn, "meminfo", "Failed to find the knotd process", "err", err)
 		return out
 	}
-	pids := strings.Fields(string(output))
-	for _, pidStr := range pids {
-		if pid, err := strconv.Atoi(pidStr); err == nil {
-			if usage := getProcessMemory(pid); usage > 0 {
-				out[pidStr] = usage
-			}
-		}
+	if usage := getProcessMemory(c.procRoot, pid); usage > 0 {
+		out[pid] = usage
 	}
 	return out
 }
 
-func getProcessMemory(pid int) uint64 {
-	// Validate pid is reasonable
-	if pid <= 0 || pid > 4194304 { // Max reasonable PID (4M)
-		return 0
-	}
-
-	content, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
-	if err != nil {
-		return 0
-	}
-
-	// Search for VmRSS line in the content
-	scanner := bufio.NewScanner(bytes.NewReader(content))
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "VmRSS:") {
-			fields := strings.Fields(line)
-			if len(fields) >= 2 {
-				if kb, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
-					return kb * 1024
-				}
-			}
-			break
-		}
-	}
+// globalStatsMetricName returns the metric name of a global statistics item
+func globalStatsMetricName(item string) string {
+	return fmt.Sprintf("knot_stats_%s", utils.SanitizeMetricName(item))
+}
 
-	return 0
+// zoneStatsMetricName returns the metric name of a zone statistics item
+func zoneStatsMetricName(item string) string {
+	return fmt.Sprintf("knot_zone_stats_%s", utils.SanitizeMetricName(item))
 }
 
 // Get or create a metric descriptor for global stats
@@ -180,18 +188,18 @@ func getGlobalStatsDescriptor(item string) [2]*prometheus.Desc {
 	}
 
 	// Create metric name based on item
-	metricName := fmt.Sprintf("knot_stats_%s", utils.SanitizeMetricName(item))
+	metricName := globalStatsMetricName(item)
 
 	// Create help text
 	help := fmt.Sprintf("Global statistic: %s", item)
 
-	// Create labels - always include section and type (using ID as type)
-	labels := []string{"module", "type"}
+	// Create labels - always include section and the item specific ID label
+	labels := []string{"module", statsIDLabel(item)}
 
 	desc := makeDescPair(metricName, help, labels, nil)
 	globalStatsDescriptors[item] = desc
 
-	utils.DebugLog("Created new global stats descriptor: %s with labels: %v", metricName, labels)
+	slog.Debug("Created global stats descriptor", "metric", metricName, "labels", labels)
 	return desc
 }
 
@@ -214,18 +222,18 @@ func getZoneStatsDescriptor(item string) [2]*prometheus.Desc {
 	}
 
 	// Create metric name based on item
-	metricName := fmt.Sprintf("knot_zone_stats_%s", utils.SanitizeMetricName(item))
+	metricName := zoneStatsMetricName(item)
 
 	// Create help text
 	help := fmt.Sprintf("Zone statistic: %s", item)
 
-	// Create labels - always include zone, section and type (using ID as type)
-	labels := []string{"zone", "module", "type"}
+	// Create labels - always include zone, section and the item specific ID label
+	labels := []string{"zone", "module", statsIDLabel(item)}
 
 	desc := makeDescPair(metricName, help, labels, nil)
 	zoneStatsDescriptors[item] = desc
 
-	utils.DebugLog("Created new zone stats descriptor: %s with labels: %v", metricName, labels)
+	slog.Debug("Created zone stats descriptor", "metric", metricName, "labels", labels)
 	return desc
 }
 
@@ -239,19 +247,199 @@ type KnotCollector struct {
 	collectZoneStatus bool
 	collectZoneTimers bool
 	collectZoneSerial bool
+	maxConcurrency    int                     // Maximum number of parallel control connections
+	newCtl            func() KnotCtlInterface // Control connection factory
+	pidFile           string                  // File holding the PID of knotd, empty asks the control socket
+	procRoot          string                  // procfs mount of the PID namespace of knotd
+	pidMu             sync.Mutex              // Guards the cached peer, apart from mu so a slow socket doesn't block status
+	peerPID           int                     // Cached PID of the control socket peer, 0 if unknown
+	peerStart         uint64                  // Start time of the peerPID process, tells it from a later one with its PID
+	zoneFilter        *ZoneFilter             // Zones to collect per-zone metrics for, nil means all
+	zoneBatchSize     int                     // Zones per zone-targeted command, 0 queries all zones at once
+	zoneListTTL       time.Duration           // How long an enumerated zone list is reused
+	zoneListMu        sync.Mutex              // Guards the zone list, the zone API reads it during collections
+	zoneList          []string                // Zones known to Knot DNS, as of zoneListFetched
+	zoneCatalogs      map[string]string       // Catalog zone of each member zone, as of zoneListFetched
+	zoneRoles         map[string]string       // Role of each zone, as of zoneListFetched
+	zoneListFetched   time.Time               // When the zone list was last loaded
+	limiter           *cardinalityLimiter     // Enforces cardinality limits
+	legacyMetricTypes bool                    // Send every value as both a gauge and a %s_total counter
+	nativeHistograms  bool                    // Add native buckets to histograms of bucket-style statistics
+	namingScheme      NamingScheme            // Metric names and labels to export
+	descs             *staticDescs            // Descriptors of fixed name metrics of the naming scheme
+	relabeler         *relabeler              // Rewrites metrics before they are exported, nil means none
+	logger            *slog.Logger
+	parseWarnings     *logging.Limiter // Rate limits warnings about unparsable values
 	mu                sync.Mutex
-	libknotVersion    string // Cache the libknot version
+	inflight          *collection // Collection in progress, shared by concurrent scrapes
+	lastErr           error       // Errors of the last finished collection
+	dedupScrapes      uint64      // Scrapes that joined an in-progress collection
+	libknotVersion    string      // Cache the libknot version
+	errMu             sync.Mutex
+	collectErrs       []error                  // Errors of the collection in progress
+	collectConnected  bool                     // Whether a task of the collection in progress connected
+	collectRuns       map[string]*collectorRun // Collector runs of the collection in progress
+	statuses          map[string]*CollectorStatus
+	lastCollection    time.Time // When the last collection finished
+	zoneListWarm      bool      // Whether the zone list was loaded, as of lastCollection
+}
+
+// collection holds the result of a single collection run shared by all
+// scrapes that arrived while it was in progress
+type collection struct {
+	done    chan struct{}
+	metrics []prometheus.Metric
+}
+
+// Option configures optional behaviour of a KnotCollector
+type Option func(*KnotCollector)
+
+// WithMaxConcurrency sets how many control commands may run in parallel,
+// each over its own connection. Values below 1 mean sequential collection.
+func WithMaxConcurrency(n int) Option {
+	return func(c *KnotCollector) {
+		if n < 1 {
+			n = 1
+		}
+		c.maxConcurrency = n
+	}
+}
+
+// WithZoneFilter restricts all per-zone metrics to zones selected by the filter
+func WithZoneFilter(f *ZoneFilter) Option {
+	return func(c *KnotCollector) {
+		c.zoneFilter = f
+	}
+}
+
+// WithZoneBatchSize makes per-zone collectors enumerate zones first and query
+// them in batches of n zones, each batch over its own connection, instead of
+// letting Knot DNS dump all zones in one response. Zero disables batching.
+func WithZoneBatchSize(n int) Option {
+	return func(c *KnotCollector) {
+		if n < 0 {
+			n = 0
+		}
+		c.zoneBatchSize = n
+	}
+}
+
+// WithZoneListTTL sets how long an enumerated zone list (and catalog
+// membership) is reused before Knot DNS is asked again. Zero enumerates zones
+// on every collection.
+func WithZoneListTTL(ttl time.Duration) Option {
+	return func(c *KnotCollector) {
+		c.zoneListTTL = ttl
+	}
+}
+
+// WithLimits bounds the number of exported zones and statistics series
+func WithLimits(limits Limits) Option {
+	return func(c *KnotCollector) {
+		c.limiter = newCardinalityLimiter(limits)
+	}
+}
+
+// WithLegacyMetricTypes makes the collector send every value twice, as a
+// gauge and as a %s_total counter, like earlier versions did
+func WithLegacyMetricTypes(legacy bool) Option {
+	return func(c *KnotCollector) {
+		c.legacyMetricTypes = legacy
+	}
+}
+
+// WithNativeHistograms adds native histogram buckets to the histograms built
+// from bucket-style statistics items, next to the classic buckets
+func WithNativeHistograms(native bool) Option {
+	return func(c *KnotCollector) {
+		c.nativeHistograms = native
+	}
+}
+
+// WithNamingScheme selects the metric names and labels to export. The Python
+// scheme exports every value as a gauge, without histograms.
+func WithNamingScheme(scheme NamingScheme) Option {
+	return func(c *KnotCollector) {
+		c.namingScheme = scheme
+		if scheme == NamingSchemePython {
+			c.descs = pythonDescs
+		} else {
+			c.descs = defaultDescs
+		}
+	}
+}
+
+// WithRelabelConfig rewrites, renames or drops metrics and adds static labels
+// before they are exported. An invalid configuration is ignored.
+func WithRelabelConfig(cfg *RelabelConfig) Option {
+	return func(c *KnotCollector) {
+		if cfg == nil {
+			c.relabeler = nil
+			return
+		}
+		r, err := newRelabeler(cfg)
+		if err != nil {
+			slog.Warn("Ignoring invalid relabel config", "err", err)
+			return
+		}
+		c.relabeler = r
+	}
+}
+
+// WithLogger sets the logger of the collector, the default logger is used
+// otherwise
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *KnotCollector) {
+		c.logger = logger
+	}
+}
+
+// WithControlFactory sets how control connections are created, such as to
+// replay recorded responses instead of talking to Knot DNS
+func WithControlFactory(newCtl func() KnotCtlInterface) Option {
+	return func(c *KnotCollector) {
+		c.newCtl = newCtl
+	}
+}
+
+// WithPIDFile sets a file to read the PID of knotd from, such as the pidfile
+// of its configuration. By default the PID is that of the peer of the control
+// socket.
+func WithPIDFile(path string) Option {
+	return func(c *KnotCollector) {
+		c.pidFile = path
+	}
+}
+
+// WithProcRoot sets where the procfs of the PID namespace of knotd is
+// mounted, /proc by default
+func WithProcRoot(root string) Option {
+	return func(c *KnotCollector) {
+		if root != "" {
+			c.procRoot = root
+		}
+	}
+}
+
+// newKnotCtl allocates a libknot control object, returning a nil interface
+// on failure
+func newKnotCtl() KnotCtlInterface {
+	if ctl := libknot.New(); ctl != nil {
+		return ctl
+	}
+	return nil
 }
 
 // NewKnotCollector creates a new KnotCollector with the specified configuration
 func NewKnotCollector(sockPath string, timeout int,
 	collectMemInfo, collectStats, collectZoneStats,
-	collectZoneStatus, collectZoneSerial, collectZoneTimers bool) *KnotCollector {
+	collectZoneStatus, collectZoneSerial, collectZoneTimers bool,
+	opts ...Option) *KnotCollector {
 
 	// Get libknot version once during initialization
 	libknotVersion := libknot.GetVersion()
 
-	return &KnotCollector{
+	c := &KnotCollector{
 		sockPath:          sockPath,
 		timeout:           timeout,
 		collectMemInfo:    collectMemInfo,
@@ -260,10 +448,41 @@ func NewKnotCollector(sockPath string, timeout int,
 		collectZoneStatus: collectZoneStatus,
 		collectZoneTimers: collectZoneTimers,
 		collectZoneSerial: collectZoneSerial,
+		maxConcurrency:    1,
+		newCtl:            newKnotCtl,
+		procRoot:          "/proc",
+		limiter:           newCardinalityLimiter(Limits{}),
+		namingScheme:      NamingSchemeDefault,
+		descs:             defaultDescs,
+		logger:            slog.Default(),
+		parseWarnings:     logging.NewLimiter(parseWarningInterval),
 		libknotVersion:    libknotVersion,
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// The Python naming scheme has no %s_total variants to send
+	if c.namingScheme == NamingSchemePython {
+		c.legacyMetricTypes = false
+	}
+
+	return c
+}
+
+// parseWarningInterval is how often a warning about values of one kind that
+// can't be parsed is logged at most
+const parseWarningInterval = time.Minute
+
+// warnParse logs a warning about a value that can't be parsed, rate limited
+// per key
+func (c *KnotCollector) warnParse(logger *slog.Logger, key, msg string, args ...any) {
+	c.parseWarnings.Log(context.Background(), logger, slog.LevelWarn, key, msg, args...)
 }
 
+// convertStateTime converts a zone-status timer to seconds, nil means the
+// timer isn't set
 func (c *KnotCollector) convertStateTime(timeStr string) *float64 {
 	// Check for special states
 	if utils.IsPrefixIn(timeStr, []string{"pending", "running", "frozen"}) {
@@ -279,41 +498,52 @@ func (c *KnotCollector) convertStateTime(timeStr string) *float64 {
 		return &seconds
 	}
 
-	log.Printf("error: unable to parse time string: %s", timeStr)
+	c.warnParse(c.logger, "zone-status/timer", "Unable to parse zone timer", "command", "zone-status", "value", timeStr)
 
 	return nil
 }
 
-// Describe implements prometheus.Collector interface
+// Describe implements prometheus.Collector interface. With relabeling, the
+// exported names are only known after collection and the collector is
+// unchecked.
 func (c *KnotCollector) Describe(ch chan<- *prometheus.Desc) {
+	if c.relabeler != nil {
+		return
+	}
+
 	sendDesc := func(desc [2]*prometheus.Desc) {
-		ch <- desc[0]
-		ch <- desc[1]
+		describeMetric(ch, desc, prometheus.GaugeValue, c.legacyMetricTypes)
 	}
 
 	// Always include build info
 	ch <- buildInfoDesc
+	ch <- dedupScrapesDesc
+	ch <- droppedSeriesDesc
+	ch <- knotUpDesc
 
 	if c.collectMemInfo {
-		sendDesc(memoryUsageDesc)
+		sendDesc(c.descs.memoryUsage)
+		sendDesc(c.descs.memoryKinds)
 	}
 
 	// For global stats and zone stats, we can't pre-describe all metrics since they're dynamic
 	// Prometheus will handle this automatically during collection
 
 	if c.collectZoneSerial {
-		sendDesc(zoneSerialDesc)
+		sendDesc(c.descs.zoneSerial)
 	}
 	if c.collectZoneTimers {
-		sendDesc(zoneRefreshDesc)
-		sendDesc(zoneRetryDesc)
-		sendDesc(zoneExpirationDesc)
-		sendDesc(zoneStatusExpirationDesc)
-		sendDesc(zoneStatusRefreshDesc)
+		sendDesc(c.descs.zoneRefresh)
+		sendDesc(c.descs.zoneRetry)
+		sendDesc(c.descs.zoneExpiration)
+		sendDesc(c.descs.zoneStatusExpiration)
+		sendDesc(c.descs.zoneStatusRefresh)
+		sendDesc(c.descs.zoneStatusResign)
 	}
 }
 
-// send both the base metric (gauge) and its %s_total variant (counter)
+// send both the base metric (gauge) and its %s_total variant (counter), used
+// in legacy mode
 func sendMetrics(ch chan<- prometheus.Metric, desc [2]*prometheus.Desc, value float64, labelValues ...string) {
 	ch <- prometheus.MustNewConstMetric(
 		desc[0],
@@ -329,10 +559,86 @@ func sendMetrics(ch chan<- prometheus.Metric, desc [2]*prometheus.Desc, value fl
 	)
 }
 
-// Collect implements prometheus.Collector interface
+// Collect implements prometheus.Collector interface. Scrapes arriving while
+// a collection is already running wait for it and share its result instead
+// of querying Knot DNS again.
 func (c *KnotCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	call := c.inflight
+	if call != nil {
+		c.dedupScrapes++
+		c.mu.Unlock()
+		c.logger.Debug("Collection already in progress, waiting for its result")
+		<-call.done
+	} else {
+		call = &collection{done: make(chan struct{})}
+		c.inflight = call
+		c.mu.Unlock()
+
+		call.metrics = c.collectAll()
+
+		c.mu.Lock()
+		c.inflight = nil
+		c.mu.Unlock()
+		close(call.done)
+	}
+
+	for _, m := range call.metrics {
+		ch <- m
+	}
+
+	c.mu.Lock()
+	dedupScrapes := c.dedupScrapes
+	c.mu.Unlock()
+	if m, ok := c.relabel(prometheus.MustNewConstMetric(dedupScrapesDesc, prometheus.CounterValue, float64(dedupScrapes))); ok {
+		ch <- m
+	}
+}
+
+// relabel applies the relabel config to a metric, reporting false when the
+// metric is dropped
+func (c *KnotCollector) relabel(m prometheus.Metric) (prometheus.Metric, bool) {
+	if c.relabeler == nil {
+		return m, true
+	}
+	return c.relabeler.apply(m)
+}
+
+// collectAll runs a full collection and returns the gathered metrics
+func (c *KnotCollector) collectAll() []prometheus.Metric {
+	var metrics []prometheus.Metric
+	ch := make(chan prometheus.Metric, 256)
+	done := make(chan struct{})
+	go func() {
+		for m := range ch {
+			if m, ok := c.relabel(m); ok {
+				metrics = append(metrics, m)
+			}
+		}
+		close(done)
+	}()
+
+	c.collect(ch)
+	close(ch)
+	<-done
+
+	return metrics
+}
+
+// LastError returns the errors of the most recent finished collection, or
+// nil when all enabled collectors succeeded
+func (c *KnotCollector) LastError() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.lastErr
+}
+
+// collect queries Knot DNS and sends all enabled metrics to ch
+func (c *KnotCollector) collect(ch chan<- prometheus.Metric) {
+	c.errMu.Lock()
+	c.collectErrs = nil
+	c.collectConnected = false
+	c.errMu.Unlock()
 
 	// Always emit build info metric
 	platform := fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH)
@@ -348,104 +654,205 @@ func (c *KnotCollector) Collect(ch chan<- prometheus.Metric) {
 		platform,
 	)
 
-	ctl := libknot.New()
-	if ctl == nil {
-		log.Printf("Failed to allocate knot control object")
-		return
-	}
-	defer ctl.Close()
-
-	err := ctl.Connect(c.sockPath)
-	if err != nil {
-		log.Printf("Failed to connect to socket: %v", err)
-		return
-	}
-	ctl.SetTimeout(c.timeout)
-
 	// Collect memory information
+	memFound := false
 	if c.collectMemInfo {
-		memUsage := memoryUsage()
-		for pid, usage := range memUsage {
-			sendMetrics(ch, memoryUsageDesc, float64(usage), pid)
+		usage := c.memoryUsage()
+		memFound = len(usage) > 0
+		for pid, usage := range usage {
+			label := strconv.Itoa(pid)
+			sendMetric(ch, c.descs.memoryUsage, prometheus.GaugeValue, c.legacyMetricTypes, float64(usage), label)
+			for kind, value := range getProcessMemoryKinds(c.procRoot, pid) {
+				sendMetric(ch, c.descs.memoryKinds, prometheus.GaugeValue, c.legacyMetricTypes, float64(value), label, kind)
+			}
 		}
 	}
 
-	// Collect global statistics (only once per collection)
-	if c.collectStats {
-		if err := c.collectGlobalStats(ctl, ch); err != nil {
-			log.Printf("Failed to collect global stats: %v", err)
+	// Batched collection needs the list of zones up front, catalog based zone
+	// filter rules need to know zone membership first
+	perZone := c.collectZoneStatus || c.collectZoneSerial || c.collectZoneStats || c.collectZoneTimers
+	batched := perZone && c.zoneBatchSize > 0
+	var batches [][]string
+	if batched {
+		zones, ok := c.selectedZones(ch)
+		if ok {
+			batches = c.zoneBatches(zones)
+		} else {
+			// The failed enumeration is a collection error, per-zone metrics
+			// are still collected, just not in batches
+			c.logger.Warn("Collecting per-zone metrics without batches, the zone list is unavailable")
+			batched = false
 		}
+	} else if perZone && c.zoneFilter.NeedsCatalogs() {
+		c.refreshZoneList(ch)
 	}
 
-	// We need a new connection for each command due to protocol limitations
-	ctl.Close()
-	ctl = libknot.New()
-	if ctl == nil {
-		return
-	}
-	defer ctl.Close()
-	if err := ctl.Connect(c.sockPath); err != nil {
-		return
+	var tasks []collectTask
+
+	// Collect global statistics (only once per collection)
+	if c.collectStats {
+		tasks = append(tasks, collectTask{name: "global stats", run: c.collectGlobalStats})
 	}
-	ctl.SetTimeout(c.timeout)
 
 	// Collect zone status (includes serials if enabled)
 	if c.collectZoneStatus || c.collectZoneSerial {
-		if err := c.collectZoneStatusInfo(ctl, ch); err != nil {
-			log.Printf("Failed to collect zone status: %v", err)
-		}
+		tasks = append(tasks, c.zoneTasks("zone status", batched, batches, c.collectZoneStatusBatch)...)
 	}
 
 	// Collect zone statistics if enabled
 	if c.collectZoneStats {
-		// Need another fresh connection
-		ctl.Close()
-		ctl = libknot.New()
-		if ctl == nil {
-			return
-		}
-		defer ctl.Close()
-		if err := ctl.Connect(c.sockPath); err != nil {
-			log.Printf("Failed to reconnect for zone stats: %v", err)
-			return
-		}
-		ctl.SetTimeout(c.timeout)
-
-		if err := c.collectZoneStatistics(ctl, ch); err != nil {
-			log.Printf("Failed to collect zone stats: %v", err)
-		}
+		tasks = append(tasks, c.zoneTasks("zone stats", batched, batches, c.collectZoneStatisticsBatch)...)
 	}
 
 	// Collect zone timers if enabled
 	if c.collectZoneTimers {
-		// Need another fresh connection
-		ctl.Close()
-		ctl = libknot.New()
-		if ctl == nil {
-			return
-		}
-		defer ctl.Close()
-		if err := ctl.Connect(c.sockPath); err != nil {
-			log.Printf("Failed to reconnect for zone timers: %v", err)
-			return
-		}
-		ctl.SetTimeout(c.timeout)
+		tasks = append(tasks, c.zoneTasks("zone timers", batched, batches, c.collectZoneTimerBatch)...)
+	}
 
-		if err := c.collectZoneTimerInfo(ctl, ch); err != nil {
-			log.Printf("Failed to collect zone timers: %v", err)
+	c.runTasks(tasks, ch)
+	c.limiter.finish(ch, c.legacyMetricTypes, c.nativeHistograms)
+
+	c.errMu.Lock()
+	err := errors.Join(c.collectErrs...)
+	connected := c.collectConnected
+	c.errMu.Unlock()
+
+	// Knot DNS is up when any control connection succeeded, so that a busy
+	// socket refusing some of the parallel connections doesn't flap it. With
+	// the meminfo collector alone, it's up when its process was found.
+	if len(tasks) > 0 || c.collectMemInfo {
+		up := 0.0
+		if connected || (len(tasks) == 0 && memFound) {
+			up = 1
 		}
+		ch <- prometheus.MustNewConstMetric(knotUpDesc, prometheus.GaugeValue, up)
 	}
+	c.mu.Lock()
+	c.lastErr = err
+	c.mu.Unlock()
+	c.updateStatus(time.Now())
+}
+
+// collectTask is a single control command based collection step
+type collectTask struct {
+	name  string
+	group string // Collector the task is a batch of, empty if it's the name
+	run   func(ctl KnotCtlInterface, ch chan<- prometheus.Metric) error
+}
+
+// collector returns the name of the collector the task belongs to
+func (t collectTask) collector() string {
+	if t.group != "" {
+		return t.group
+	}
+	return t.name
+}
+
+// runTasks executes the tasks with at most maxConcurrency of them in flight.
+// We need a new connection for each command due to protocol limitations, so
+// every task gets its own control connection.
+func (c *KnotCollector) runTasks(tasks []collectTask, ch chan<- prometheus.Metric) {
+	sem := make(chan struct{}, c.maxConcurrency)
+	var wg sync.WaitGroup
+
+	for _, task := range tasks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(task collectTask) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			start := time.Now()
+			ctl, err := c.connect()
+			if err != nil {
+				c.logger.Error("Failed to connect", "collector", task.name, "err", err)
+				c.recordError(fmt.Errorf("%s: %v", task.name, err))
+				c.recordRun(task, start, err)
+				return
+			}
+			defer ctl.Close()
+			c.errMu.Lock()
+			c.collectConnected = true
+			c.errMu.Unlock()
+
+			err = task.run(ctl, ch)
+			if err != nil {
+				c.logger.Error("Collection failed", "collector", task.name, "err", err)
+				c.recordError(fmt.Errorf("%s: %v", task.name, err))
+			}
+			c.recordRun(task, start, err)
+		}(task)
+	}
+
+	wg.Wait()
+}
+
+// recordError records a failure of the collection in progress
+func (c *KnotCollector) recordError(err error) {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	c.collectErrs = append(c.collectErrs, err)
+}
+
+// zoneSelected reports whether per-zone metrics should be emitted for the zone
+func (c *KnotCollector) zoneSelected(zone string) bool {
+	return c.zoneFilter.Match(zone, c.zoneCatalog(zone))
+}
+
+// sendZoneCommand sends a per-zone command for the given zones. Without
+// explicit zones, the zones selected by the filter are passed as arguments
+// when they are known up front so that Knot DNS only processes those zones.
+func (c *KnotCollector) sendZoneCommand(ctl KnotCtlInterface, cmd string, rtype string, zones []string) error {
+	if len(zones) > 0 {
+		c.logger.Debug("Sending command for a batch of zones", "command", cmd, "zones", len(zones))
+		return ctl.SendZoneCommand(cmd, rtype, zones)
+	}
+	if zones, ok := c.zoneFilter.ExactZones(); ok {
+		c.logger.Debug("Sending command for selected zones", "command", cmd, "zones", len(zones))
+		return ctl.SendZoneCommand(cmd, rtype, zones)
+	}
+	if rtype != "" {
+		return ctl.SendCommandWithType(cmd, rtype)
+	}
+	return ctl.SendCommand(cmd)
+}
+
+// sendZoneMetrics sends a per-zone metric unless the zone is over the zone limit
+func (c *KnotCollector) sendZoneMetrics(ch chan<- prometheus.Metric, family string, desc [2]*prometheus.Desc, value float64, zone string) {
+	if !c.limiter.admitZone(zone) {
+		c.limiter.drop(family, limitReasonZones)
+		return
+	}
+	sendMetric(ch, desc, prometheus.GaugeValue, c.legacyMetricTypes, value, zone)
+}
+
+// connect opens a new control connection to Knot DNS
+func (c *KnotCollector) connect() (KnotCtlInterface, error) {
+	ctl := c.newCtl()
+	if ctl == nil {
+		return nil, fmt.Errorf("failed to allocate knot control object")
+	}
+
+	if err := ctl.Connect(c.sockPath); err != nil {
+		ctl.Close()
+		return nil, fmt.Errorf("failed to connect to socket: %v", err)
+	}
+	ctl.SetTimeout(c.timeout)
+
+	return ctl, nil
 }
 
 // Helper methods for collecting different types of metrics
 func (c *KnotCollector) collectGlobalStats(ctl KnotCtlInterface, ch chan<- prometheus.Metric) error {
-	utils.DebugLog("Collecting global stats...")
+	logger := c.logger.With("command", "stats")
+	logger.Debug("Collecting global stats")
 	if err := ctl.SendCommand("stats"); err != nil {
 		return err
 	}
 
 	count := 0
 	responseCount := 0
+	histograms := newHistogramSet()
 
 	for {
 		dataType, data, err := ctl.ReceiveResponse()
@@ -455,48 +862,81 @@ func (c *KnotCollector) collectGlobalStats(ctl KnotCtlInterface, ch chan<- prome
 
 		responseCount++
 
-		// Debug every response for the first 20 responses
-		if utils.DebugMode && responseCount <= 20 {
-			utils.DebugLog("Response %d: type=%d, section='%s', item='%s', id='%s', zone='%s', data='%s'",
-				responseCount, dataType, data.Section, data.Item, data.ID, data.Zone, data.Data)
-		}
-
 		// Break on BLOCK (end of response) or END (end of connection)
 		if dataType == libknot.CtlTypeBlock || dataType == libknot.CtlTypeEnd {
-			utils.DebugLog("Stats collection ended: type=%d, total responses=%d", dataType, responseCount)
 			break
 		}
+		logger.Debug("Received response", "response", responseCount, "type", dataType,
+			"section", data.Section, "item", data.Item, "id", data.ID, "data", data.Data)
 
 		// Process both DATA (type=1) and EXTRA (type=2) responses
 		if (dataType == libknot.CtlTypeData || dataType == libknot.CtlTypeExtra) && data.Item != "" && data.Data != "" {
 			count++
 			if value, err := strconv.ParseFloat(data.Data, 64); err == nil {
-				utils.DebugLog("Global stat: section='%s', item='%s', id='%s', value=%s",
-					data.Section, data.Item, data.ID, data.Data)
+				if c.namingScheme == NamingSchemePython {
+					c.sendPythonStats(ch, "", data.Section, data.Item, data.ID, value)
+					continue
+				}
+
+				family := globalStatsMetricName(data.Item)
+
+				// Bucket-style items make up a histogram
+				if upper, ok := parseBucketID(data.ID); ok && !c.legacyMetricTypes {
+					if !c.limiter.admitDescriptor("global-histogram/" + data.Item) {
+						c.limiter.drop(family, limitReasonDescriptors)
+						continue
+					}
+					desc := getGlobalStatsHistDescriptor(data.Item)
+					if c.limiter.admitSeries(family, data.Section) {
+						histograms.add(desc, upper, uint64(value), data.Section)
+					} else {
+						c.limiter.aggregateHistogram(family, limitReasonSeries, desc, upper, uint64(value),
+							overflowLabelValue)
+					}
+					continue
+				}
+
+				if !c.limiter.admitDescriptor("global/" + data.Item) {
+					c.limiter.drop(family, limitReasonDescriptors)
+					continue
+				}
 
 				// Get the dynamic metric descriptor
 				desc := getGlobalStatsDescriptor(data.Item)
-				sendMetrics(ch, desc, value,
+				valueType := statsValueType(data.Section, data.Item)
+				if !c.limiter.admitSeries(family, data.Section, data.ID) {
+					c.limiter.aggregate(family, limitReasonSeries, desc, valueType, value, data.Section, overflowLabelValue)
+					continue
+				}
+				sendMetric(ch, desc, valueType, c.legacyMetricTypes, value,
 					data.Section, // section label
-					data.ID,      // type label (using ID field, can be empty)
+					data.ID,      // ID label (rcode, protocol, ... or type, can be empty)
 				)
 			} else {
-				utils.DebugLog("Failed to parse value '%s' for item '%s'", data.Data, data.Item)
+				c.warnParse(logger, "stats/"+data.Item, "Failed to parse statistic value",
+					"section", data.Section, "item", data.Item, "id", data.ID, "value", data.Data)
 			}
 		} else if dataType == libknot.CtlTypeData || dataType == libknot.CtlTypeExtra {
-			// Debug cases where we skip metrics
-			utils.DebugLog("Skipped metric: type=%d, item='%s', data='%s' (missing item or data)",
-				dataType, data.Item, data.Data)
+			logger.Debug("Skipped response without item or data", "type", dataType, "item", data.Item, "data", data.Data)
 		}
 	}
 
-	utils.DebugLog("Global stats: collected %d statistics from %d total responses", count, responseCount)
+	histograms.send(ch, c.nativeHistograms)
+
+	logger.Debug("Collected global stats", "statistics", count, "responses", responseCount)
 	return nil
 }
 
 func (c *KnotCollector) collectZoneStatusInfo(ctl KnotCtlInterface, ch chan<- prometheus.Metric) error {
-	utils.DebugLog("Collecting zone status...")
-	if err := ctl.SendCommand("zone-status"); err != nil {
+	return c.collectZoneStatusBatch(ctl, ch, nil)
+}
+
+// collectZoneStatusBatch collects zone status for the given zones, or for all
+// selected zones when zones is empty
+func (c *KnotCollector) collectZoneStatusBatch(ctl KnotCtlInterface, ch chan<- prometheus.Metric, zones []string) error {
+	logger := c.logger.With("command", "zone-status")
+	logger.Debug("Collecting zone status")
+	if err := c.sendZoneCommand(ctl, "zone-status", "", zones); err != nil {
 		return err
 	}
 
@@ -504,6 +944,7 @@ func (c *KnotCollector) collectZoneStatusInfo(ctl KnotCtlInterface, ch chan<- pr
 	responseCount := 0
 	currentZone := ""
 	responseIndex := 0
+	selected := false
 
 	for {
 		dataType, data, err := ctl.ReceiveResponse()
@@ -512,16 +953,13 @@ func (c *KnotCollector) collectZoneStatusInfo(ctl KnotCtlInterface, ch chan<- pr
 		}
 
 		responseCount++
-		if utils.DebugMode && responseCount <= 10 { // Debug first 10 records only in debug mode
-			utils.DebugLog("Zone status response %d: type=%d, section='%s', item='%s', id='%s', zone='%s', data='%s'",
-				responseCount, dataType, data.Section, data.Item, data.ID, data.Zone, data.Data)
-		}
 
 		// Break on BLOCK (end of response) or END (end of connection)
 		if dataType == libknot.CtlTypeBlock || dataType == libknot.CtlTypeEnd {
-			utils.DebugLog("Zone status collection complete, processed %d responses", responseCount)
 			break
 		}
+		logger.Debug("Received response", "response", responseCount, "type", dataType, "zone", data.Zone,
+			"section", data.Section, "item", data.Item, "id", data.ID, "data", data.Data)
 
 		// Process both DATA (type=1) and EXTRA (type=2) responses
 		if dataType == libknot.CtlTypeData || dataType == libknot.CtlTypeExtra {
@@ -531,14 +969,15 @@ func (c *KnotCollector) collectZoneStatusInfo(ctl KnotCtlInterface, ch chan<- pr
 			if dataType == libknot.CtlTypeData && data.Zone != "" && data.Zone != currentZone {
 				currentZone = data.Zone
 				responseIndex = 0
-			} else if dataType == libknot.CtlTypeExtra && currentZone != "" {
+				selected = c.zoneSelected(currentZone)
+			} else if dataType == libknot.CtlTypeExtra && currentZone != "" && selected {
 				// Type 2 (EXTRA) contains the zone details in order
 				responseIndex++
 
 				// Based on the output, position 1 appears to be the serial
 				if c.collectZoneSerial && responseIndex == 1 {
 					if serial, err := strconv.ParseFloat(data.Data, 64); err == nil {
-						sendMetrics(ch, zoneSerialDesc, serial, currentZone)
+						c.sendZoneMetrics(ch, "knot_zone_serial", c.descs.zoneSerial, serial, currentZone)
 					}
 				}
 
@@ -549,38 +988,47 @@ func (c *KnotCollector) collectZoneStatusInfo(ctl KnotCtlInterface, ch chan<- pr
 					switch responseIndex {
 					case 7: // refresh timer (appears as +1h28m44s format)
 						if seconds := c.convertStateTime(data.Data); seconds != nil {
-							sendMetrics(ch, zoneStatusRefreshDesc, *seconds, currentZone)
-							if utils.DebugMode {
-								utils.DebugLog("Zone status refresh timer: zone=%s, position=%d, value=%s, seconds=%f",
-									currentZone, responseIndex, data.Data, *seconds)
-							}
+							c.sendZoneMetrics(ch, "knot_zone_status_refresh_seconds", c.descs.zoneStatusRefresh, *seconds, currentZone)
+							logger.Debug("Zone refresh timer", "zone", currentZone, "value", data.Data, "seconds", *seconds)
 						}
 					case 9: // expiration timer (appears as +27D23h58m44s format)
 						if seconds := c.convertStateTime(data.Data); seconds != nil {
-							sendMetrics(ch, zoneStatusExpirationDesc, *seconds, currentZone)
-							if utils.DebugMode {
-								utils.DebugLog("Zone status expiration timer: zone=%s, position=%d, value=%s, seconds=%f",
-									currentZone, responseIndex, data.Data, *seconds)
-							}
+							c.sendZoneMetrics(ch, "knot_zone_status_expiration_seconds", c.descs.zoneStatusExpiration, *seconds, currentZone)
+							logger.Debug("Zone expiration timer", "zone", currentZone, "value", data.Data, "seconds", *seconds)
 						}
 					}
 				}
+
+				// The re-sign event is only listed for signed zones
+				if c.collectZoneStatus && data.Type == "DNSSEC re-sign" && data.Data != "" && data.Data != "-" {
+					if seconds := c.convertStateTime(data.Data); seconds != nil {
+						c.sendZoneMetrics(ch, "knot_zone_status_resign_seconds", c.descs.zoneStatusResign, *seconds, currentZone)
+					}
+				}
 			}
 		}
 	}
 
-	utils.DebugLog("Zone status: processed %d items from %d responses", count, responseCount)
+	logger.Debug("Collected zone status", "items", count, "responses", responseCount)
 	return nil
 }
 
 func (c *KnotCollector) collectZoneStatistics(ctl KnotCtlInterface, ch chan<- prometheus.Metric) error {
-	utils.DebugLog("Collecting zone statistics...")
-	if err := ctl.SendCommand("zone-stats"); err != nil {
+	return c.collectZoneStatisticsBatch(ctl, ch, nil)
+}
+
+// collectZoneStatisticsBatch collects zone statistics for the given zones, or
+// for all selected zones when zones is empty
+func (c *KnotCollector) collectZoneStatisticsBatch(ctl KnotCtlInterface, ch chan<- prometheus.Metric, zones []string) error {
+	logger := c.logger.With("command", "zone-stats")
+	logger.Debug("Collecting zone statistics")
+	if err := c.sendZoneCommand(ctl, "zone-stats", "", zones); err != nil {
 		return err
 	}
 
 	count := 0
 	responseCount := 0
+	histograms := newHistogramSet()
 
 	for {
 		dataType, data, err := ctl.ReceiveResponse()
@@ -589,55 +1037,104 @@ func (c *KnotCollector) collectZoneStatistics(ctl KnotCtlInterface, ch chan<- pr
 		}
 
 		responseCount++
-		if utils.DebugMode && responseCount <= 10 { // Debug first 10 responses only in debug mode
-			utils.DebugLog("Zone stats response %d: type=%d, section='%s', item='%s', id='%s', zone='%s', data='%s'",
-				responseCount, dataType, data.Section, data.Item, data.ID, data.Zone, data.Data)
-		}
 
 		// Break on BLOCK (end of response) or END (end of connection)
 		if dataType == libknot.CtlTypeBlock || dataType == libknot.CtlTypeEnd {
-			utils.DebugLog("Zone stats collection complete, processed %d responses", responseCount)
 			break
 		}
+		logger.Debug("Received response", "response", responseCount, "type", dataType, "zone", data.Zone,
+			"section", data.Section, "item", data.Item, "id", data.ID, "data", data.Data)
 
 		// Process both DATA (type=1) and EXTRA (type=2) responses
 		if (dataType == libknot.CtlTypeData || dataType == libknot.CtlTypeExtra) && data.Zone != "" && data.Item != "" && data.Data != "" {
+			if !c.zoneSelected(data.Zone) {
+				continue
+			}
+
 			count++
 			statType := data.Item
 			statSubtype := data.ID
 
 			if value, err := strconv.ParseFloat(data.Data, 64); err == nil {
-				if utils.DebugMode && count <= 15 {
-					utils.DebugLog("Zone stat: type=%d, zone=%s, section=%s, item=%s, id=%s, value=%s",
-						dataType, data.Zone, data.Section, statType, statSubtype, data.Data)
+				if c.namingScheme == NamingSchemePython {
+					c.sendPythonStats(ch, data.Zone, data.Section, statType, statSubtype, value)
+					continue
 				}
 
-				// Get the dynamic metric descriptor
+				family := zoneStatsMetricName(statType)
+
+				// Bucket-style items make up a histogram, histograms of
+				// zones over the limit are summed into zone="other"
+				if upper, ok := parseBucketID(statSubtype); ok && !c.legacyMetricTypes {
+					if !c.limiter.admitDescriptor("zone-histogram/" + statType) {
+						c.limiter.drop(family, limitReasonDescriptors)
+						continue
+					}
+					desc := getZoneStatsHistDescriptor(statType)
+					switch {
+					case !c.limiter.admitZone(data.Zone):
+						c.limiter.aggregateHistogram(family, limitReasonZones, desc, upper, uint64(value),
+							overflowLabelValue, data.Section)
+					case !c.limiter.admitSeries(family, data.Zone, data.Section):
+						c.limiter.aggregateHistogram(family, limitReasonSeries, desc, upper, uint64(value),
+							overflowLabelValue, data.Section)
+					default:
+						histograms.add(desc, upper, uint64(value), data.Zone, data.Section)
+					}
+					continue
+				}
+
+				if !c.limiter.admitDescriptor("zone/" + statType) {
+					c.limiter.drop(family, limitReasonDescriptors)
+					continue
+				}
+
+				// Get the dynamic metric descriptor, series over the limits
+				// are summed into "other"
 				desc := getZoneStatsDescriptor(statType)
-				sendMetrics(ch, desc, value,
-					data.Zone,    // zone label
-					data.Section, // section label
-					statSubtype,  // type label (using ID field)
-				)
+				valueType := statsValueType(data.Section, statType)
+				switch {
+				case !c.limiter.admitZone(data.Zone):
+					c.limiter.aggregate(family, limitReasonZones, desc, valueType, value,
+						overflowLabelValue, data.Section, statSubtype)
+				case !c.limiter.admitSeries(family, data.Zone, data.Section, statSubtype):
+					c.limiter.aggregate(family, limitReasonSeries, desc, valueType, value,
+						overflowLabelValue, data.Section, overflowLabelValue)
+				default:
+					sendMetric(ch, desc, valueType, c.legacyMetricTypes, value,
+						data.Zone,    // zone label
+						data.Section, // section label
+						statSubtype,  // ID label (rcode, protocol, ... or type)
+					)
+				}
 			} else {
-				utils.DebugLog("Failed to parse zone stat value '%s' for zone '%s', item '%s'", data.Data, data.Zone, data.Item)
+				c.warnParse(logger, "zone-stats/"+statType, "Failed to parse statistic value", "zone", data.Zone,
+					"section", data.Section, "item", statType, "id", statSubtype, "value", data.Data)
 			}
 		} else if dataType == libknot.CtlTypeData || dataType == libknot.CtlTypeExtra {
-			// Debug cases where we skip metrics
-			utils.DebugLog("Skipped zone stat: type=%d, zone='%s', item='%s', data='%s' (missing required fields)",
-				dataType, data.Zone, data.Item, data.Data)
+			logger.Debug("Skipped response without zone, item or data", "type", dataType,
+				"zone", data.Zone, "item", data.Item, "data", data.Data)
 		}
 	}
 
-	utils.DebugLog("Zone stats: collected %d statistics from %d responses", count, responseCount)
+	histograms.send(ch, c.nativeHistograms)
+
+	logger.Debug("Collected zone statistics", "statistics", count, "responses", responseCount)
 	return nil
 }
 
 func (c *KnotCollector) collectZoneTimerInfo(ctl KnotCtlInterface, ch chan<- prometheus.Metric) error {
-	utils.DebugLog("Collecting zone timers from SOA records...")
+	return c.collectZoneTimerBatch(ctl, ch, nil)
+}
+
+// collectZoneTimerBatch collects SOA timers for the given zones, or for all
+// selected zones when zones is empty
+func (c *KnotCollector) collectZoneTimerBatch(ctl KnotCtlInterface, ch chan<- prometheus.Metric, zones []string) error {
+	logger := c.logger.With("command", "zone-read")
+	logger.Debug("Collecting zone timers from SOA records")
 
 	// Use zone-read with SOA type to get only SOA records
-	if err := ctl.SendCommandWithType("zone-read", "SOA"); err != nil {
+	if err := c.sendZoneCommand(ctl, "zone-read", "SOA", zones); err != nil {
 		return fmt.Errorf("zone-read SOA command failed: %v", err)
 	}
 
@@ -651,77 +1148,31 @@ func (c *KnotCollector) collectZoneTimerInfo(ctl KnotCtlInterface, ch chan<- pro
 		}
 
 		count++
-		if utils.DebugMode && count <= 10 { // Debug first 10 records only in debug mode
-			utils.DebugLog("Zone timer response %d: type=%d, zone='%s', data='%s'",
-				count, dataType, data.Zone, data.Data)
-		}
 
 		// Break on BLOCK (end of response) or END (end of connection)
 		if dataType == libknot.CtlTypeBlock || dataType == libknot.CtlTypeEnd {
-			utils.DebugLog("Zone timers collection complete, processed %d responses", count)
 			break
 		}
+		logger.Debug("Received response", "response", count, "type", dataType, "zone", data.Zone, "data", data.Data)
 
 		// Look for SOA records
-		if dataType == libknot.CtlTypeData && data.Zone != "" {
-
-			soaFields := strings.Fields(data.Data)
-			if utils.DebugMode && count <= 5 {
-				utils.DebugLog("Zone %s: parsed %d fields: %v", data.Zone, len(soaFields), soaFields)
+		if dataType == libknot.CtlTypeData && data.Zone != "" && c.zoneSelected(data.Zone) {
+			soa, err := parseSOA(data.Data)
+			if err != nil {
+				c.warnParse(logger, "zone-read/soa", "Failed to parse SOA record", "zone", data.Zone, "err", err)
+				continue
 			}
 
-			// SOA format: "primary admin serial refresh retry expiration minimum"
-			// Must have exactly 7 fields
-			if len(soaFields) == 7 {
-				// Check if this looks like a proper SOA record
-				isPrimarySuffix := strings.HasSuffix(soaFields[0], ".")
-				isAdminValid := strings.HasSuffix(soaFields[1], ".")
-
-				if isPrimarySuffix && isAdminValid {
-					// Check if fields 2-6 are numeric
-					allNumeric := true
-					var numericValues [5]int64
-
-					for i := 2; i <= 6; i++ {
-						val, err := strconv.ParseInt(soaFields[i], 10, 64)
-						if err != nil {
-							allNumeric = false
-							break
-						}
-						numericValues[i-2] = val
-					}
-
-					if allNumeric {
-						// Refresh timer (index 3 in SOA, index 1 in our array)
-						sendMetrics(ch, zoneRefreshDesc, float64(numericValues[1]), data.Zone)
-
-						// Retry timer (index 4 in SOA, index 2 in our array)
-						sendMetrics(ch, zoneRetryDesc, float64(numericValues[2]), data.Zone)
-
-						// Expiration timer (index 5 in SOA, index 3 in our array)
-						sendMetrics(ch, zoneExpirationDesc, float64(numericValues[3]), data.Zone)
-					} else {
-						if utils.DebugMode && count <= 5 {
-							utils.DebugLog("Zone %s: numeric validation failed", data.Zone)
-						}
-					}
-				} else {
-					if utils.DebugMode && count <= 5 {
-						utils.DebugLog("Zone %s: format validation failed", data.Zone)
-					}
-				}
-			} else {
-				if utils.DebugMode && count <= 5 {
-					utils.DebugLog("Zone %s: wrong field count (%d)", data.Zone, len(soaFields))
-				}
-			}
+			c.sendZoneMetrics(ch, "knot_zone_refresh_seconds", c.descs.zoneRefresh, float64(soa.Refresh), data.Zone)
+			c.sendZoneMetrics(ch, "knot_zone_retry_seconds", c.descs.zoneRetry, float64(soa.Retry), data.Zone)
+			c.sendZoneMetrics(ch, "knot_zone_expiration_seconds", c.descs.zoneExpiration, float64(soa.Expiration), data.Zone)
 		}
 	}
 
 	if count >= maxResponses {
-		utils.DebugLog("Zone timers: stopped at maximum responses (%d)", maxResponses)
+		logger.Warn("Stopped at the maximum number of responses", "responses", maxResponses)
 	}
 
-	utils.DebugLog("Zone timers: processed SOA records for %d zones", count)
+	logger.Debug("Collected zone timers", "responses", count)
 	return nil
 }
@@ -0,0 +1,49 @@
+package collector
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// descMeta is what a descriptor was created with. The client library does
+// not expose it, relabeling and the catalog need it.
+type descMeta struct {
+	name   string
+	help   string
+	labels []string // Variable and constant label names, sorted
+}
+
+// Metadata of the descriptors created by newDesc. Those are package-level
+// descriptors and the statistics descriptors cached per item, which live as
+// long as the process. Per-instance descriptors, like the relabeler's, must
+// not be created with newDesc.
+var (
+	descMetas      = make(map[*prometheus.Desc]descMeta)
+	descMetasMutex = sync.RWMutex{}
+)
+
+// newDesc creates a descriptor and records its metadata for lookupDesc
+func newDesc(fqName, help string, variableLabels []string, constLabels prometheus.Labels) *prometheus.Desc {
+	desc := prometheus.NewDesc(fqName, help, variableLabels, constLabels)
+
+	labels := append([]string{}, variableLabels...)
+	for name := range constLabels {
+		labels = append(labels, name)
+	}
+	sort.Strings(labels)
+
+	descMetasMutex.Lock()
+	descMetas[desc] = descMeta{name: fqName, help: help, labels: labels}
+	descMetasMutex.Unlock()
+	return desc
+}
+
+// lookupDesc returns the metadata of a descriptor created by newDesc
+func lookupDesc(desc *prometheus.Desc) (descMeta, bool) {
+	descMetasMutex.RLock()
+	defer descMetasMutex.RUnlock()
+	meta, exists := descMetas[desc]
+	return meta, exists
+}
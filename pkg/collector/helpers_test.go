@@ -1,5 +1,12 @@
 package collector
 
+import (
+	"testing"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot/scripted"
+	"github.com/stretchr/testify/require"
+)
+
 // Helper functions used across multiple test files
 
 // floatPtr creates a pointer to a float64 value
@@ -7,3 +14,16 @@ package collector
 func floatPtr(f float64) *float64 {
 	return &f
 }
+
+// loadFixtureServer returns a scripted server answering with the records of
+// a recorded fixture
+func loadFixtureServer(t *testing.T, path string) *scripted.Server {
+	server, err := scripted.Load(path)
+	require.NoError(t, err)
+	return server
+}
+
+// ctlFactory returns a control connection factory of a scripted server
+func ctlFactory(server *scripted.Server) func() KnotCtlInterface {
+	return func() KnotCtlInterface { return server.NewCtl() }
+}
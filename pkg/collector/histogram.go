@@ -0,0 +1,234 @@
+package collector
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// nativeHistogramSchema is the resolution of exported native histograms,
+// bucket boundaries grow by a factor of 2^(2^-3), about 9%
+const nativeHistogramSchema = 3
+
+// Dynamic histogram descriptors for bucket-style statistics items, created on
+// demand like globalStatsDescriptors and zoneStatsDescriptors
+var (
+	globalStatsHistDescriptors = make(map[string]*prometheus.Desc)
+	globalStatsHistDescMutex   = sync.RWMutex{}
+
+	zoneStatsHistDescriptors = make(map[string]*prometheus.Desc)
+	zoneStatsHistDescMutex   = sync.RWMutex{}
+)
+
+// parseBucketID parses statistics IDs that are inclusive value ranges, such
+// as the "16-31" query and reply size buckets of mod-stats, and returns the
+// upper bound of the range
+func parseBucketID(id string) (float64, bool) {
+	lower, upper, found := strings.Cut(id, "-")
+	if !found {
+		return 0, false
+	}
+	lo, err := strconv.ParseUint(lower, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	hi, err := strconv.ParseUint(upper, 10, 64)
+	if err != nil || hi < lo {
+		return 0, false
+	}
+	return float64(hi), true
+}
+
+// getHistDescriptor gets or creates a histogram descriptor in a registry
+func getHistDescriptor(descriptors map[string]*prometheus.Desc, mu *sync.RWMutex,
+	item, metricName, help string, labels []string) *prometheus.Desc {
+
+	mu.RLock()
+	if desc, exists := descriptors[item]; exists {
+		mu.RUnlock()
+		return desc
+	}
+	mu.RUnlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// Double-check in case another goroutine created it
+	if desc, exists := descriptors[item]; exists {
+		return desc
+	}
+
+	desc := newDesc(metricName, help, labels, nil)
+	descriptors[item] = desc
+
+	slog.Debug("Created histogram descriptor", "metric", metricName, "labels", labels)
+	return desc
+}
+
+// Get or create a histogram descriptor for bucket-style global stats
+func getGlobalStatsHistDescriptor(item string) *prometheus.Desc {
+	return getHistDescriptor(globalStatsHistDescriptors, &globalStatsHistDescMutex, item,
+		globalStatsMetricName(item), fmt.Sprintf("Global statistic histogram: %s", item),
+		[]string{"module"})
+}
+
+// Get or create a histogram descriptor for bucket-style zone stats
+func getZoneStatsHistDescriptor(item string) *prometheus.Desc {
+	return getHistDescriptor(zoneStatsHistDescriptors, &zoneStatsHistDescMutex, item,
+		zoneStatsMetricName(item), fmt.Sprintf("Zone statistic histogram: %s", item),
+		[]string{"zone", "module"})
+}
+
+// histogramSeries accumulates the bucket counts of one histogram series
+type histogramSeries struct {
+	desc        *prometheus.Desc
+	labelValues []string
+	buckets     map[float64]uint64 // Non-cumulative count by inclusive upper bound
+}
+
+// histogramSet collects histogram series from bucket-style statistics items
+// of a response, as Knot DNS reports each bucket as a separate item
+type histogramSet struct {
+	series map[string]*histogramSeries
+	order  []string
+}
+
+func newHistogramSet() *histogramSet {
+	return &histogramSet{series: make(map[string]*histogramSeries)}
+}
+
+// add adds the count of a bucket with the given upper bound to a series
+func (s *histogramSet) add(desc *prometheus.Desc, upper float64, count uint64, labelValues ...string) {
+	key := desc.String() + "\xff" + strings.Join(labelValues, "\xff")
+	series, ok := s.series[key]
+	if !ok {
+		series = &histogramSeries{desc: desc, labelValues: labelValues, buckets: make(map[float64]uint64)}
+		s.series[key] = series
+		s.order = append(s.order, key)
+	}
+	series.buckets[upper] += count
+}
+
+// send emits the collected histograms, with native histogram buckets in
+// addition to the classic ones when native is set
+func (s *histogramSet) send(ch chan<- prometheus.Metric, native bool) {
+	for _, key := range s.order {
+		series := s.series[key]
+		ch <- &bucketHistogram{
+			desc:       series.desc,
+			labelPairs: prometheus.MakeLabelPairs(series.desc, series.labelValues),
+			buckets:    series.buckets,
+			native:     native,
+		}
+	}
+}
+
+// bucketHistogram is a constant histogram built from Knot DNS bucket counts.
+// Knot DNS doesn't report the sum of observed values, so the sum is NaN.
+type bucketHistogram struct {
+	desc       *prometheus.Desc
+	labelPairs []*dto.LabelPair
+	buckets    map[float64]uint64
+	native     bool
+}
+
+// Desc implements prometheus.Metric interface
+func (h *bucketHistogram) Desc() *prometheus.Desc {
+	return h.desc
+}
+
+// Write implements prometheus.Metric interface
+func (h *bucketHistogram) Write(out *dto.Metric) error {
+	bounds := make([]float64, 0, len(h.buckets))
+	for upper := range h.buckets {
+		bounds = append(bounds, upper)
+	}
+	sort.Float64s(bounds)
+
+	his := &dto.Histogram{SampleSum: proto.Float64(math.NaN())}
+	var count uint64
+	for _, upper := range bounds {
+		count += h.buckets[upper]
+		his.Bucket = append(his.Bucket, &dto.Bucket{
+			UpperBound:      proto.Float64(upper),
+			CumulativeCount: proto.Uint64(count),
+		})
+	}
+	his.SampleCount = proto.Uint64(count)
+
+	if h.native {
+		h.writeNative(his, bounds)
+	}
+
+	out.Label = h.labelPairs
+	out.Histogram = his
+	return nil
+}
+
+// writeNative adds native histogram buckets. Each Knot DNS bucket is counted
+// in the exponential bucket containing its upper bound, so the resolution is
+// the coarser of the two bucket layouts.
+func (h *bucketHistogram) writeNative(his *dto.Histogram, bounds []float64) {
+	his.Schema = proto.Int32(nativeHistogramSchema)
+	his.ZeroThreshold = proto.Float64(0)
+
+	var zero uint64
+	counts := make(map[int]int64)
+	for _, upper := range bounds {
+		if upper <= 0 {
+			zero += h.buckets[upper]
+			continue
+		}
+		counts[nativeBucketIndex(upper)] += int64(h.buckets[upper])
+	}
+	his.ZeroCount = proto.Uint64(zero)
+
+	indexes := make([]int, 0, len(counts))
+	for index := range counts {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	// Spans of consecutive buckets with delta encoded counts
+	var span *dto.BucketSpan
+	var prevIndex int
+	var prevCount int64
+	for i, index := range indexes {
+		if i == 0 || index != prevIndex+1 {
+			offset := index
+			if i > 0 {
+				offset = index - prevIndex - 1
+			}
+			span = &dto.BucketSpan{Offset: proto.Int32(int32(offset)), Length: proto.Uint32(0)}
+			his.PositiveSpan = append(his.PositiveSpan, span)
+		}
+		*span.Length++
+		his.PositiveDelta = append(his.PositiveDelta, counts[index]-prevCount)
+		prevIndex = index
+		prevCount = counts[index]
+	}
+
+	if len(his.PositiveSpan) == 0 && zero == 0 {
+		// An empty span marks the histogram as native
+		his.PositiveSpan = []*dto.BucketSpan{{Offset: proto.Int32(0), Length: proto.Uint32(0)}}
+	}
+}
+
+// nativeBucketIndex returns the index of the native histogram bucket
+// (base^(index-1), base^index] containing a positive value
+func nativeBucketIndex(value float64) int {
+	frac, exp := math.Frexp(value)
+	if frac == 0.5 {
+		// Powers of two are upper bounds of buckets
+		return (exp - 1) << nativeHistogramSchema
+	}
+	return int(math.Ceil(math.Log2(value) * (1 << nativeHistogramSchema)))
+}
@@ -0,0 +1,245 @@
+package collector
+
+import (
+	"math"
+	"testing"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseBucketID tests recognition of bucket-style statistics IDs
+func TestParseBucketID(t *testing.T) {
+	tests := []struct {
+		id       string
+		upper    float64
+		isBucket bool
+	}{
+		{"0-15", 15, true},
+		{"16-31", 31, true},
+		{"288-65535", 65535, true},
+		{"udp4", 0, false},
+		{"NXDOMAIN", 0, false},
+		{"", 0, false},
+		{"16-", 0, false},
+		{"-16", 0, false},
+		{"31-16", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			upper, ok := parseBucketID(tt.id)
+			assert.Equal(t, tt.isBucket, ok)
+			assert.Equal(t, tt.upper, upper)
+		})
+	}
+}
+
+// TestNativeBucketIndex tests the mapping of values to native histogram buckets
+func TestNativeBucketIndex(t *testing.T) {
+	assert.Equal(t, 0, nativeBucketIndex(1))
+	assert.Equal(t, 8, nativeBucketIndex(2))
+	assert.Equal(t, 32, nativeBucketIndex(16))
+	assert.Equal(t, 1, nativeBucketIndex(1.05))
+
+	// Every value lies within its bucket (base^(index-1), base^index]
+	base := math.Exp2(math.Exp2(-nativeHistogramSchema))
+	for _, value := range []float64{15, 31, 287, 65535} {
+		index := nativeBucketIndex(value)
+		assert.Greater(t, value, math.Pow(base, float64(index-1)))
+		assert.LessOrEqual(t, value, math.Pow(base, float64(index))*(1+1e-12))
+	}
+}
+
+// sizeStatsCtl returns a mock answering stats with query-size buckets
+func sizeStatsCtl() *MockLibknotCtl {
+	mockCtl := new(MockLibknotCtl)
+	mockCtl.On("SendCommand", "stats").Return(nil)
+	for _, bucket := range []struct{ id, value string }{
+		{"16-31", "5"},
+		{"0-15", "2"},
+		{"288-65535", "1"},
+	} {
+		mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+			Section: "mod-stats", Item: "hist-query-size", ID: bucket.id, Data: bucket.value,
+		}, nil).Once()
+	}
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+		Section: "mod-stats", Item: "hist-request-protocol", ID: "udp4", Data: "7",
+	}, nil).Once()
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeBlock, nil, nil).Once()
+	return mockCtl
+}
+
+// TestCollectGlobalStats_Histogram tests that bucket-style items are exported
+// as a classic cumulative histogram
+func TestCollectGlobalStats_Histogram(t *testing.T) {
+	mockCtl := sizeStatsCtl()
+	collector := NewKnotCollector("/test", 1000, false, true, false, false, false, false)
+	ch := make(chan prometheus.Metric, 10)
+
+	require.NoError(t, collector.collectGlobalStats(mockCtl, ch))
+	close(ch)
+
+	var histogram *dto.Metric
+	names := make(map[string]bool)
+	for m := range ch {
+		names[metricName(m)] = true
+		if metricName(m) == "knot_stats_hist_query_size" {
+			histogram = &dto.Metric{}
+			require.NoError(t, m.Write(histogram))
+		}
+	}
+
+	assert.Equal(t, map[string]bool{
+		"knot_stats_hist_query_size":             true,
+		"knot_stats_hist_request_protocol_total": true,
+	}, names)
+
+	require.NotNil(t, histogram)
+	require.NotNil(t, histogram.Histogram)
+	assert.Equal(t, "mod-stats", histogram.Label[0].GetValue())
+	assert.Equal(t, uint64(8), histogram.Histogram.GetSampleCount())
+	assert.True(t, math.IsNaN(histogram.Histogram.GetSampleSum()))
+
+	buckets := histogram.Histogram.GetBucket()
+	require.Len(t, buckets, 3)
+	assert.Equal(t, float64(15), buckets[0].GetUpperBound())
+	assert.Equal(t, uint64(2), buckets[0].GetCumulativeCount())
+	assert.Equal(t, float64(31), buckets[1].GetUpperBound())
+	assert.Equal(t, uint64(7), buckets[1].GetCumulativeCount())
+	assert.Equal(t, float64(65535), buckets[2].GetUpperBound())
+	assert.Equal(t, uint64(8), buckets[2].GetCumulativeCount())
+
+	// No native buckets unless enabled
+	assert.Nil(t, histogram.Histogram.Schema)
+	mockCtl.AssertExpectations(t)
+}
+
+// TestCollectGlobalStats_NativeHistogram tests the native buckets of histograms
+func TestCollectGlobalStats_NativeHistogram(t *testing.T) {
+	mockCtl := sizeStatsCtl()
+	collector := NewKnotCollector("/test", 1000, false, true, false, false, false, false,
+		WithNativeHistograms(true))
+
+	registry := prometheus.NewPedanticRegistry()
+	ch := make(chan prometheus.Metric, 10)
+	require.NoError(t, collector.collectGlobalStats(mockCtl, ch))
+	close(ch)
+	var metrics []prometheus.Metric
+	for m := range ch {
+		metrics = append(metrics, m)
+	}
+	require.NoError(t, registry.Register(staticCollector(metrics)))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	var histogram *dto.Histogram
+	for _, family := range families {
+		if family.GetName() == "knot_stats_hist_query_size" {
+			assert.Equal(t, dto.MetricType_HISTOGRAM, family.GetType())
+			histogram = family.Metric[0].Histogram
+		}
+	}
+	require.NotNil(t, histogram)
+
+	// Classic buckets are kept next to the native ones
+	assert.Len(t, histogram.GetBucket(), 3)
+	assert.Equal(t, int32(nativeHistogramSchema), histogram.GetSchema())
+	assert.Equal(t, uint64(0), histogram.GetZeroCount())
+
+	// Expand the spans and deltas back into bucket counts
+	counts := make(map[int]int64)
+	index := 0
+	var count int64
+	delta := 0
+	for _, span := range histogram.GetPositiveSpan() {
+		index += int(span.GetOffset())
+		for i := uint32(0); i < span.GetLength(); i++ {
+			count += histogram.GetPositiveDelta()[delta]
+			counts[index] = count
+			delta++
+			index++
+		}
+	}
+	assert.Equal(t, map[int]int64{
+		nativeBucketIndex(15):    2,
+		nativeBucketIndex(31):    5,
+		nativeBucketIndex(65535): 1,
+	}, counts)
+	mockCtl.AssertExpectations(t)
+}
+
+// TestCollectGlobalStats_HistogramLegacy tests that legacy mode keeps flat
+// series for bucket-style items
+func TestCollectGlobalStats_HistogramLegacy(t *testing.T) {
+	mockCtl := sizeStatsCtl()
+	collector := NewKnotCollector("/test", 1000, false, true, false, false, false, false,
+		WithLegacyMetricTypes(true))
+	ch := make(chan prometheus.Metric, 20)
+
+	require.NoError(t, collector.collectGlobalStats(mockCtl, ch))
+
+	metrics := drainMetrics(t, ch)
+	assert.Len(t, metrics["knot_stats_hist_query_size"], 3)
+	assert.Len(t, metrics["knot_stats_hist_query_size_total"], 3)
+	mockCtl.AssertExpectations(t)
+}
+
+// TestCollectZoneStatistics_HistogramZoneLimit tests that histograms of zones
+// over the limit are summed into zone="other"
+func TestCollectZoneStatistics_HistogramZoneLimit(t *testing.T) {
+	mockCtl := new(MockLibknotCtl)
+	mockCtl.On("SendCommand", "zone-stats").Return(nil)
+	for _, zone := range []string{"a.example.", "b.example.", "c.example."} {
+		for _, id := range []string{"0-15", "16-31"} {
+			mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+				Zone: zone, Section: "mod-stats", Item: "hist-reply-size", ID: id, Data: "1",
+			}, nil).Once()
+		}
+	}
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeBlock, nil, nil).Once()
+
+	collector := NewKnotCollector("/test", 1000, false, false, true, false, false, false,
+		WithLimits(Limits{MaxZones: 1}))
+	ch := make(chan prometheus.Metric, 20)
+	require.NoError(t, collector.collectZoneStatistics(mockCtl, ch))
+	collector.limiter.finish(ch, false, false)
+	close(ch)
+
+	counts := make(map[string]uint64)
+	for m := range ch {
+		if metricName(m) != "knot_zone_stats_hist_reply_size" {
+			continue
+		}
+		var pb dto.Metric
+		require.NoError(t, m.Write(&pb))
+		labels := make(map[string]string)
+		for _, pair := range pb.Label {
+			labels[pair.GetName()] = pair.GetValue()
+		}
+		counts[labels["zone"]] = pb.Histogram.GetSampleCount()
+	}
+
+	assert.Equal(t, map[string]uint64{"a.example.": 2, "other": 4}, counts)
+	mockCtl.AssertExpectations(t)
+}
+
+// staticCollector is a collector sending a fixed set of metrics
+type staticCollector []prometheus.Metric
+
+func (s staticCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range s {
+		ch <- m.Desc()
+	}
+}
+
+func (s staticCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range s {
+		ch <- m
+	}
+}
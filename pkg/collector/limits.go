@@ -0,0 +1,214 @@
+package collector
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// overflowLabelValue replaces label values of series aggregated because of
+// cardinality limits
+const overflowLabelValue = "other"
+
+// Reasons reported by the dropped series metric
+const (
+	limitReasonZones       = "zones"
+	limitReasonSeries      = "series"
+	limitReasonDescriptors = "descriptors"
+)
+
+// Series dropped or aggregated because of cardinality limits
+var droppedSeriesDesc = newDesc(
+	"knot_exporter_dropped_series",
+	"Series dropped or aggregated into \"other\" during the last collection because of cardinality limits",
+	[]string{"family", "reason"},
+	nil,
+)
+
+// Limits bounds the number of series the collector exports. Zero means
+// unlimited.
+type Limits struct {
+	// MaxZones is the number of distinct zones per-zone metrics are exported
+	// for. Statistics of further zones are summed into zone="other", their
+	// serials and timers are dropped.
+	MaxZones int
+	// MaxSeries is the number of series per statistics metric family.
+	// Further series are summed into a series with "other" label values.
+	MaxSeries int
+	// MaxDescriptors is the number of distinct global and zone statistics
+	// metric families. Statistics of further items are dropped.
+	MaxDescriptors int
+}
+
+// admissionSet admits up to max distinct keys. Admitted keys stay admitted
+// across collections for as long as they keep being seen, so the exported
+// series don't flap between scrapes.
+type admissionSet struct {
+	max      int
+	admitted map[string]bool
+	seen     map[string]bool
+}
+
+func newAdmissionSet(max int) *admissionSet {
+	return &admissionSet{
+		max:      max,
+		admitted: make(map[string]bool),
+		seen:     make(map[string]bool),
+	}
+}
+
+func (s *admissionSet) admit(key string) bool {
+	s.seen[key] = true
+	if s.admitted[key] {
+		return true
+	}
+	if len(s.admitted) < s.max {
+		s.admitted[key] = true
+		return true
+	}
+	return false
+}
+
+// prune releases keys not seen since the previous prune
+func (s *admissionSet) prune() {
+	for key := range s.admitted {
+		if !s.seen[key] {
+			delete(s.admitted, key)
+		}
+	}
+	s.seen = make(map[string]bool)
+}
+
+// overflowSeries accumulates the values of series aggregated into "other"
+type overflowSeries struct {
+	desc        [2]*prometheus.Desc
+	valueType   prometheus.ValueType
+	labelValues []string
+	value       float64
+}
+
+// droppedKey identifies a dropped series counter
+type droppedKey struct {
+	family string
+	reason string
+}
+
+// cardinalityLimiter enforces Limits across the tasks of a collection
+type cardinalityLimiter struct {
+	mu       sync.Mutex
+	limits   Limits
+	zones    *admissionSet
+	families *admissionSet // Statistics metric families
+	series   map[string]*admissionSet
+	overflow map[string]*overflowSeries
+	overHist *histogramSet
+	dropped  map[droppedKey]int
+}
+
+func newCardinalityLimiter(limits Limits) *cardinalityLimiter {
+	return &cardinalityLimiter{
+		limits:   limits,
+		zones:    newAdmissionSet(limits.MaxZones),
+		families: newAdmissionSet(limits.MaxDescriptors),
+		series:   make(map[string]*admissionSet),
+		overflow: make(map[string]*overflowSeries),
+		overHist: newHistogramSet(),
+		dropped:  make(map[droppedKey]int),
+	}
+}
+
+// admitZone reports whether per-zone series may be exported for the zone
+func (l *cardinalityLimiter) admitZone(zone string) bool {
+	if l.limits.MaxZones <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.zones.admit(normalizeZoneName(zone))
+}
+
+// admitSeries reports whether a series with the given label values may be
+// exported in the family
+func (l *cardinalityLimiter) admitSeries(family string, labelValues ...string) bool {
+	if l.limits.MaxSeries <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	set, ok := l.series[family]
+	if !ok {
+		set = newAdmissionSet(l.limits.MaxSeries)
+		l.series[family] = set
+	}
+	return set.admit(strings.Join(labelValues, "\xff"))
+}
+
+// admitDescriptor reports whether a statistics metric family may be
+// exported. The key tells the family apart from the families of other kinds
+// of statistics.
+func (l *cardinalityLimiter) admitDescriptor(key string) bool {
+	if l.limits.MaxDescriptors <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.families.admit(key)
+}
+
+// aggregate adds a value to the "other" series of a family
+func (l *cardinalityLimiter) aggregate(family, reason string, desc [2]*prometheus.Desc,
+	valueType prometheus.ValueType, value float64, labelValues ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := family + "\xff" + strings.Join(labelValues, "\xff")
+	series, ok := l.overflow[key]
+	if !ok {
+		series = &overflowSeries{desc: desc, valueType: valueType, labelValues: labelValues}
+		l.overflow[key] = series
+	}
+	series.value += value
+	l.dropped[droppedKey{family, reason}]++
+}
+
+// aggregateHistogram adds a bucket count to the "other" histogram of a family
+func (l *cardinalityLimiter) aggregateHistogram(family, reason string, desc *prometheus.Desc,
+	upper float64, count uint64, labelValues ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.overHist.add(desc, upper, count, labelValues...)
+	l.dropped[droppedKey{family, reason}]++
+}
+
+// drop records a series that was not exported
+func (l *cardinalityLimiter) drop(family, reason string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.dropped[droppedKey{family, reason}]++
+}
+
+// finish emits the aggregated and dropped series of the collection and
+// prepares the limiter for the next one
+func (l *cardinalityLimiter) finish(ch chan<- prometheus.Metric, legacy, native bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, series := range l.overflow {
+		sendMetric(ch, series.desc, series.valueType, legacy, series.value, series.labelValues...)
+	}
+	l.overHist.send(ch, native)
+	for key, count := range l.dropped {
+		slog.Debug("Cardinality limit reached", "metric", key.family, "limit", key.reason, "series", count)
+		ch <- prometheus.MustNewConstMetric(droppedSeriesDesc, prometheus.GaugeValue, float64(count), key.family, key.reason)
+	}
+
+	l.zones.prune()
+	l.families.prune()
+	for _, set := range l.series {
+		set.prune()
+	}
+	l.overflow = make(map[string]*overflowSeries)
+	l.overHist = newHistogramSet()
+	l.dropped = make(map[droppedKey]int)
+}
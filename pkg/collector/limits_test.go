@@ -0,0 +1,260 @@
+package collector
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metricSample is a flattened metric for assertions
+type metricSample struct {
+	labels map[string]string
+	value  float64
+}
+
+// drainMetrics closes ch and returns the collected metrics keyed by descriptor name
+func drainMetrics(t *testing.T, ch chan prometheus.Metric) map[string][]metricSample {
+	close(ch)
+	out := make(map[string][]metricSample)
+	for m := range ch {
+		var pb dto.Metric
+		require.NoError(t, m.Write(&pb))
+		sample := metricSample{labels: make(map[string]string)}
+		for _, lp := range pb.GetLabel() {
+			sample.labels[lp.GetName()] = lp.GetValue()
+		}
+		switch {
+		case pb.Gauge != nil:
+			sample.value = pb.GetGauge().GetValue()
+		case pb.Counter != nil:
+			sample.value = pb.GetCounter().GetValue()
+		case pb.Untyped != nil:
+			sample.value = pb.GetUntyped().GetValue()
+		}
+		name := metricName(m)
+		out[name] = append(out[name], sample)
+	}
+	return out
+}
+
+// metricName returns the fully-qualified name of a metric
+func metricName(m prometheus.Metric) string {
+	if relabeled, ok := m.(*relabeledMetric); ok {
+		return relabeled.meta.name
+	}
+	meta, _ := lookupDesc(m.Desc())
+	return meta.name
+}
+
+// TestAdmissionSet tests that admitted keys are sticky and released when unseen
+func TestAdmissionSet(t *testing.T) {
+	set := newAdmissionSet(2)
+	assert.True(t, set.admit("a"))
+	assert.True(t, set.admit("b"))
+	assert.False(t, set.admit("c"))
+	assert.True(t, set.admit("a"), "Admitted key should stay admitted")
+
+	// "b" was seen this round, so it stays admitted after pruning
+	set.prune()
+	assert.False(t, set.admit("c"))
+	assert.True(t, set.admit("b"))
+
+	// "a" was not seen since the last prune and frees its slot
+	set.prune()
+	assert.True(t, set.admit("c"))
+	assert.False(t, set.admit("a"))
+}
+
+// TestLimiterUnlimited tests that zero limits admit everything
+func TestLimiterUnlimited(t *testing.T) {
+	l := newCardinalityLimiter(Limits{})
+	for i := 0; i < 100; i++ {
+		assert.True(t, l.admitZone(string(rune('a'+i%26))+".example."))
+		assert.True(t, l.admitSeries("family", "label", string(rune(i))))
+	}
+	assert.True(t, l.admitDescriptor("global/never.seen.item"))
+}
+
+// TestCollectZoneStatistics_ZoneLimit tests that statistics of zones over the
+// limit are summed into zone="other"
+func TestCollectZoneStatistics_ZoneLimit(t *testing.T) {
+	mockCtl := new(MockLibknotCtl)
+	mockCtl.On("SendCommand", "zone-stats").Return(nil)
+	for i, zone := range []string{"a.example.", "b.example.", "c.example."} {
+		mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+			Zone: zone, Section: "mod-stats", Item: "limit-zone-item", ID: "A", Data: []string{"1", "2", "3"}[i],
+		}, nil).Once()
+	}
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeBlock, nil, nil).Once()
+
+	collector := NewKnotCollector("/test", 1000, false, false, true, false, false, false,
+		WithLimits(Limits{MaxZones: 1}))
+	ch := make(chan prometheus.Metric, 20)
+	require.NoError(t, collector.collectZoneStatistics(mockCtl, ch))
+	collector.limiter.finish(ch, false, false)
+
+	metrics := drainMetrics(t, ch)
+	samples := metrics["knot_zone_stats_limit_zone_item_total"]
+	require.Len(t, samples, 2)
+	assert.Equal(t, "a.example.", samples[0].labels["zone"])
+	assert.Equal(t, float64(1), samples[0].value)
+	assert.Equal(t, "other", samples[1].labels["zone"])
+	assert.Equal(t, float64(5), samples[1].value)
+
+	dropped := metrics["knot_exporter_dropped_series"]
+	require.Len(t, dropped, 1)
+	assert.Equal(t, "knot_zone_stats_limit_zone_item", dropped[0].labels["family"])
+	assert.Equal(t, "zones", dropped[0].labels["reason"])
+	assert.Equal(t, float64(2), dropped[0].value)
+	mockCtl.AssertExpectations(t)
+}
+
+// TestCollectZoneTimerInfo_ZoneLimit tests that timers of zones over the limit are dropped
+func TestCollectZoneTimerInfo_ZoneLimit(t *testing.T) {
+	mockCtl := new(MockLibknotCtl)
+	mockCtl.On("SendCommandWithType", "zone-read", "SOA").Return(nil)
+	for _, zone := range []string{"a.example.", "b.example."} {
+		mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+			Zone: zone, Data: "ns1.example. admin.example. 1 3600 600 86400 300",
+		}, nil).Once()
+	}
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeBlock, nil, nil).Once()
+
+	collector := NewKnotCollector("/test", 1000, false, false, false, false, false, true,
+		WithLimits(Limits{MaxZones: 1}))
+	ch := make(chan prometheus.Metric, 20)
+	require.NoError(t, collector.collectZoneTimerInfo(mockCtl, ch))
+	collector.limiter.finish(ch, false, false)
+
+	metrics := drainMetrics(t, ch)
+	require.Len(t, metrics["knot_zone_refresh_seconds"], 1)
+	assert.Equal(t, "a.example.", metrics["knot_zone_refresh_seconds"][0].labels["zone"])
+	assert.Len(t, metrics["knot_exporter_dropped_series"], 3)
+	mockCtl.AssertExpectations(t)
+}
+
+// TestCollectGlobalStats_SeriesLimit tests that series over the per-family
+// limit are summed into type="other"
+func TestCollectGlobalStats_SeriesLimit(t *testing.T) {
+	mockCtl := new(MockLibknotCtl)
+	mockCtl.On("SendCommand", "stats").Return(nil)
+	for _, id := range []string{"udp4", "tcp4", "udp6"} {
+		mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+			Section: "mod-stats", Item: "limit-series-item", ID: id, Data: "10",
+		}, nil).Once()
+	}
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeBlock, nil, nil).Once()
+
+	collector := NewKnotCollector("/test", 1000, false, true, false, false, false, false,
+		WithLimits(Limits{MaxSeries: 2}))
+	ch := make(chan prometheus.Metric, 20)
+	require.NoError(t, collector.collectGlobalStats(mockCtl, ch))
+	collector.limiter.finish(ch, false, false)
+
+	metrics := drainMetrics(t, ch)
+	samples := metrics["knot_stats_limit_series_item_total"]
+	require.Len(t, samples, 3)
+	assert.Equal(t, "other", samples[2].labels["type"])
+	assert.Equal(t, float64(10), samples[2].value)
+	mockCtl.AssertExpectations(t)
+}
+
+// TestCollectGlobalStats_DescriptorLimit tests that items needing metric
+// families beyond the limit of the collector are dropped
+func TestCollectGlobalStats_DescriptorLimit(t *testing.T) {
+	mockCtl := new(MockLibknotCtl)
+	mockCtl.On("SendCommand", "stats").Return(nil)
+	for _, item := range []string{"limit.first.item", "limit.new.item", "limit.first.item"} {
+		mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+			Section: "server", Item: item, Data: "1",
+		}, nil).Once()
+	}
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeBlock, nil, nil).Once()
+
+	// Families created by other collectors don't count
+	getGlobalStatsDescriptor("limit.other.item")
+	collector := NewKnotCollector("/test", 1000, false, true, false, false, false, false,
+		WithLimits(Limits{MaxDescriptors: 1}))
+	ch := make(chan prometheus.Metric, 20)
+	require.NoError(t, collector.collectGlobalStats(mockCtl, ch))
+	collector.limiter.finish(ch, false, false)
+
+	metrics := drainMetrics(t, ch)
+	assert.Len(t, metrics["knot_stats_limit_first_item_total"], 2)
+	assert.Empty(t, metrics["knot_stats_limit_new_item_total"])
+	require.Len(t, metrics["knot_exporter_dropped_series"], 1)
+	assert.Equal(t, "descriptors", metrics["knot_exporter_dropped_series"][0].labels["reason"])
+
+	globalStatsDescMutex.RLock()
+	_, created := globalStatsDescriptors["limit.new.item"]
+	globalStatsDescMutex.RUnlock()
+	assert.False(t, created, "Descriptor over the limit should not be created")
+	mockCtl.AssertExpectations(t)
+}
+
+// TestLimiterDescriptorsConcurrent tests that parallel tasks can't admit more
+// families than the limit
+func TestLimiterDescriptorsConcurrent(t *testing.T) {
+	l := newCardinalityLimiter(Limits{MaxDescriptors: 5})
+	var admitted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if l.admitDescriptor(fmt.Sprintf("global/item%d", i)) {
+				admitted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(5), admitted.Load())
+}
+
+// TestCollectZoneStatistics_HistogramSeriesLimit tests that histogram series
+// over the per-family limit are summed into zone="other"
+func TestCollectZoneStatistics_HistogramSeriesLimit(t *testing.T) {
+	mockCtl := new(MockLibknotCtl)
+	mockCtl.On("SendCommand", "zone-stats").Return(nil)
+	for _, zone := range []string{"a.example.", "b.example.", "c.example."} {
+		for _, bucket := range []string{"0-15", "16-31"} {
+			mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+				Zone: zone, Section: "mod-stats", Item: "limit-hist-size", ID: bucket, Data: "2",
+			}, nil).Once()
+		}
+	}
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeBlock, nil, nil).Once()
+
+	collector := NewKnotCollector("/test", 1000, false, false, true, false, false, false,
+		WithLimits(Limits{MaxSeries: 2}))
+	ch := make(chan prometheus.Metric, 20)
+	require.NoError(t, collector.collectZoneStatistics(mockCtl, ch))
+	collector.limiter.finish(ch, false, false)
+
+	close(ch)
+	counts := make(map[string]uint64)
+	var dropped float64
+	for m := range ch {
+		var pb dto.Metric
+		require.NoError(t, m.Write(&pb))
+		if pb.Histogram == nil {
+			dropped += pb.GetGauge().GetValue()
+			continue
+		}
+		for _, lp := range pb.GetLabel() {
+			if lp.GetName() == "zone" {
+				counts[lp.GetValue()] = pb.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	assert.Equal(t, map[string]uint64{"a.example.": 4, "b.example.": 4, "other": 4}, counts)
+	assert.Equal(t, float64(2), dropped)
+	mockCtl.AssertExpectations(t)
+}
@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxPID is the highest PID Linux assigns
+const maxPID = 4194304
+
+// processDir returns the /proc directory of a process, false for PIDs out of
+// range
+func processDir(procRoot string, pid int) (string, bool) {
+	if pid <= 0 || pid > maxPID {
+		return "", false
+	}
+	return filepath.Join(procRoot, strconv.Itoa(pid)), true
+}
+
+// getProcessMemory returns the VmRSS of a process in bytes, 0 if unknown
+func getProcessMemory(procRoot string, pid int) uint64 {
+	dir, ok := processDir(procRoot, pid)
+	if !ok {
+		return 0
+	}
+	status, err := readKBFields(filepath.Join(dir, "status"))
+	if err != nil {
+		return 0
+	}
+	return status["VmRSS"]
+}
+
+// getProcessMemoryKinds returns the memory of a process in bytes by kind, see
+// readMemoryKinds
+func getProcessMemoryKinds(procRoot string, pid int) map[string]uint64 {
+	dir, ok := processDir(procRoot, pid)
+	if !ok {
+		return nil
+	}
+	return readMemoryKinds(dir)
+}
+
+// readMemoryKinds returns the memory of the process with the given /proc
+// directory in bytes by kind:
+//   - rss: resident memory, including shared file mappings such as LMDB
+//   - pss: resident memory with shared pages split among the sharing processes
+//   - uss: resident memory not shared with other processes
+//   - swap: swapped out anonymous memory
+//   - anon: resident anonymous memory, the heap holding zone data
+//   - file: resident file-backed and shared memory
+//   - virtual: size of the address space
+//
+// smaps_rollup is only readable with the ptrace access mode of the process,
+// without it pss and uss are missing and the others come from status.
+func readMemoryKinds(dir string) map[string]uint64 {
+	kinds := make(map[string]uint64)
+	if status, err := readKBFields(filepath.Join(dir, "status")); err == nil {
+		setKind(kinds, "rss", status, "VmRSS")
+		setKind(kinds, "virtual", status, "VmSize")
+		setKind(kinds, "swap", status, "VmSwap")
+		setKind(kinds, "anon", status, "RssAnon")
+		if file, ok := status["RssFile"]; ok {
+			kinds["file"] = file + status["RssShmem"]
+		}
+	}
+
+	rollup, err := readKBFields(filepath.Join(dir, "smaps_rollup"))
+	if err != nil {
+		return kinds
+	}
+	setKind(kinds, "rss", rollup, "Rss")
+	setKind(kinds, "pss", rollup, "Pss")
+	setKind(kinds, "swap", rollup, "Swap")
+	if private, ok := rollup["Private_Clean"]; ok {
+		kinds["uss"] = private + rollup["Private_Dirty"]
+	}
+	if anon, ok := rollup["Anonymous"]; ok {
+		kinds["anon"] = anon
+		if rss := rollup["Rss"]; rss >= anon {
+			kinds["file"] = rss - anon
+		}
+	}
+	return kinds
+}
+
+// setKind sets a kind from a field, if present
+func setKind(kinds map[string]uint64, kind string, fields map[string]uint64, field string) {
+	if value, ok := fields[field]; ok {
+		kinds[kind] = value
+	}
+}
+
+// readKBFields returns the fields of a /proc file with "Name: <value> kB"
+// lines in bytes
+func readKBFields(path string) (map[string]uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		name, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		parts := strings.Fields(value)
+		if len(parts) != 2 || parts[1] != "kB" {
+			continue
+		}
+		if kb, err := strconv.ParseUint(parts[0], 10, 64); err == nil {
+			fields[name] = kb * 1024
+		}
+	}
+	return fields, scanner.Err()
+}
@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReadMemoryKinds tests the memory kinds of a process with a readable
+// smaps_rollup
+func TestReadMemoryKinds(t *testing.T) {
+	kinds := readMemoryKinds(filepath.Join("testdata", "proc", "1234"))
+	assert.Equal(t, map[string]uint64{
+		"rss":     401204 * 1024,
+		"pss":     260102 * 1024,
+		"uss":     (139604 + 120500) * 1024,
+		"swap":    2052 * 1024,
+		"anon":    120404 * 1024,
+		"file":    (401204 - 120404) * 1024,
+		"virtual": 8536112 * 1024,
+	}, kinds)
+}
+
+// TestReadMemoryKindsStatusOnly tests the fallback to status when
+// smaps_rollup is unreadable
+func TestReadMemoryKindsStatusOnly(t *testing.T) {
+	kinds := readMemoryKinds(filepath.Join("testdata", "proc", "5678"))
+	assert.Equal(t, map[string]uint64{
+		"rss":     401200 * 1024,
+		"swap":    2048 * 1024,
+		"anon":    120400 * 1024,
+		"file":    (280000 + 800) * 1024,
+		"virtual": 8536112 * 1024,
+	}, kinds)
+
+	assert.Empty(t, readMemoryKinds(filepath.Join("testdata", "proc", "missing")))
+}
+
+// TestGetProcessMemoryFixture tests the VmRSS of a process below another
+// procfs root
+func TestGetProcessMemoryFixture(t *testing.T) {
+	procRoot := filepath.Join("testdata", "proc")
+	assert.Equal(t, uint64(401200*1024), getProcessMemory(procRoot, 1234))
+	assert.Equal(t, uint64(0), getProcessMemory(procRoot, 4321))
+	assert.Equal(t, uint64(0), getProcessMemory(procRoot, maxPID+1))
+}
+
+// TestGetProcessMemoryKinds tests the memory kinds of the test process
+func TestGetProcessMemoryKinds(t *testing.T) {
+	if _, err := os.Stat("/proc/self/status"); err != nil {
+		t.Skip("no /proc filesystem")
+	}
+	kinds := getProcessMemoryKinds("/proc", os.Getpid())
+	assert.Greater(t, kinds["rss"], uint64(0))
+	assert.GreaterOrEqual(t, kinds["virtual"], kinds["rss"])
+
+	assert.Nil(t, getProcessMemoryKinds("/proc", -1))
+	assert.Nil(t, getProcessMemoryKinds("/proc", 9999999))
+}
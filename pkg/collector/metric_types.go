@@ -0,0 +1,50 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// statsGaugeItems lists the Knot DNS statistics items, keyed by section and
+// item, that report a current value rather than a monotonic counter. All
+// other statistics items, including every mod-stats item, are counters.
+var statsGaugeItems = map[string]map[string]bool{
+	"server": {
+		"zone-count": true,
+	},
+}
+
+// statsValueType returns the value type of a Knot DNS statistics item
+func statsValueType(section, item string) prometheus.ValueType {
+	if statsGaugeItems[section][item] {
+		return prometheus.GaugeValue
+	}
+	return prometheus.CounterValue
+}
+
+// sendMetric sends a value with its proper type: counters as the %s_total
+// variant, everything else as the base gauge. In legacy mode both variants
+// are sent regardless of the type.
+func sendMetric(ch chan<- prometheus.Metric, desc [2]*prometheus.Desc, valueType prometheus.ValueType,
+	legacy bool, value float64, labelValues ...string) {
+
+	if legacy {
+		sendMetrics(ch, desc, value, labelValues...)
+		return
+	}
+
+	if valueType == prometheus.CounterValue {
+		ch <- prometheus.MustNewConstMetric(desc[1], prometheus.CounterValue, value, labelValues...)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(desc[0], prometheus.GaugeValue, value, labelValues...)
+}
+
+// describeMetric sends the descriptors sendMetric may use for a value type
+func describeMetric(ch chan<- *prometheus.Desc, desc [2]*prometheus.Desc, valueType prometheus.ValueType, legacy bool) {
+	if legacy || valueType == prometheus.GaugeValue {
+		ch <- desc[0]
+	}
+	if legacy || valueType == prometheus.CounterValue {
+		ch <- desc[1]
+	}
+}
@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStatsValueType tests the value types of statistics items
+func TestStatsValueType(t *testing.T) {
+	assert.Equal(t, prometheus.GaugeValue, statsValueType("server", "zone-count"))
+	assert.Equal(t, prometheus.CounterValue, statsValueType("server", "udp-received"))
+	assert.Equal(t, prometheus.CounterValue, statsValueType("mod-stats", "request-protocol"))
+	assert.Equal(t, prometheus.CounterValue, statsValueType("mod-stats", "zone-count"))
+}
+
+// TestSendMetric tests that values are sent once with their proper type
+func TestSendMetric(t *testing.T) {
+	desc := makeDescPair("knot_test", "Test metric", []string{"zone"}, nil)
+
+	tests := []struct {
+		name      string
+		valueType prometheus.ValueType
+		legacy    bool
+		expected  []string
+	}{
+		{"gauge", prometheus.GaugeValue, false, []string{"knot_test"}},
+		{"counter", prometheus.CounterValue, false, []string{"knot_test_total"}},
+		{"legacy gauge", prometheus.GaugeValue, true, []string{"knot_test", "knot_test_total"}},
+		{"legacy counter", prometheus.CounterValue, true, []string{"knot_test", "knot_test_total"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := make(chan prometheus.Metric, 2)
+			sendMetric(ch, desc, tt.valueType, tt.legacy, 42, "example.com.")
+			close(ch)
+
+			var names []string
+			for m := range ch {
+				var pb dto.Metric
+				require.NoError(t, m.Write(&pb))
+				assert.Equal(t, float64(42), pb.GetGauge().GetValue()+pb.GetCounter().GetValue())
+				if m.Desc() == desc[1] {
+					assert.NotNil(t, pb.Counter)
+				} else {
+					assert.NotNil(t, pb.Gauge)
+				}
+				names = append(names, metricName(m))
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+// TestDescribeMetric tests that only the descriptors in use are described
+func TestDescribeMetric(t *testing.T) {
+	desc := makeDescPair("knot_test", "Test metric", nil, nil)
+
+	describe := func(valueType prometheus.ValueType, legacy bool) []*prometheus.Desc {
+		ch := make(chan *prometheus.Desc, 2)
+		describeMetric(ch, desc, valueType, legacy)
+		close(ch)
+		var descs []*prometheus.Desc
+		for d := range ch {
+			descs = append(descs, d)
+		}
+		return descs
+	}
+
+	assert.Equal(t, []*prometheus.Desc{desc[0]}, describe(prometheus.GaugeValue, false))
+	assert.Equal(t, []*prometheus.Desc{desc[1]}, describe(prometheus.CounterValue, false))
+	assert.Equal(t, []*prometheus.Desc{desc[0], desc[1]}, describe(prometheus.GaugeValue, true))
+}
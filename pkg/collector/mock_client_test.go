@@ -33,6 +33,11 @@ func (m *MockLibknotCtl) SendCommandWithType(cmd string, rtype string) error {
 	return args.Error(0)
 }
 
+func (m *MockLibknotCtl) SendZoneCommand(cmd string, rtype string, zones []string) error {
+	args := m.Called(cmd, rtype, zones)
+	return args.Error(0)
+}
+
 func (m *MockLibknotCtl) ReceiveResponse() (libknot.CtlType, *libknot.CtlData, error) {
 	args := m.Called()
 	dataType := args.Get(0).(libknot.CtlType)
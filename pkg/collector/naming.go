@@ -0,0 +1,180 @@
+package collector
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/utils"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NamingScheme selects the metric names and labels the collector exports
+type NamingScheme string
+
+const (
+	// NamingSchemeDefault is the naming of this exporter
+	NamingSchemeDefault NamingScheme = "default"
+	// NamingSchemePython reproduces the metric names, labels and types of the
+	// Python knot_exporter shipped with Knot DNS
+	NamingSchemePython NamingScheme = "python"
+)
+
+// ParseNamingScheme parses a naming scheme name
+func ParseNamingScheme(name string) (NamingScheme, error) {
+	switch scheme := NamingScheme(strings.ToLower(name)); scheme {
+	case NamingSchemeDefault, NamingSchemePython:
+		return scheme, nil
+	}
+	return "", fmt.Errorf("unknown naming scheme %q (expected %q or %q)", name, NamingSchemeDefault, NamingSchemePython)
+}
+
+// staticDescs holds the descriptors of metrics with fixed names
+type staticDescs struct {
+	memoryUsage          [2]*prometheus.Desc
+	memoryKinds          [2]*prometheus.Desc
+	zoneSerial           [2]*prometheus.Desc
+	zoneRefresh          [2]*prometheus.Desc
+	zoneRetry            [2]*prometheus.Desc
+	zoneExpiration       [2]*prometheus.Desc
+	zoneStatusRefresh    [2]*prometheus.Desc
+	zoneStatusExpiration [2]*prometheus.Desc
+	zoneStatusResign     [2]*prometheus.Desc
+}
+
+var defaultDescs = &staticDescs{
+	memoryUsage:          memoryUsageDesc,
+	memoryKinds:          memoryKindsDesc,
+	zoneSerial:           zoneSerialDesc,
+	zoneRefresh:          zoneRefreshDesc,
+	zoneRetry:            zoneRetryDesc,
+	zoneExpiration:       zoneExpirationDesc,
+	zoneStatusRefresh:    zoneStatusRefreshDesc,
+	zoneStatusExpiration: zoneStatusExpirationDesc,
+	zoneStatusResign:     zoneStatusResignDesc,
+}
+
+// The Python exporter reports zone serials and zone-status timers as types of
+// a single knot_zone_stats family with section="zone". SOA timers, the
+// re-sign timer and memory by kind have no Python counterpart and keep their
+// names.
+var pythonDescs = &staticDescs{
+	memoryUsage: pythonDesc("knot_memory_usage", "Memory usage of Knot DNS processes",
+		[]string{"type"}, prometheus.Labels{"section": "server"}),
+	memoryKinds: memoryKindsDesc,
+	zoneSerial: pythonDesc("knot_zone_stats", "",
+		[]string{"zone"}, prometheus.Labels{"section": "zone", "type": "serial"}),
+	zoneRefresh:    zoneRefreshDesc,
+	zoneRetry:      zoneRetryDesc,
+	zoneExpiration: zoneExpirationDesc,
+	zoneStatusRefresh: pythonDesc("knot_zone_stats", "",
+		[]string{"zone"}, prometheus.Labels{"section": "zone", "type": "refresh"}),
+	zoneStatusExpiration: pythonDesc("knot_zone_stats", "",
+		[]string{"zone"}, prometheus.Labels{"section": "zone", "type": "expiration"}),
+	zoneStatusResign: zoneStatusResignDesc,
+}
+
+// pythonDesc creates a gauge only descriptor pair, the Python exporter has no
+// %s_total variants
+func pythonDesc(fqName, help string, variableLabels []string, constLabels prometheus.Labels) [2]*prometheus.Desc {
+	desc := newDesc(fqName, help, variableLabels, constLabels)
+	return [2]*prometheus.Desc{desc, desc}
+}
+
+// Dynamic statistics descriptors of the Python naming scheme, keyed by
+// pythonStatsKey. Global and zone statistics share metric names.
+var (
+	pythonStatsDescriptors = make(map[string][2]*prometheus.Desc)
+	pythonStatsDescMutex   = sync.RWMutex{}
+)
+
+// pythonStatsMetricName returns the Python exporter name of a statistics item
+func pythonStatsMetricName(item string) string {
+	return "knot_" + utils.SanitizeMetricName(item)
+}
+
+// pythonStatsKey identifies a Python statistics descriptor. Items without an
+// ID have no type label.
+func pythonStatsKey(item string, zone, withID bool) string {
+	return fmt.Sprintf("%s/%t/%t", item, zone, withID)
+}
+
+// Get or create a Python statistics descriptor
+func getPythonStatsDescriptor(item string, zone, withID bool) [2]*prometheus.Desc {
+	key := pythonStatsKey(item, zone, withID)
+
+	pythonStatsDescMutex.RLock()
+	if desc, exists := pythonStatsDescriptors[key]; exists {
+		pythonStatsDescMutex.RUnlock()
+		return desc
+	}
+	pythonStatsDescMutex.RUnlock()
+
+	pythonStatsDescMutex.Lock()
+	defer pythonStatsDescMutex.Unlock()
+
+	// Double-check in case another goroutine created it
+	if desc, exists := pythonStatsDescriptors[key]; exists {
+		return desc
+	}
+
+	var labels []string
+	if zone {
+		labels = append(labels, "zone")
+	}
+	labels = append(labels, "section")
+	if withID {
+		labels = append(labels, "type")
+	}
+
+	metricName := pythonStatsMetricName(item)
+	desc := pythonDesc(metricName, "", labels, nil)
+	pythonStatsDescriptors[key] = desc
+
+	slog.Debug("Created Python stats descriptor", "metric", metricName, "labels", labels)
+	return desc
+}
+
+// sendPythonStats sends a global (zone is empty) or zone statistics value
+// named like the Python exporter, as a gauge with section and type labels
+func (c *KnotCollector) sendPythonStats(ch chan<- prometheus.Metric, zone, section, item, id string, value float64) {
+	// The Python exporter leaves out zero values of statistics with a type
+	if id != "" && value == 0 {
+		return
+	}
+
+	family := pythonStatsMetricName(item)
+	key := pythonStatsKey(item, zone != "", id != "")
+	if !c.limiter.admitDescriptor("python/" + key) {
+		c.limiter.drop(family, limitReasonDescriptors)
+		return
+	}
+	desc := getPythonStatsDescriptor(item, zone != "", id != "")
+
+	var labelValues []string
+	if zone != "" {
+		labelValues = append(labelValues, zone)
+	}
+	labelValues = append(labelValues, section)
+	if id != "" {
+		labelValues = append(labelValues, id)
+	}
+
+	// Series over the limits are summed into "other"
+	switch {
+	case zone != "" && !c.limiter.admitZone(zone):
+		labelValues[0] = overflowLabelValue
+		c.limiter.aggregate(family, limitReasonZones, desc, prometheus.GaugeValue, value, labelValues...)
+	case !c.limiter.admitSeries(key, labelValues...):
+		if zone != "" {
+			labelValues[0] = overflowLabelValue
+		}
+		if id != "" {
+			labelValues[len(labelValues)-1] = overflowLabelValue
+		}
+		c.limiter.aggregate(family, limitReasonSeries, desc, prometheus.GaugeValue, value, labelValues...)
+	default:
+		sendMetric(ch, desc, prometheus.GaugeValue, false, value, labelValues...)
+	}
+}
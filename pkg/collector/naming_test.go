@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixtureMetricNames returns the metric families of an exposition file
+func fixtureMetricNames(t *testing.T, path string) []string {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) >= 3 && fields[1] == "TYPE" {
+			names = append(names, fields[2])
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return names
+}
+
+// TestParseNamingScheme tests parsing of naming scheme names
+func TestParseNamingScheme(t *testing.T) {
+	scheme, err := ParseNamingScheme("python")
+	require.NoError(t, err)
+	assert.Equal(t, NamingSchemePython, scheme)
+
+	scheme, err = ParseNamingScheme("Default")
+	require.NoError(t, err)
+	assert.Equal(t, NamingSchemeDefault, scheme)
+
+	_, err = ParseNamingScheme("go")
+	assert.Error(t, err)
+}
+
+// TestPythonNamingParity tests that the Python naming scheme reproduces the
+// output of the Python knot_exporter for the fixtures in testdata/python,
+// each holding control responses and the metrics the Python exporter exposes
+// for them. The metrics are written after the collect() of knot_exporter.py,
+// they aren't recorded from a running Python exporter.
+func TestPythonNamingParity(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "python", "*"))
+	require.NoError(t, err)
+	require.NotEmpty(t, dirs)
+
+	for _, dir := range dirs {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			server := loadFixtureServer(t, filepath.Join(dir, "responses.json"))
+			expected := filepath.Join(dir, "metrics.prom")
+
+			collector := NewKnotCollector("/test", 1000, false, true, true, true, true, false,
+				WithNamingScheme(NamingSchemePython), WithLegacyMetricTypes(true))
+			collector.newCtl = ctlFactory(server)
+
+			ch := make(chan prometheus.Metric, 100)
+			collector.collect(ch)
+			close(ch)
+			var metrics []prometheus.Metric
+			for m := range ch {
+				metrics = append(metrics, m)
+			}
+
+			file, err := os.Open(expected)
+			require.NoError(t, err)
+			defer file.Close()
+
+			assert.NoError(t, testutil.CollectAndCompare(staticCollector(metrics), file,
+				fixtureMetricNames(t, expected)...))
+		})
+	}
+}
+
+// TestPythonNamingNoDefaultNames tests that the Python naming scheme doesn't
+// export metrics named by the default scheme
+func TestPythonNamingNoDefaultNames(t *testing.T) {
+	server := loadFixtureServer(t, filepath.Join("testdata", "python", "stats", "responses.json"))
+	collector := NewKnotCollector("/test", 1000, false, true, false, false, false, false,
+		WithNamingScheme(NamingSchemePython))
+	collector.newCtl = ctlFactory(server)
+
+	ch := make(chan prometheus.Metric, 100)
+	collector.collect(ch)
+
+	for name := range drainMetrics(t, ch) {
+		assert.False(t, strings.HasPrefix(name, "knot_stats_"), "Unexpected metric %s", name)
+		assert.False(t, strings.HasSuffix(name, "_total"), "Unexpected metric %s", name)
+	}
+}
@@ -0,0 +1,103 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errPeerNamespace is returned when the peer of the control socket runs in a
+// PID namespace the exporter can't see
+var errPeerNamespace = errors.New("knotd runs in another PID namespace, set a PID file")
+
+// knotdPID returns the PID of the knotd process the collector talks to: the
+// one in the PID file, if set, or the peer of the control socket. Unlike
+// pidof, this finds the right process with several knotd instances and
+// needs no tools in the container. The peer is only asked again once its
+// process is gone or its PID belongs to a process started later, so scrapes
+// don't open another control connection.
+func (c *KnotCollector) knotdPID() (int, error) {
+	if c.pidFile != "" {
+		return readPIDFile(c.pidFile)
+	}
+
+	c.pidMu.Lock()
+	pid, start := c.peerPID, c.peerStart
+	c.pidMu.Unlock()
+	if pid != 0 {
+		if current, err := processStartTime(c.procRoot, pid); err == nil && current == start {
+			return pid, nil
+		}
+	}
+
+	// Without the lock, a hanging socket only delays this lookup
+	pid, err := socketPeerPID(c.sockPath, time.Duration(c.timeout)*time.Millisecond)
+	if err != nil {
+		return 0, err
+	}
+	if pid == 0 {
+		return 0, errPeerNamespace
+	}
+
+	// A process that can't be told apart from a later one isn't cached
+	if start, err := processStartTime(c.procRoot, pid); err == nil {
+		c.pidMu.Lock()
+		c.peerPID, c.peerStart = pid, start
+		c.pidMu.Unlock()
+	}
+	return pid, nil
+}
+
+// processStartTime returns the start time of a process in clock ticks after
+// boot, from the 22nd field of its stat file
+func processStartTime(procRoot string, pid int) (uint64, error) {
+	dir, ok := processDir(procRoot, pid)
+	if !ok {
+		return 0, fmt.Errorf("invalid PID %d", pid)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return 0, err
+	}
+	// The command name in parentheses may contain spaces, the fields after it
+	// start with the 3rd
+	end := strings.LastIndexByte(string(content), ')')
+	if end < 0 {
+		return 0, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	fields := strings.Fields(string(content[end+1:]))
+	if len(fields) < 20 {
+		return 0, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	return strconv.ParseUint(fields[19], 10, 64)
+}
+
+// CheckMemInfo finds knotd like the meminfo collector does and returns its
+// PID, with an error when no memory usage would be exported
+func (c *KnotCollector) CheckMemInfo() (int, error) {
+	pid, err := c.knotdPID()
+	if err != nil {
+		return 0, err
+	}
+	if getProcessMemory(c.procRoot, pid) == 0 {
+		return pid, fmt.Errorf("no memory usage of process %d in %s", pid, c.procRoot)
+	}
+	return pid, nil
+}
+
+// readPIDFile returns the PID held by a PID file
+func readPIDFile(path string) (int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PID file: %v", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid PID file %s: %q", path, strings.TrimSpace(string(content)))
+	}
+	return pid, nil
+}
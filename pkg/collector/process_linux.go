@@ -0,0 +1,35 @@
+package collector
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+)
+
+// socketPeerPID returns the PID of the process listening on a UNIX socket,
+// from the SO_PEERCRED credentials of a connection to it. The PID is 0 when
+// the process is outside the PID namespace of the exporter.
+func socketPeerPID(path string, timeout time.Duration) (int, error) {
+	conn, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to control socket: %v", err)
+	}
+	defer conn.Close()
+
+	raw, err := conn.(*net.UnixConn).SyscallConn()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get peer credentials: %v", err)
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return 0, fmt.Errorf("failed to get peer credentials: %v", err)
+	}
+	if credErr != nil {
+		return 0, fmt.Errorf("failed to get peer credentials: %v", credErr)
+	}
+	return int(cred.Pid), nil
+}
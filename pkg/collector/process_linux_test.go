@@ -0,0 +1,84 @@
+package collector
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSocketPeerPID tests finding the process listening on the control
+// socket from its peer credentials
+func TestSocketPeerPID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "knot.sock")
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	pid, err := socketPeerPID(path, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), pid)
+
+	c := NewKnotCollector(path, 1000, true, false, false, false, false, false)
+	pid, err = c.knotdPID()
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), pid)
+	assert.Contains(t, c.memoryUsage(), os.Getpid())
+
+	_, err = socketPeerPID(filepath.Join(t.TempDir(), "missing.sock"), time.Second)
+	assert.ErrorContains(t, err, "failed to connect to control socket")
+}
+
+// TestKnotdPIDCached tests that the peer of the control socket is only asked
+// again once its process is gone
+func TestKnotdPIDCached(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "knot.sock")
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+
+	c := NewKnotCollector(path, 1000, true, false, false, false, false, false)
+	pid, err := c.knotdPID()
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), pid)
+
+	// The socket is no longer asked while the process exists
+	listener.Close()
+	pid, err = c.knotdPID()
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), pid)
+
+	// A process started later with the same PID makes the socket asked again
+	c.procRoot = t.TempDir()
+	writeProcStat(t, c.procRoot, pid, c.peerStart+1)
+	_, err = c.knotdPID()
+	assert.ErrorContains(t, err, "failed to connect to control socket")
+
+	// So does a procfs without the process
+	c.procRoot = t.TempDir()
+	_, err = c.knotdPID()
+	assert.ErrorContains(t, err, "failed to connect to control socket")
+}
+
+// TestKnotdPIDNotBlockingStatus tests that a hanging control socket doesn't
+// block the collection status
+func TestKnotdPIDNotBlockingStatus(t *testing.T) {
+	c := NewKnotCollector("/nonexistent/socket.sock", 1000, true, false, false, false, false, false)
+	c.pidMu.Lock()
+	defer c.pidMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.LastError()
+		c.CollectorStatus()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Status blocked by the knotd PID lookup")
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package collector
+
+import (
+	"errors"
+	"time"
+)
+
+// socketPeerPID is only supported on Linux, elsewhere the PID of knotd comes
+// from a PID file
+func socketPeerPID(path string, timeout time.Duration) (int, error) {
+	return 0, errors.New("peer credentials of the control socket are not supported on this platform, set a PID file")
+}
@@ -0,0 +1,117 @@
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReadPIDFile tests reading valid and invalid PID files
+func TestReadPIDFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "knot.pid")
+	require.NoError(t, os.WriteFile(path, []byte("1234\n"), 0o644))
+	pid, err := readPIDFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1234, pid)
+
+	for _, content := range []string{"", "knotd", "0", "-5"} {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		_, err := readPIDFile(path)
+		assert.Error(t, err, content)
+	}
+
+	_, err = readPIDFile(filepath.Join(dir, "missing.pid"))
+	assert.ErrorContains(t, err, "failed to read PID file")
+}
+
+// writeProcStat writes the stat file of a process below a procfs root
+func writeProcStat(t *testing.T, procRoot string, pid int, start uint64) {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	stat := fmt.Sprintf("%d (knot d) S 1 %d %d 0 -1 4194560 100 0 0 0 10 5 0 0 20 0 21 0 %d 1000 100\n", pid, pid, pid, start)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0o644))
+}
+
+// TestProcessStartTime tests reading the start time of a process
+func TestProcessStartTime(t *testing.T) {
+	root := t.TempDir()
+	writeProcStat(t, root, 1234, 987654)
+	start, err := processStartTime(root, 1234)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(987654), start)
+
+	_, err = processStartTime(root, 5678)
+	assert.Error(t, err)
+	_, err = processStartTime(root, 0)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "1234", "stat"), []byte("1234 (knotd) S 1\n"), 0o644))
+	_, err = processStartTime(root, 1234)
+	assert.Error(t, err)
+}
+
+// TestCollectMemoryPIDFile tests memory metrics of the process of a PID file
+// below another procfs root
+func TestCollectMemoryPIDFile(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "knot.pid")
+	require.NoError(t, os.WriteFile(pidFile, []byte("1234\n"), 0o644))
+
+	c := NewKnotCollector("/nonexistent/socket.sock", 1000, true, false, false, false, false, false,
+		WithPIDFile(pidFile), WithProcRoot(filepath.Join("testdata", "proc")))
+
+	expected := `
+# HELP knot_memory_usage_bytes Memory usage of Knot DNS processes
+# TYPE knot_memory_usage_bytes gauge
+knot_memory_usage_bytes{pid="1234"} 4.108288e+08
+`
+	assert.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expected), "knot_memory_usage_bytes"))
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	kinds := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "knot_memory_bytes" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, pair := range metric.GetLabel() {
+				labels[pair.GetName()] = pair.GetValue()
+			}
+			assert.Equal(t, "1234", labels["pid"])
+			kinds[labels["kind"]] = metric.GetGauge().GetValue()
+		}
+	}
+	assert.Equal(t, float64(260102*1024), kinds["pss"])
+	assert.Len(t, kinds, 7)
+}
+
+// TestKnotUpMemInfoOnly tests knot_up when only the meminfo collector is
+// enabled, which finds knotd without control commands
+func TestKnotUpMemInfoOnly(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "knot.pid")
+	require.NoError(t, os.WriteFile(pidFile, []byte("1234\n"), 0o644))
+
+	c := NewKnotCollector("/nonexistent/socket.sock", 1000, true, false, false, false, false, false,
+		WithPIDFile(pidFile), WithProcRoot(filepath.Join("testdata", "proc")))
+	expected := `
+# HELP knot_up Whether the last collection could connect to Knot DNS
+# TYPE knot_up gauge
+knot_up 1
+`
+	assert.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expected), "knot_up"))
+
+	require.NoError(t, os.WriteFile(pidFile, []byte("99999\n"), 0o644))
+	assert.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(strings.Replace(expected, "knot_up 1", "knot_up 0", 1)), "knot_up"))
+}
@@ -0,0 +1,314 @@
+package collector
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// metricNameLabel is the pseudo label holding the metric name during relabeling
+const metricNameLabel = "__name__"
+
+// Relabeling actions, with the semantics of Prometheus metric_relabel_configs
+const (
+	relabelReplace   = "replace"
+	relabelKeep      = "keep"
+	relabelDrop      = "drop"
+	relabelLabelMap  = "labelmap"
+	relabelLabelDrop = "labeldrop"
+	relabelLabelKeep = "labelkeep"
+)
+
+var (
+	metricNameRE = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRE  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// RelabelConfig defines how metrics are rewritten before they are exported
+type RelabelConfig struct {
+	// StaticLabels are added to every metric, without overriding labels the
+	// metric already has
+	StaticLabels map[string]string `yaml:"static_labels"`
+	// Rules are applied in order to every metric, the metric name being the
+	// __name__ label
+	Rules []RelabelRule `yaml:"metric_relabel_configs"`
+}
+
+// RelabelRule is a single relabeling step in the format of a Prometheus
+// relabel_config
+type RelabelRule struct {
+	SourceLabels []string `yaml:"source_labels"`
+	Separator    *string  `yaml:"separator"`
+	Regex        *string  `yaml:"regex"`
+	TargetLabel  string   `yaml:"target_label"`
+	Replacement  *string  `yaml:"replacement"`
+	Action       string   `yaml:"action"`
+}
+
+// LoadRelabelConfig reads a relabeling configuration from a YAML file
+func LoadRelabelConfig(path string) (*RelabelConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &RelabelConfig{}
+	if err := yaml.Unmarshal(content, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if _, err := newRelabeler(cfg); err != nil {
+		return nil, fmt.Errorf("invalid relabel config %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// relabelRule is a compiled RelabelRule
+type relabelRule struct {
+	sourceLabels []string
+	separator    string
+	regex        *regexp.Regexp
+	targetLabel  string
+	replacement  string
+	action       string
+}
+
+// relabeler rewrites the metrics of a collection according to a RelabelConfig
+type relabeler struct {
+	staticLabels map[string]string
+	rules        []relabelRule
+
+	mu    sync.Mutex
+	descs map[string]*prometheus.Desc // Descriptors by name, help and label names
+}
+
+func newRelabeler(cfg *RelabelConfig) (*relabeler, error) {
+	r := &relabeler{
+		staticLabels: make(map[string]string),
+		descs:        make(map[string]*prometheus.Desc),
+	}
+
+	for name, value := range cfg.StaticLabels {
+		if !labelNameRE.MatchString(name) || strings.HasPrefix(name, "__") {
+			return nil, fmt.Errorf("invalid static label name %q", name)
+		}
+		r.staticLabels[name] = value
+	}
+
+	for i, rule := range cfg.Rules {
+		compiled, err := compileRelabelRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %v", i+1, err)
+		}
+		r.rules = append(r.rules, compiled)
+	}
+
+	return r, nil
+}
+
+func compileRelabelRule(rule RelabelRule) (relabelRule, error) {
+	compiled := relabelRule{
+		sourceLabels: rule.SourceLabels,
+		separator:    ";",
+		targetLabel:  rule.TargetLabel,
+		replacement:  "$1",
+		action:       strings.ToLower(rule.Action),
+	}
+	if rule.Separator != nil {
+		compiled.separator = *rule.Separator
+	}
+	if rule.Replacement != nil {
+		compiled.replacement = *rule.Replacement
+	}
+	if compiled.action == "" {
+		compiled.action = relabelReplace
+	}
+
+	expr := "(.*)"
+	if rule.Regex != nil {
+		expr = *rule.Regex
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return relabelRule{}, fmt.Errorf("invalid regex %q: %v", expr, err)
+	}
+	compiled.regex = re
+
+	switch compiled.action {
+	case relabelReplace:
+		if compiled.targetLabel == "" {
+			return relabelRule{}, fmt.Errorf("action %s requires target_label", compiled.action)
+		}
+	case relabelKeep, relabelDrop:
+		if len(compiled.sourceLabels) == 0 {
+			return relabelRule{}, fmt.Errorf("action %s requires source_labels", compiled.action)
+		}
+	case relabelLabelMap, relabelLabelDrop, relabelLabelKeep:
+	default:
+		return relabelRule{}, fmt.Errorf("unknown action %q", rule.Action)
+	}
+
+	return compiled, nil
+}
+
+// process applies the rules to a label set, reporting false when the metric
+// is dropped
+func (r *relabeler) process(labels map[string]string) bool {
+	for _, rule := range r.rules {
+		values := make([]string, 0, len(rule.sourceLabels))
+		for _, name := range rule.sourceLabels {
+			values = append(values, labels[name])
+		}
+		value := strings.Join(values, rule.separator)
+
+		switch rule.action {
+		case relabelReplace:
+			match := rule.regex.FindStringSubmatchIndex(value)
+			if match == nil {
+				continue
+			}
+			target := string(rule.regex.ExpandString(nil, rule.targetLabel, value, match))
+			result := string(rule.regex.ExpandString(nil, rule.replacement, value, match))
+			if result == "" {
+				delete(labels, target)
+			} else {
+				labels[target] = result
+			}
+		case relabelKeep:
+			if !rule.regex.MatchString(value) {
+				return false
+			}
+		case relabelDrop:
+			if rule.regex.MatchString(value) {
+				return false
+			}
+		case relabelLabelMap:
+			mapped := make(map[string]string)
+			for name, v := range labels {
+				if rule.regex.MatchString(name) {
+					mapped[rule.regex.ReplaceAllString(name, rule.replacement)] = v
+				}
+			}
+			for name, v := range mapped {
+				labels[name] = v
+			}
+		case relabelLabelDrop:
+			for name := range labels {
+				if name != metricNameLabel && rule.regex.MatchString(name) {
+					delete(labels, name)
+				}
+			}
+		case relabelLabelKeep:
+			for name := range labels {
+				if name != metricNameLabel && !rule.regex.MatchString(name) {
+					delete(labels, name)
+				}
+			}
+		}
+	}
+	return true
+}
+
+// apply rewrites a metric, reporting false when it is dropped
+func (r *relabeler) apply(m prometheus.Metric) (prometheus.Metric, bool) {
+	meta, exists := lookupDesc(m.Desc())
+	if !exists {
+		slog.Debug("Relabeling skipped metric of unknown descriptor", "desc", m.Desc())
+		return m, true
+	}
+	name := meta.name
+
+	var pb dto.Metric
+	if err := m.Write(&pb); err != nil {
+		slog.Debug("Relabeling dropped unreadable metric", "metric", name, "err", err)
+		return nil, false
+	}
+
+	labels := make(map[string]string, len(pb.Label)+len(r.staticLabels)+1)
+	for name, value := range r.staticLabels {
+		labels[name] = value
+	}
+	for _, pair := range pb.Label {
+		labels[pair.GetName()] = pair.GetValue()
+	}
+	labels[metricNameLabel] = name
+
+	if !r.process(labels) {
+		return nil, false
+	}
+
+	name = labels[metricNameLabel]
+	if !metricNameRE.MatchString(name) {
+		slog.Debug("Relabeling dropped metric with invalid name", "metric", name)
+		return nil, false
+	}
+
+	// Labels starting with "__" are internal to relabeling, labels with an
+	// empty value are equal to missing labels
+	names := make([]string, 0, len(labels))
+	for label, value := range labels {
+		if strings.HasPrefix(label, "__") || value == "" {
+			continue
+		}
+		if !labelNameRE.MatchString(label) {
+			slog.Debug("Relabeling dropped metric with invalid label name", "metric", name, "label", label)
+			return nil, false
+		}
+		names = append(names, label)
+	}
+	sort.Strings(names)
+
+	pb.Label = make([]*dto.LabelPair, 0, len(names))
+	for _, label := range names {
+		pb.Label = append(pb.Label, &dto.LabelPair{Name: proto.String(label), Value: proto.String(labels[label])})
+	}
+
+	return &relabeledMetric{
+		desc:   r.desc(name, meta.help, names),
+		meta:   descMeta{name: name, help: meta.help, labels: names},
+		metric: &pb,
+	}, true
+}
+
+// desc returns a cached descriptor for a metric name and label names
+func (r *relabeler) desc(name, help string, labelNames []string) *prometheus.Desc {
+	key := name + "\xff" + help + "\xff" + strings.Join(labelNames, "\xff")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if desc, exists := r.descs[key]; exists {
+		return desc
+	}
+	// Not recorded by newDesc: relabeled metrics are final, and the
+	// descriptors go away with the relabeler on reload
+	desc := prometheus.NewDesc(name, help, labelNames, nil)
+	r.descs[key] = desc
+	return desc
+}
+
+// relabeledMetric is a metric with rewritten name and labels
+type relabeledMetric struct {
+	desc   *prometheus.Desc
+	meta   descMeta
+	metric *dto.Metric
+}
+
+// Desc implements prometheus.Metric interface
+func (m *relabeledMetric) Desc() *prometheus.Desc {
+	return m.desc
+}
+
+// Write implements prometheus.Metric interface
+func (m *relabeledMetric) Write(out *dto.Metric) error {
+	proto.Reset(out)
+	proto.Merge(out, m.metric)
+	return nil
+}
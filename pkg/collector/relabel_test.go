@@ -0,0 +1,265 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// relabelMetrics applies a relabel config to metrics and returns the
+// exported samples by metric name
+func relabelMetrics(t *testing.T, cfg *RelabelConfig, metrics ...prometheus.Metric) map[string][]metricSample {
+	r, err := newRelabeler(cfg)
+	require.NoError(t, err)
+
+	ch := make(chan prometheus.Metric, len(metrics))
+	for _, m := range metrics {
+		if m, ok := r.apply(m); ok {
+			ch <- m
+		}
+	}
+	return drainMetrics(t, ch)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+var relabelTestDesc = newDesc("knot_stats_request_protocol_total", "Global statistic: request-protocol",
+	[]string{"module", "protocol"}, nil)
+
+func relabelTestMetric(protocol string, value float64) prometheus.Metric {
+	return prometheus.MustNewConstMetric(relabelTestDesc, prometheus.CounterValue, value, "mod-stats", protocol)
+}
+
+// TestLookupDesc tests that descriptors keep what they were created with
+func TestLookupDesc(t *testing.T) {
+	meta, exists := lookupDesc(relabelTestDesc)
+	require.True(t, exists)
+	assert.Equal(t, "knot_stats_request_protocol_total", meta.name)
+	assert.Equal(t, "Global statistic: request-protocol", meta.help)
+	assert.Equal(t, []string{"module", "protocol"}, meta.labels)
+
+	meta, exists = lookupDesc(newDesc("knot_test", `Help with "quotes"`, []string{"zone"}, prometheus.Labels{"section": "zone"}))
+	require.True(t, exists)
+	assert.Equal(t, `Help with "quotes"`, meta.help)
+	assert.Equal(t, []string{"section", "zone"}, meta.labels)
+
+	_, exists = lookupDesc(prometheus.NewDesc("knot_test", "", nil, nil))
+	assert.False(t, exists)
+}
+
+// TestRelabelUnknownDesc tests that metrics of foreign descriptors pass
+// through unchanged
+func TestRelabelUnknownDesc(t *testing.T) {
+	r, err := newRelabeler(&RelabelConfig{StaticLabels: map[string]string{"site": "prg1"}})
+	require.NoError(t, err)
+
+	m := prometheus.MustNewConstMetric(prometheus.NewDesc("foreign", "", nil, nil), prometheus.GaugeValue, 1)
+	out, ok := r.apply(m)
+	require.True(t, ok)
+	assert.Same(t, m, out)
+}
+
+// TestRelabelStaticLabels tests that static labels are added without
+// overriding existing labels
+func TestRelabelStaticLabels(t *testing.T) {
+	metrics := relabelMetrics(t, &RelabelConfig{
+		StaticLabels: map[string]string{"site": "prg1", "module": "ignored"},
+	}, relabelTestMetric("udp4", 3))
+
+	require.Len(t, metrics["knot_stats_request_protocol_total"], 1)
+	sample := metrics["knot_stats_request_protocol_total"][0]
+	assert.Equal(t, map[string]string{"module": "mod-stats", "protocol": "udp4", "site": "prg1"}, sample.labels)
+	assert.Equal(t, float64(3), sample.value)
+}
+
+// TestRelabelRename tests renaming a metric through the __name__ label
+func TestRelabelRename(t *testing.T) {
+	metrics := relabelMetrics(t, &RelabelConfig{
+		Rules: []RelabelRule{{
+			SourceLabels: []string{"__name__"},
+			Regex:        stringPtr("knot_stats_(.*)_total"),
+			TargetLabel:  "__name__",
+			Replacement:  stringPtr("knot_${1}_total"),
+		}},
+	}, relabelTestMetric("udp4", 3))
+
+	assert.Empty(t, metrics["knot_stats_request_protocol_total"])
+	require.Len(t, metrics["knot_request_protocol_total"], 1)
+}
+
+// TestRelabelDropAndKeep tests dropping metrics by label values
+func TestRelabelDropAndKeep(t *testing.T) {
+	metrics := relabelMetrics(t, &RelabelConfig{
+		Rules: []RelabelRule{{
+			SourceLabels: []string{"protocol"},
+			Regex:        stringPtr("tcp.*"),
+			Action:       "drop",
+		}},
+	}, relabelTestMetric("udp4", 1), relabelTestMetric("tcp4", 2))
+
+	require.Len(t, metrics["knot_stats_request_protocol_total"], 1)
+	assert.Equal(t, "udp4", metrics["knot_stats_request_protocol_total"][0].labels["protocol"])
+
+	metrics = relabelMetrics(t, &RelabelConfig{
+		Rules: []RelabelRule{{
+			SourceLabels: []string{"__name__", "protocol"},
+			Regex:        stringPtr("knot_stats_.*;tcp4"),
+			Action:       "keep",
+		}},
+	}, relabelTestMetric("udp4", 1), relabelTestMetric("tcp4", 2))
+
+	require.Len(t, metrics["knot_stats_request_protocol_total"], 1)
+	assert.Equal(t, "tcp4", metrics["knot_stats_request_protocol_total"][0].labels["protocol"])
+}
+
+// TestRelabelLabelActions tests labelmap, labeldrop and labelkeep
+func TestRelabelLabelActions(t *testing.T) {
+	metrics := relabelMetrics(t, &RelabelConfig{
+		StaticLabels: map[string]string{"site": "prg1", "tier": "edge"},
+		Rules: []RelabelRule{
+			{Regex: stringPtr("protocol"), Replacement: stringPtr("proto"), Action: "labelmap"},
+			{Regex: stringPtr("protocol|tier"), Action: "labeldrop"},
+			{Regex: stringPtr("proto|site"), Action: "labelkeep"},
+		},
+	}, relabelTestMetric("udp4", 1))
+
+	require.Len(t, metrics["knot_stats_request_protocol_total"], 1)
+	assert.Equal(t, map[string]string{"proto": "udp4", "site": "prg1"},
+		metrics["knot_stats_request_protocol_total"][0].labels)
+}
+
+// TestRelabelKeepsType tests that relabeled metrics keep their type and help
+func TestRelabelKeepsType(t *testing.T) {
+	r, err := newRelabeler(&RelabelConfig{StaticLabels: map[string]string{"site": "prg1"}})
+	require.NoError(t, err)
+
+	m, ok := r.apply(relabelTestMetric("udp4", 5))
+	require.True(t, ok)
+
+	var pb dto.Metric
+	require.NoError(t, m.Write(&pb))
+	require.NotNil(t, pb.Counter)
+	assert.Equal(t, float64(5), pb.GetCounter().GetValue())
+
+	assert.Equal(t, "Global statistic: request-protocol", m.(*relabeledMetric).meta.help)
+	_, exists := lookupDesc(m.Desc())
+	assert.False(t, exists, "Relabeled descriptors should not be recorded")
+
+	// Descriptors are shared between series of a family
+	other, ok := r.apply(relabelTestMetric("tcp4", 1))
+	require.True(t, ok)
+	assert.Same(t, m.Desc(), other.Desc())
+}
+
+// TestRelabelInvalidName tests that metrics renamed to invalid names are dropped
+func TestRelabelInvalidName(t *testing.T) {
+	metrics := relabelMetrics(t, &RelabelConfig{
+		Rules: []RelabelRule{{
+			TargetLabel: "__name__",
+			Replacement: stringPtr("invalid-name"),
+		}},
+	}, relabelTestMetric("udp4", 1))
+
+	assert.Empty(t, metrics)
+}
+
+// TestNewRelabelerErrors tests validation of relabel configs
+func TestNewRelabelerErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  RelabelConfig
+	}{
+		{"invalid static label", RelabelConfig{StaticLabels: map[string]string{"bad-label": "x"}}},
+		{"reserved static label", RelabelConfig{StaticLabels: map[string]string{"__name__": "x"}}},
+		{"unknown action", RelabelConfig{Rules: []RelabelRule{{Action: "explode"}}}},
+		{"invalid regex", RelabelConfig{Rules: []RelabelRule{{Regex: stringPtr("("), TargetLabel: "x"}}}},
+		{"replace without target", RelabelConfig{Rules: []RelabelRule{{Action: "replace"}}}},
+		{"drop without source", RelabelConfig{Rules: []RelabelRule{{Action: "drop"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newRelabeler(&tt.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestLoadRelabelConfig tests loading a relabel config file
+func TestLoadRelabelConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "relabel.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+static_labels:
+  site: prg1
+metric_relabel_configs:
+  - source_labels: [__name__]
+    regex: '.*_total'
+    action: drop
+`), 0o600))
+
+	cfg, err := LoadRelabelConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"site": "prg1"}, cfg.StaticLabels)
+	require.Len(t, cfg.Rules, 1)
+	assert.Equal(t, "drop", cfg.Rules[0].Action)
+
+	require.NoError(t, os.WriteFile(path, []byte("metric_relabel_configs:\n  - action: explode\n"), 0o600))
+	_, err = LoadRelabelConfig(path)
+	assert.Error(t, err)
+
+	_, err = LoadRelabelConfig(filepath.Join(t.TempDir(), "missing.yml"))
+	assert.Error(t, err)
+}
+
+// TestCollectWithRelabelConfig tests that relabeling applies to collected metrics
+func TestCollectWithRelabelConfig(t *testing.T) {
+	collector := NewKnotCollector("/nonexistent", 1000, false, false, false, false, false, false,
+		WithRelabelConfig(&RelabelConfig{
+			StaticLabels: map[string]string{"tier": "edge"},
+			Rules: []RelabelRule{{
+				SourceLabels: []string{"__name__"},
+				Regex:        stringPtr("knot_exporter_.*"),
+				Action:       "drop",
+			}},
+		}))
+
+	ch := make(chan prometheus.Metric, 10)
+	collector.Collect(ch)
+	metrics := drainMetrics(t, ch)
+
+	assert.Empty(t, metrics["knot_exporter_deduplicated_scrapes_total"])
+	require.Len(t, metrics["knot_build_info"], 1)
+	assert.Equal(t, "edge", metrics["knot_build_info"][0].labels["tier"])
+
+	// Relabeling makes the collector unchecked
+	descs := make(chan *prometheus.Desc, 10)
+	collector.Describe(descs)
+	close(descs)
+	assert.Empty(t, descs)
+}
+
+// TestRelabelDescriptorsNotRecorded tests that relabelers, rebuilt on every
+// reload, don't grow the descriptor metadata
+func TestRelabelDescriptorsNotRecorded(t *testing.T) {
+	descMetasMutex.RLock()
+	before := len(descMetas)
+	descMetasMutex.RUnlock()
+
+	for i := 0; i < 3; i++ {
+		r, err := newRelabeler(&RelabelConfig{StaticLabels: map[string]string{"site": "prg1"}})
+		require.NoError(t, err)
+		_, ok := r.apply(relabelTestMetric("udp4", 5))
+		require.True(t, ok)
+	}
+
+	descMetasMutex.RLock()
+	defer descMetasMutex.RUnlock()
+	assert.Equal(t, before, len(descMetas))
+}
@@ -0,0 +1,141 @@
+package collector
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	reloadSuccessfulDesc = newDesc(
+		"knot_exporter_config_last_reload_successful",
+		"Whether the last configuration reload succeeded",
+		nil, nil,
+	)
+	reloadTimestampDesc = newDesc(
+		"knot_exporter_config_last_reload_success_timestamp_seconds",
+		"Time of the last successful configuration reload, or of the start",
+		nil, nil,
+	)
+	reloadsDesc = newDesc(
+		"knot_exporter_config_reloads_total",
+		"Number of configuration reloads by result",
+		[]string{"result"}, nil,
+	)
+)
+
+// ReloadableCollector serves a KnotCollector that a reload replaces
+// atomically: a scrape uses either the previous or the new collector as a
+// whole. The exported metrics change with the configuration, so it is an
+// unchecked collector.
+type ReloadableCollector struct {
+	current atomic.Pointer[KnotCollector]
+
+	mu             sync.Mutex // Serializes reloads
+	lastSuccessful bool
+	lastSuccess    time.Time
+	successes      int
+	failures       int
+}
+
+// NewReloadableCollector returns a ReloadableCollector serving c
+func NewReloadableCollector(c *KnotCollector) *ReloadableCollector {
+	r := &ReloadableCollector{lastSuccessful: true, lastSuccess: time.Now()}
+	r.current.Store(c)
+	return r
+}
+
+// Collector returns the collector currently in use
+func (r *ReloadableCollector) Collector() *KnotCollector {
+	return r.current.Load()
+}
+
+// Reload builds a new collector and swaps it in. When build fails, the
+// current collector stays in use and the error is returned. The outcome is
+// exported as metrics.
+func (r *ReloadableCollector) Reload(build func() (*KnotCollector, error)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, err := build()
+	if err != nil {
+		r.lastSuccessful = false
+		r.failures++
+		return err
+	}
+
+	r.current.Store(c)
+	r.lastSuccessful = true
+	r.lastSuccess = time.Now()
+	r.successes++
+	return nil
+}
+
+// Describe implements prometheus.Collector interface. It describes nothing,
+// making the collector unchecked.
+func (r *ReloadableCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector interface
+func (r *ReloadableCollector) Collect(ch chan<- prometheus.Metric) {
+	c := r.Collector()
+	c.Collect(ch)
+
+	r.mu.Lock()
+	successful := 0.0
+	if r.lastSuccessful {
+		successful = 1
+	}
+	metrics := []prometheus.Metric{
+		prometheus.MustNewConstMetric(reloadSuccessfulDesc, prometheus.GaugeValue, successful),
+		prometheus.MustNewConstMetric(reloadTimestampDesc, prometheus.GaugeValue, float64(r.lastSuccess.UnixNano())/1e9),
+		prometheus.MustNewConstMetric(reloadsDesc, prometheus.CounterValue, float64(r.successes), "success"),
+		prometheus.MustNewConstMetric(reloadsDesc, prometheus.CounterValue, float64(r.failures), "failure"),
+	}
+	r.mu.Unlock()
+
+	for _, m := range metrics {
+		if m, ok := c.relabel(m); ok {
+			ch <- m
+		}
+	}
+}
+
+// LastError returns the errors of the most recent collection of the current
+// collector
+func (r *ReloadableCollector) LastError() error {
+	return r.Collector().LastError()
+}
+
+// LastCollection returns when the current collector last finished a
+// collection
+func (r *ReloadableCollector) LastCollection() time.Time {
+	return r.Collector().LastCollection()
+}
+
+// CollectorStatus returns the state of the collectors of the current
+// collector
+func (r *ReloadableCollector) CollectorStatus() []CollectorStatus {
+	return r.Collector().CollectorStatus()
+}
+
+// CachesWarm reports whether the caches of the current collector are filled
+func (r *ReloadableCollector) CachesWarm() bool {
+	return r.Collector().CachesWarm()
+}
+
+// Warm runs a collection of the current collector unless one finished
+func (r *ReloadableCollector) Warm() {
+	r.Collector().Warm()
+}
+
+// ZoneList returns the zone list of the current collector
+func (r *ReloadableCollector) ZoneList() ([]ZoneEntry, error) {
+	return r.Collector().ZoneList()
+}
+
+// Zones returns the status of zones from the current collector
+func (r *ReloadableCollector) Zones(zones []string) ([]ZoneInfo, error) {
+	return r.Collector().Zones(zones)
+}
@@ -0,0 +1,92 @@
+package collector
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// zoneMetric is the zone statistic served by newZoneServer
+const zoneMetric = "knot_zone_stats_query_type_total"
+
+// TestReloadableCollector tests swapping the collector and the reload metrics
+func TestReloadableCollector(t *testing.T) {
+	server := newZoneServer("example.com.")
+	first := NewKnotCollector("/test", 1000, false, false, false, false, false, false)
+	first.newCtl = ctlFactory(server)
+	r := NewReloadableCollector(first)
+	assert.Same(t, first, r.Collector())
+
+	ch := make(chan prometheus.Metric, 100)
+	r.Collect(ch)
+	metrics := drainMetrics(t, ch)
+	assert.NotContains(t, metrics, zoneMetric)
+	assert.Equal(t, 1.0, metrics["knot_exporter_config_last_reload_successful"][0].value)
+	started := metrics["knot_exporter_config_last_reload_success_timestamp_seconds"][0].value
+	assert.Positive(t, started)
+
+	// A failed reload keeps the collector
+	require.Error(t, r.Reload(func() (*KnotCollector, error) {
+		return nil, errors.New("invalid zone filter")
+	}))
+	assert.Same(t, first, r.Collector())
+
+	ch = make(chan prometheus.Metric, 100)
+	r.Collect(ch)
+	metrics = drainMetrics(t, ch)
+	assert.Equal(t, 0.0, metrics["knot_exporter_config_last_reload_successful"][0].value)
+	assert.Equal(t, started, metrics["knot_exporter_config_last_reload_success_timestamp_seconds"][0].value)
+
+	// A successful reload serves the new collector
+	second := NewKnotCollector("/test", 1000, false, false, true, false, false, false)
+	second.newCtl = ctlFactory(server)
+	require.NoError(t, r.Reload(func() (*KnotCollector, error) { return second, nil }))
+	assert.Same(t, second, r.Collector())
+
+	ch = make(chan prometheus.Metric, 100)
+	r.Collect(ch)
+	metrics = drainMetrics(t, ch)
+	require.Len(t, metrics[zoneMetric], 1)
+	assert.Equal(t, 1.0, metrics["knot_exporter_config_last_reload_successful"][0].value)
+	assert.GreaterOrEqual(t, metrics["knot_exporter_config_last_reload_success_timestamp_seconds"][0].value, started)
+
+	reloads := make(map[string]float64)
+	for _, sample := range metrics["knot_exporter_config_reloads_total"] {
+		reloads[sample.labels["result"]] = sample.value
+	}
+	assert.Equal(t, map[string]float64{"success": 1, "failure": 1}, reloads)
+
+	// The state is the one of the new collector
+	assert.NoError(t, r.LastError())
+	assert.Equal(t, second.LastCollection(), r.LastCollection())
+	assert.True(t, r.CachesWarm())
+}
+
+// TestReloadableCollectorRegistry tests that the exported metrics may change
+// while registered
+func TestReloadableCollectorRegistry(t *testing.T) {
+	server := newZoneServer("example.com.")
+	first := NewKnotCollector("/test", 1000, false, false, false, false, false, false)
+	first.newCtl = ctlFactory(server)
+	r := NewReloadableCollector(first)
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(r))
+	_, err := registry.Gather()
+	require.NoError(t, err)
+
+	second := NewKnotCollector("/test", 1000, false, false, true, false, false, false)
+	second.newCtl = ctlFactory(server)
+	require.NoError(t, r.Reload(func() (*KnotCollector, error) { return second, nil }))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	var names []string
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	assert.Contains(t, names, zoneMetric)
+}
@@ -0,0 +1,33 @@
+package collector
+
+// defaultStatsIDLabel is the label holding the ID of statistics items without
+// a known meaning
+const defaultStatsIDLabel = "type"
+
+// statsIDLabels maps Knot DNS statistics items to the name of the label
+// holding their ID, so that e.g. response codes and protocols don't share one
+// ambiguous "type" label. Items not listed use defaultStatsIDLabel.
+var statsIDLabels = map[string]string{
+	// mod-stats
+	"request-protocol":     "protocol",
+	"server-operation":     "operation",
+	"request-bytes":        "operation",
+	"response-bytes":       "operation",
+	"edns-presence":        "direction",
+	"flag-presence":        "flag",
+	"response-code":        "rcode",
+	"request-edns-option":  "edns_option",
+	"response-edns-option": "edns_option",
+	"reply-nodata":         "qtype",
+	"query-type":           "qtype",
+	"query-size":           "size_bucket",
+	"reply-size":           "size_bucket",
+}
+
+// statsIDLabel returns the name of the label holding the ID of a statistics item
+func statsIDLabel(item string) string {
+	if label, ok := statsIDLabels[item]; ok {
+		return label
+	}
+	return defaultStatsIDLabel
+}
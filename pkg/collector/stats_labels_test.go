@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStatsIDLabel tests the ID label names of statistics items
+func TestStatsIDLabel(t *testing.T) {
+	assert.Equal(t, "rcode", statsIDLabel("response-code"))
+	assert.Equal(t, "protocol", statsIDLabel("request-protocol"))
+	assert.Equal(t, "qtype", statsIDLabel("query-type"))
+	assert.Equal(t, "flag", statsIDLabel("flag-presence"))
+	assert.Equal(t, "size_bucket", statsIDLabel("query-size"))
+	assert.Equal(t, "type", statsIDLabel("unknown-item"))
+
+	for item, label := range statsIDLabels {
+		assert.NotContains(t, []string{"zone", "module", "le"}, label, "Label of %s clashes with a fixed label", item)
+	}
+}
+
+// TestCollectGlobalStats_IDLabels tests that known items use their own ID label
+func TestCollectGlobalStats_IDLabels(t *testing.T) {
+	mockCtl := new(MockLibknotCtl)
+	mockCtl.On("SendCommand", "stats").Return(nil)
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+		Section: "mod-stats", Item: "response-code", ID: "NXDOMAIN", Data: "3",
+	}, nil).Once()
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+		Section: "mod-stats", Item: "labels-unknown-item", ID: "foo", Data: "4",
+	}, nil).Once()
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeBlock, nil, nil).Once()
+
+	collector := NewKnotCollector("/test", 1000, false, true, false, false, false, false)
+	ch := make(chan prometheus.Metric, 10)
+	require.NoError(t, collector.collectGlobalStats(mockCtl, ch))
+
+	metrics := drainMetrics(t, ch)
+	require.Len(t, metrics["knot_stats_response_code_total"], 1)
+	assert.Equal(t, map[string]string{"module": "mod-stats", "rcode": "NXDOMAIN"},
+		metrics["knot_stats_response_code_total"][0].labels)
+	require.Len(t, metrics["knot_stats_labels_unknown_item_total"], 1)
+	assert.Equal(t, map[string]string{"module": "mod-stats", "type": "foo"},
+		metrics["knot_stats_labels_unknown_item_total"][0].labels)
+	mockCtl.AssertExpectations(t)
+}
+
+// TestCollectZoneStatistics_IDLabels tests ID labels of zone statistics
+func TestCollectZoneStatistics_IDLabels(t *testing.T) {
+	mockCtl := new(MockLibknotCtl)
+	mockCtl.On("SendCommand", "zone-stats").Return(nil)
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+		Zone: "example.com.", Section: "mod-stats", Item: "request-protocol", ID: "udp4", Data: "10",
+	}, nil).Once()
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeBlock, nil, nil).Once()
+
+	collector := NewKnotCollector("/test", 1000, false, false, true, false, false, false)
+	ch := make(chan prometheus.Metric, 10)
+	require.NoError(t, collector.collectZoneStatistics(mockCtl, ch))
+
+	metrics := drainMetrics(t, ch)
+	require.Len(t, metrics["knot_zone_stats_request_protocol_total"], 1)
+	assert.Equal(t, map[string]string{"zone": "example.com.", "module": "mod-stats", "protocol": "udp4"},
+		metrics["knot_zone_stats_request_protocol_total"][0].labels)
+	mockCtl.AssertExpectations(t)
+}
@@ -0,0 +1,159 @@
+package collector
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CollectorStatus is the state of a single collector, such as global stats
+// or zone status, as of its most recent run
+type CollectorStatus struct {
+	Name          string     `json:"name"`
+	Healthy       bool       `json:"healthy"` // Whether the last run succeeded
+	LastRun       time.Time  `json:"last_run"`
+	LastSuccess   *time.Time `json:"last_success,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+	Duration      float64    `json:"duration_seconds"` // Duration of the last run, over all batches
+}
+
+// collectorRun accumulates the tasks of a collector in a single collection
+type collectorRun struct {
+	start, end time.Time
+	errs       []error
+}
+
+// recordRun records a finished task of the collection in progress
+func (c *KnotCollector) recordRun(task collectTask, start time.Time, err error) {
+	end := time.Now()
+
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+
+	if c.collectRuns == nil {
+		c.collectRuns = make(map[string]*collectorRun)
+	}
+	run, exists := c.collectRuns[task.collector()]
+	if !exists {
+		run = &collectorRun{start: start, end: end}
+		c.collectRuns[task.collector()] = run
+	}
+	run.start = minTime(run.start, start)
+	run.end = maxTime(run.end, end)
+	if err != nil {
+		run.errs = append(run.errs, err)
+	}
+}
+
+// updateStatus folds the runs of a finished collection into the collector
+// statuses
+func (c *KnotCollector) updateStatus(finished time.Time) {
+	c.errMu.Lock()
+	runs := c.collectRuns
+	c.collectRuns = nil
+	c.errMu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.statuses == nil {
+		c.statuses = make(map[string]*CollectorStatus)
+	}
+	for name, run := range runs {
+		status, exists := c.statuses[name]
+		if !exists {
+			status = &CollectorStatus{Name: name}
+			c.statuses[name] = status
+		}
+
+		end := run.end
+		status.LastRun = end
+		status.Duration = run.end.Sub(run.start).Seconds()
+		status.Healthy = len(run.errs) == 0
+		if status.Healthy {
+			status.LastSuccess = &end
+		} else {
+			status.LastError = errors.Join(run.errs...).Error()
+			status.LastErrorTime = &end
+		}
+	}
+
+	c.lastCollection = finished
+	c.zoneListMu.Lock()
+	c.zoneListWarm = !c.zoneListFetched.IsZero()
+	c.zoneListMu.Unlock()
+}
+
+// CollectorStatus returns the state of every collector that ran so far,
+// sorted by name
+func (c *KnotCollector) CollectorStatus() []CollectorStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	statuses := make([]CollectorStatus, 0, len(c.statuses))
+	for _, status := range c.statuses {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// LastCollection returns when the most recent collection finished, or the
+// zero time if none did yet
+func (c *KnotCollector) LastCollection() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastCollection
+}
+
+// CachesWarm reports whether a collection finished and filled the zone list
+// cache, if the configuration relies on it
+func (c *KnotCollector) CachesWarm() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.lastCollection.IsZero() {
+		return false
+	}
+	perZone := c.collectZoneStatus || c.collectZoneSerial || c.collectZoneStats || c.collectZoneTimers
+	if perZone && (c.zoneBatchSize > 0 || c.zoneFilter.NeedsCatalogs()) {
+		return c.zoneListWarm
+	}
+	return true
+}
+
+// Warm runs a collection, discarding its metrics, unless one already
+// finished. Concurrent scrapes share it.
+func (c *KnotCollector) Warm() {
+	if !c.LastCollection().IsZero() {
+		return
+	}
+
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+	c.Collect(ch)
+	close(ch)
+	<-done
+}
+
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,116 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot/scripted"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCollectorStatus tests tracking of the outcome of every collector
+func TestCollectorStatus(t *testing.T) {
+	server := &scripted.Server{Responses: map[string][]scripted.Record{
+		"zone-status": {{Zone: "example.com.", Type: "role", Data: "master"}},
+	}}
+	collector := NewKnotCollector("/test", 1000, false, true, false, true, false, false)
+	collector.newCtl = ctlFactory(server)
+
+	assert.True(t, collector.LastCollection().IsZero())
+	assert.False(t, collector.CachesWarm())
+	assert.Empty(t, collector.CollectorStatus())
+
+	collector.Warm()
+	first := collector.LastCollection()
+	require.False(t, first.IsZero())
+	assert.True(t, collector.CachesWarm())
+
+	statuses := collector.CollectorStatus()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "global stats", statuses[0].Name)
+	assert.Equal(t, "zone status", statuses[1].Name)
+	for _, status := range statuses {
+		assert.True(t, status.Healthy)
+		require.NotNil(t, status.LastSuccess)
+		assert.Empty(t, status.LastError)
+		assert.GreaterOrEqual(t, status.Duration, float64(0))
+	}
+
+	// Warming up again doesn't collect
+	collector.Warm()
+	assert.Equal(t, first, collector.LastCollection())
+}
+
+// TestCollectorStatusErrors tests that failures are kept after recovery
+func TestCollectorStatusErrors(t *testing.T) {
+	collector := NewKnotCollector("/nonexistent", 1000, false, true, false, false, false, false)
+
+	ch := make(chan prometheus.Metric, 10)
+	collector.collect(ch)
+	drainMetrics(t, ch)
+
+	statuses := collector.CollectorStatus()
+	require.Len(t, statuses, 1)
+	assert.False(t, statuses[0].Healthy)
+	assert.Nil(t, statuses[0].LastSuccess)
+	assert.Contains(t, statuses[0].LastError, "failed to connect")
+	require.NotNil(t, statuses[0].LastErrorTime)
+	failure := *statuses[0].LastErrorTime
+
+	collector.newCtl = ctlFactory(&scripted.Server{})
+	ch = make(chan prometheus.Metric, 10)
+	collector.collect(ch)
+	drainMetrics(t, ch)
+
+	statuses = collector.CollectorStatus()
+	require.Len(t, statuses, 1)
+	assert.True(t, statuses[0].Healthy)
+	assert.NotNil(t, statuses[0].LastSuccess)
+	assert.Contains(t, statuses[0].LastError, "failed to connect")
+	assert.Equal(t, failure, *statuses[0].LastErrorTime)
+}
+
+// TestCachesWarmZoneList tests that batched collection needs the zone list
+func TestCachesWarmZoneList(t *testing.T) {
+	collector := NewKnotCollector("/nonexistent", 1000, false, false, false, true, false, false,
+		WithZoneBatchSize(10))
+
+	ch := make(chan prometheus.Metric, 10)
+	collector.collect(ch)
+	drainMetrics(t, ch)
+
+	assert.False(t, collector.LastCollection().IsZero())
+	assert.False(t, collector.CachesWarm())
+
+	collector.newCtl = ctlFactory(&scripted.Server{})
+	ch = make(chan prometheus.Metric, 10)
+	collector.collect(ch)
+	drainMetrics(t, ch)
+	assert.True(t, collector.CachesWarm())
+}
+
+// TestCollectorStatusBatches tests that batches are reported as one collector
+func TestCollectorStatusBatches(t *testing.T) {
+	server := &scripted.Server{Responses: map[string][]scripted.Record{
+		"zone-status": {
+			{Zone: "a.example.", Type: "role", Data: "master"},
+			{Zone: "b.example.", Type: "role", Data: "master"},
+			{Zone: "c.example.", Type: "role", Data: "master"},
+		},
+	}}
+	collector := NewKnotCollector("/test", 1000, false, false, false, true, false, false,
+		WithZoneBatchSize(1))
+	collector.newCtl = ctlFactory(server)
+
+	ch := make(chan prometheus.Metric, 10)
+	collector.collect(ch)
+	drainMetrics(t, ch)
+
+	var names []string
+	for _, status := range collector.CollectorStatus() {
+		names = append(names, status.Name)
+		assert.True(t, status.Healthy)
+	}
+	assert.Equal(t, []string{"zone list", "zone status"}, names)
+}
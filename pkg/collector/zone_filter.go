@@ -0,0 +1,186 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Zone filter rule prefixes. A rule without a prefix is an exact zone name.
+const (
+	zoneRuleExact   = "exact:"
+	zoneRuleSuffix  = "suffix:"
+	zoneRuleRegex   = "regex:"
+	zoneRuleCatalog = "catalog:"
+)
+
+// zoneRule matches zones by name or by catalog membership
+type zoneRule struct {
+	exact   string
+	suffix  string
+	regex   *regexp.Regexp
+	catalog string
+}
+
+// ZoneFilter selects the zones per-zone metrics are collected for. A zone is
+// selected when it matches at least one include rule (or there are none) and
+// no exclude rule.
+type ZoneFilter struct {
+	include []zoneRule
+	exclude []zoneRule
+}
+
+// NewZoneFilter parses include and exclude rules. Supported rule forms are
+// "example.com" or "exact:example.com", "suffix:example.com" (the zone
+// itself and everything below it), "regex:<expression>" matched against the
+// zone name without the trailing dot, and "catalog:catz.example" selecting
+// members of the given catalog zone.
+func NewZoneFilter(include, exclude []string) (*ZoneFilter, error) {
+	f := &ZoneFilter{}
+
+	for _, rule := range include {
+		r, err := parseZoneRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid zone include rule %q: %v", rule, err)
+		}
+		f.include = append(f.include, r)
+	}
+
+	for _, rule := range exclude {
+		r, err := parseZoneRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid zone exclude rule %q: %v", rule, err)
+		}
+		f.exclude = append(f.exclude, r)
+	}
+
+	return f, nil
+}
+
+func parseZoneRule(rule string) (zoneRule, error) {
+	switch {
+	case strings.HasPrefix(rule, zoneRuleSuffix):
+		suffix := normalizeZoneName(strings.TrimPrefix(rule, zoneRuleSuffix))
+		if suffix == "" {
+			return zoneRule{}, fmt.Errorf("empty suffix")
+		}
+		return zoneRule{suffix: suffix}, nil
+	case strings.HasPrefix(rule, zoneRuleRegex):
+		re, err := regexp.Compile(strings.TrimPrefix(rule, zoneRuleRegex))
+		if err != nil {
+			return zoneRule{}, err
+		}
+		return zoneRule{regex: re}, nil
+	case strings.HasPrefix(rule, zoneRuleCatalog):
+		catalog := normalizeZoneName(strings.TrimPrefix(rule, zoneRuleCatalog))
+		if catalog == "" {
+			return zoneRule{}, fmt.Errorf("empty catalog zone")
+		}
+		return zoneRule{catalog: catalog}, nil
+	default:
+		exact := normalizeZoneName(strings.TrimPrefix(rule, zoneRuleExact))
+		if exact == "" {
+			return zoneRule{}, fmt.Errorf("empty zone name")
+		}
+		return zoneRule{exact: exact}, nil
+	}
+}
+
+// normalizeZoneName lowercases a zone name and strips the trailing dot so
+// that "Example.COM." and "example.com" compare equal
+func normalizeZoneName(zone string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(zone)), ".")
+}
+
+func (r zoneRule) matches(zone, catalog string) bool {
+	switch {
+	case r.exact != "":
+		return zone == r.exact
+	case r.suffix != "":
+		return zone == r.suffix || strings.HasSuffix(zone, "."+r.suffix)
+	case r.regex != nil:
+		return r.regex.MatchString(zone)
+	case r.catalog != "":
+		return catalog != "" && catalog == r.catalog
+	}
+	return false
+}
+
+// Match reports whether metrics should be collected for the zone. The catalog
+// argument is the catalog zone the zone is a member of, or empty.
+func (f *ZoneFilter) Match(zone, catalog string) bool {
+	if f == nil {
+		return true
+	}
+
+	zone = normalizeZoneName(zone)
+	catalog = normalizeZoneName(catalog)
+
+	if len(f.include) > 0 {
+		included := false
+		for _, r := range f.include {
+			if r.matches(zone, catalog) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+
+	for _, r := range f.exclude {
+		if r.matches(zone, catalog) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NeedsCatalogs reports whether any rule depends on catalog membership
+func (f *ZoneFilter) NeedsCatalogs() bool {
+	if f == nil {
+		return false
+	}
+	for _, rules := range [][]zoneRule{f.include, f.exclude} {
+		for _, r := range rules {
+			if r.catalog != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ExactZones returns the zone names to pass to Knot DNS as command arguments
+// when the include rules consist of exact names only. Exclude rules still
+// have to be applied to the responses.
+func (f *ZoneFilter) ExactZones() ([]string, bool) {
+	if f == nil || len(f.include) == 0 {
+		return nil, false
+	}
+
+	zones := make([]string, 0, len(f.include))
+	for _, r := range f.include {
+		if r.exact == "" {
+			return nil, false
+		}
+		zones = append(zones, r.exact+".")
+	}
+	return zones, true
+}
+
+// parseCatalogMembership extracts the catalog zone name from the zone-status
+// "catalog" value, which is the catalog zone optionally followed by
+// "#<group>" for member zones
+func parseCatalogMembership(value string) string {
+	switch value {
+	case "", "-", "none", "generated", "interpret":
+		return ""
+	}
+	if idx := strings.Index(value, "#"); idx >= 0 {
+		value = value[:idx]
+	}
+	return normalizeZoneName(value)
+}
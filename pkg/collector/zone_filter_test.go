@@ -0,0 +1,257 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestZoneFilterMatch tests include and exclude rule matching
+func TestZoneFilterMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		zone     string
+		catalog  string
+		expected bool
+	}{
+		{"no rules", nil, nil, "example.com.", "", true},
+		{"exact match", []string{"example.com"}, nil, "example.com.", "", true},
+		{"exact prefix match", []string{"exact:example.com."}, nil, "example.com", "", true},
+		{"exact case insensitive", []string{"Example.COM"}, nil, "example.com.", "", true},
+		{"exact no match", []string{"example.com"}, nil, "example.org.", "", false},
+		{"exact no subdomain", []string{"example.com"}, nil, "sub.example.com.", "", false},
+		{"suffix zone itself", []string{"suffix:example.com"}, nil, "example.com.", "", true},
+		{"suffix subdomain", []string{"suffix:example.com"}, nil, "a.b.example.com.", "", true},
+		{"suffix label boundary", []string{"suffix:example.com"}, nil, "badexample.com.", "", false},
+		{"regex match", []string{"regex:^cust[0-9]+\\.example$"}, nil, "cust42.example.", "", true},
+		{"regex no match", []string{"regex:^cust[0-9]+\\.example$"}, nil, "other.example.", "", false},
+		{"catalog member", []string{"catalog:catz.example"}, nil, "member.example.", "catz.example.", true},
+		{"catalog other", []string{"catalog:catz.example"}, nil, "member.example.", "other.example.", false},
+		{"catalog not member", []string{"catalog:catz.example"}, nil, "member.example.", "", false},
+		{"exclude only", nil, []string{"suffix:internal"}, "db.internal.", "", false},
+		{"exclude only passes others", nil, []string{"suffix:internal"}, "example.com.", "", true},
+		{"exclude wins over include", []string{"suffix:example.com"}, []string{"test.example.com"}, "test.example.com.", "", false},
+		{"any include matches", []string{"example.org", "suffix:example.com"}, nil, "www.example.com.", "", true},
+		{"exclude catalog", nil, []string{"catalog:catz.example"}, "member.example.", "catz.example", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewZoneFilter(tt.include, tt.exclude)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, f.Match(tt.zone, tt.catalog))
+		})
+	}
+}
+
+// TestZoneFilterNil tests that a nil filter selects everything
+func TestZoneFilterNil(t *testing.T) {
+	var f *ZoneFilter
+	assert.True(t, f.Match("example.com.", ""))
+	assert.False(t, f.NeedsCatalogs())
+
+	zones, ok := f.ExactZones()
+	assert.False(t, ok)
+	assert.Nil(t, zones)
+}
+
+// TestZoneFilterInvalidRules tests rule parsing errors
+func TestZoneFilterInvalidRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+	}{
+		{"empty name", []string{""}, nil},
+		{"empty suffix", []string{"suffix:"}, nil},
+		{"empty catalog", nil, []string{"catalog:."}},
+		{"invalid regex", []string{"regex:("}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewZoneFilter(tt.include, tt.exclude)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestZoneFilterExactZones tests detection of rules that can be pushed down to Knot DNS
+func TestZoneFilterExactZones(t *testing.T) {
+	f, err := NewZoneFilter([]string{"example.com", "Example.ORG."}, []string{"suffix:test"})
+	require.NoError(t, err)
+	zones, ok := f.ExactZones()
+	assert.True(t, ok)
+	assert.Equal(t, []string{"example.com.", "example.org."}, zones)
+
+	f, err = NewZoneFilter([]string{"example.com", "suffix:example.org"}, nil)
+	require.NoError(t, err)
+	_, ok = f.ExactZones()
+	assert.False(t, ok)
+
+	f, err = NewZoneFilter(nil, []string{"example.com"})
+	require.NoError(t, err)
+	_, ok = f.ExactZones()
+	assert.False(t, ok)
+}
+
+// TestZoneFilterNeedsCatalogs tests detection of catalog based rules
+func TestZoneFilterNeedsCatalogs(t *testing.T) {
+	f, err := NewZoneFilter([]string{"example.com"}, []string{"regex:test"})
+	require.NoError(t, err)
+	assert.False(t, f.NeedsCatalogs())
+
+	f, err = NewZoneFilter(nil, []string{"catalog:catz"})
+	require.NoError(t, err)
+	assert.True(t, f.NeedsCatalogs())
+}
+
+// TestParseCatalogMembership tests parsing of the zone-status catalog value
+func TestParseCatalogMembership(t *testing.T) {
+	assert.Equal(t, "", parseCatalogMembership("-"))
+	assert.Equal(t, "", parseCatalogMembership("none"))
+	assert.Equal(t, "", parseCatalogMembership("generated"))
+	assert.Equal(t, "", parseCatalogMembership("interpret"))
+	assert.Equal(t, "catz.example", parseCatalogMembership("catz.example."))
+	assert.Equal(t, "catz.example", parseCatalogMembership("catz.example.#customers"))
+}
+
+// TestCollectZoneStatistics_ZoneFilter tests that zone stats are filtered per zone
+func TestCollectZoneStatistics_ZoneFilter(t *testing.T) {
+	mockCtl := new(MockLibknotCtl)
+	mockCtl.On("SendCommand", "zone-stats").Return(nil)
+
+	for _, zone := range []string{"example.com.", "example.org.", "www.example.com."} {
+		mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+			Zone:    zone,
+			Section: "mod-stats",
+			Item:    "query-type",
+			ID:      "A",
+			Data:    "10",
+		}, nil).Once()
+	}
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeBlock, nil, nil).Once()
+
+	f, err := NewZoneFilter([]string{"suffix:example.com"}, nil)
+	require.NoError(t, err)
+	collector := NewKnotCollector("/test", 1000, false, false, true, false, false, false, WithZoneFilter(f))
+	ch := make(chan prometheus.Metric, 20)
+
+	err = collector.collectZoneStatistics(mockCtl, ch)
+	assert.NoError(t, err)
+	close(ch)
+
+	// Two selected zones
+	assert.Len(t, ch, 2)
+	mockCtl.AssertExpectations(t)
+}
+
+// TestCollectZoneStatusInfo_ZoneFilterPushdown tests that exact zone rules are
+// passed to Knot DNS as command arguments
+func TestCollectZoneStatusInfo_ZoneFilterPushdown(t *testing.T) {
+	mockCtl := new(MockLibknotCtl)
+	mockCtl.On("SendZoneCommand", "zone-status", "", []string{"example.com."}).Return(nil)
+
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+		Zone: "example.com.",
+	}, nil).Once()
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeExtra, &libknot.CtlData{
+		Data: "2023101801",
+	}, nil).Once()
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeBlock, nil, nil).Once()
+
+	f, err := NewZoneFilter([]string{"example.com"}, nil)
+	require.NoError(t, err)
+	collector := NewKnotCollector("/test", 1000, false, false, false, false, true, false, WithZoneFilter(f))
+	ch := make(chan prometheus.Metric, 10)
+
+	err = collector.collectZoneStatusInfo(mockCtl, ch)
+	assert.NoError(t, err)
+	close(ch)
+
+	assert.Len(t, ch, 1)
+	mockCtl.AssertExpectations(t)
+}
+
+// TestCollectZoneStatusInfo_ZoneFilterSkipsZone tests that EXTRA records of an
+// unselected zone are ignored
+func TestCollectZoneStatusInfo_ZoneFilterSkipsZone(t *testing.T) {
+	mockCtl := new(MockLibknotCtl)
+	mockCtl.On("SendCommand", "zone-status").Return(nil)
+
+	for _, zone := range []string{"skip.example.", "keep.example."} {
+		mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+			Zone: zone,
+		}, nil).Once()
+		mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeExtra, &libknot.CtlData{
+			Data: "1",
+		}, nil).Once()
+	}
+	mockCtl.On("ReceiveResponse").Return(libknot.CtlTypeBlock, nil, nil).Once()
+
+	f, err := NewZoneFilter(nil, []string{"skip.example"})
+	require.NoError(t, err)
+	collector := NewKnotCollector("/test", 1000, false, false, false, false, true, false, WithZoneFilter(f))
+	ch := make(chan prometheus.Metric, 10)
+
+	err = collector.collectZoneStatusInfo(mockCtl, ch)
+	assert.NoError(t, err)
+	close(ch)
+
+	// Serial of keep.example only
+	assert.Len(t, ch, 1)
+	mockCtl.AssertExpectations(t)
+}
+
+// TestCollectZoneTimerInfo_CatalogFilter tests catalog membership based filtering
+func TestCollectZoneTimerInfo_CatalogFilter(t *testing.T) {
+	statusCtl := new(MockLibknotCtl)
+	statusCtl.On("SendCommand", "zone-status").Return(nil)
+	statusCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+		Zone: "member.example.", Type: "role", Data: "master",
+	}, nil).Once()
+	statusCtl.On("ReceiveResponse").Return(libknot.CtlTypeExtra, &libknot.CtlData{
+		Type: "catalog", Data: "catz.example.",
+	}, nil).Once()
+	statusCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+		Zone: "other.example.", Type: "role", Data: "master",
+	}, nil).Once()
+	statusCtl.On("ReceiveResponse").Return(libknot.CtlTypeExtra, &libknot.CtlData{
+		Type: "catalog", Data: "-",
+	}, nil).Once()
+	statusCtl.On("ReceiveResponse").Return(libknot.CtlTypeBlock, nil, nil).Once()
+
+	f, err := NewZoneFilter([]string{"catalog:catz.example"}, nil)
+	require.NoError(t, err)
+	collector := NewKnotCollector("/test", 1000, false, false, false, false, false, true, WithZoneFilter(f))
+
+	err = collector.loadZoneList(statusCtl, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"member.example.", "other.example."}, collector.zoneList)
+	assert.Equal(t, map[string]string{"member.example": "catz.example"}, collector.zoneCatalogs)
+
+	timerCtl := new(MockLibknotCtl)
+	timerCtl.On("SendCommandWithType", "zone-read", "SOA").Return(nil)
+	for _, zone := range []string{"member.example.", "other.example."} {
+		timerCtl.On("ReceiveResponse").Return(libknot.CtlTypeData, &libknot.CtlData{
+			Zone: zone,
+			Data: "ns1.example. admin.example. 1 3600 600 86400 300",
+		}, nil).Once()
+	}
+	timerCtl.On("ReceiveResponse").Return(libknot.CtlTypeBlock, nil, nil).Once()
+
+	ch := make(chan prometheus.Metric, 20)
+	err = collector.collectZoneTimerInfo(timerCtl, ch)
+	assert.NoError(t, err)
+	close(ch)
+
+	// Three timers for member.example only
+	assert.Len(t, ch, 3)
+	statusCtl.AssertExpectations(t)
+	timerCtl.AssertExpectations(t)
+}
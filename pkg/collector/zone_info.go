@@ -0,0 +1,213 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot"
+)
+
+// ZoneInfo is the status of a zone as reported by zone-status, with the SOA
+// timers of zone-read
+type ZoneInfo struct {
+	Name        string      `json:"name"`
+	Role        string      `json:"role,omitempty"`
+	Serial      *uint32     `json:"serial,omitempty"`
+	Transaction string      `json:"transaction,omitempty"`
+	Freeze      string      `json:"freeze,omitempty"`
+	Catalog     string      `json:"catalog,omitempty"` // Catalog zone the zone is a member of
+	Events      []ZoneEvent `json:"events"`            // Scheduled events, soonest first
+	SOA         *SOATimers  `json:"soa,omitempty"`
+}
+
+// ZoneEvent is a scheduled zone event such as a refresh or expiration
+type ZoneEvent struct {
+	Name string `json:"name"`
+	// Seconds until the event, 0 for pending or running events
+	InSeconds float64 `json:"in_seconds"`
+	State     string  `json:"state"` // As reported by Knot DNS, e.g. "+1h28m44s" or "pending"
+}
+
+// SOATimers holds the values of a zone's SOA record
+type SOATimers struct {
+	Primary    string `json:"primary"`
+	Admin      string `json:"admin"`
+	Serial     int64  `json:"serial"`
+	Refresh    int64  `json:"refresh"`
+	Retry      int64  `json:"retry"`
+	Expiration int64  `json:"expiration"`
+	Minimum    int64  `json:"minimum"`
+}
+
+// parseSOA parses the data of a SOA record in the form
+// "primary admin serial refresh retry expiration minimum"
+func parseSOA(data string) (*SOATimers, error) {
+	fields := strings.Fields(data)
+	if len(fields) != 7 {
+		return nil, fmt.Errorf("wrong field count (%d)", len(fields))
+	}
+	if !strings.HasSuffix(fields[0], ".") || !strings.HasSuffix(fields[1], ".") {
+		return nil, fmt.Errorf("format validation failed")
+	}
+
+	var values [5]int64
+	for i := range values {
+		val, err := strconv.ParseInt(fields[i+2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("numeric validation failed")
+		}
+		values[i] = val
+	}
+
+	return &SOATimers{
+		Primary:    fields[0],
+		Admin:      fields[1],
+		Serial:     values[0],
+		Refresh:    values[1],
+		Retry:      values[2],
+		Expiration: values[3],
+		Minimum:    values[4],
+	}, nil
+}
+
+// Zones returns the status of the given zones, or of all zones when zones is
+// empty, sorted by name. Unlike metrics, the zone filter doesn't apply. The
+// zones are queried in batches of the zone batch size.
+func (c *KnotCollector) Zones(zones []string) ([]ZoneInfo, error) {
+	if len(zones) == 0 {
+		entries, err := c.ZoneList()
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			zones = append(zones, entry.Name)
+		}
+		if len(zones) == 0 {
+			return []ZoneInfo{}, nil
+		}
+	}
+
+	infos := make(map[string]*ZoneInfo)
+	var order []string
+
+	zoneInfo := func(zone string) *ZoneInfo {
+		name := normalizeZoneName(zone)
+		if info, exists := infos[name]; exists {
+			return info
+		}
+		info := &ZoneInfo{Name: name + ".", Events: []ZoneEvent{}}
+		infos[name] = info
+		order = append(order, name)
+		return info
+	}
+
+	batches := [][]string{zones}
+	if c.zoneBatchSize > 0 {
+		batches = c.zoneBatches(zones)
+	}
+	for _, batch := range batches {
+		err := c.queryZones("zone-status", "", batch, func(zone string, data *libknot.CtlData) {
+			c.parseZoneStatus(zoneInfo(zone), data)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("zone-status: %v", err)
+		}
+
+		err = c.queryZones("zone-read", "SOA", batch, func(zone string, data *libknot.CtlData) {
+			soa, err := parseSOA(data.Data)
+			if err != nil {
+				c.warnParse(c.logger.With("command", "zone-read"), "zone-read/soa", "Failed to parse SOA record", "zone", zone, "err", err)
+				return
+			}
+			// Only zones reported by zone-status are listed
+			if info, exists := infos[normalizeZoneName(zone)]; exists {
+				info.SOA = soa
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("zone-read: %v", err)
+		}
+	}
+
+	sort.Strings(order)
+	result := make([]ZoneInfo, 0, len(order))
+	for _, name := range order {
+		info := infos[name]
+		sort.SliceStable(info.Events, func(i, j int) bool { return info.Events[i].InSeconds < info.Events[j].InSeconds })
+		result = append(result, *info)
+	}
+	return result, nil
+}
+
+// queryZones sends a per-zone command for the given zones on a connection of
+// its own and calls fn for every record, with the zone the record belongs to
+func (c *KnotCollector) queryZones(cmd, rtype string, zones []string, fn func(zone string, data *libknot.CtlData)) error {
+	ctl, err := c.connect()
+	if err != nil {
+		return err
+	}
+	defer ctl.Close()
+
+	if err := c.sendZoneCommand(ctl, cmd, rtype, zones); err != nil {
+		return err
+	}
+
+	currentZone := ""
+	for {
+		dataType, data, err := ctl.ReceiveResponse()
+		if err != nil {
+			return err
+		}
+
+		// Break on BLOCK (end of response) or END (end of connection)
+		if dataType == libknot.CtlTypeBlock || dataType == libknot.CtlTypeEnd {
+			return nil
+		}
+
+		// Records of a zone start with a DATA record naming it, EXTRA
+		// records continue the current zone
+		if dataType == libknot.CtlTypeData && data.Zone != "" {
+			currentZone = data.Zone
+		}
+		if currentZone != "" && data.Type != "" {
+			fn(currentZone, data)
+		}
+	}
+}
+
+// parseZoneStatus adds a zone-status record to the zone's info
+func (c *KnotCollector) parseZoneStatus(info *ZoneInfo, data *libknot.CtlData) {
+	value := data.Data
+	if value == "-" {
+		value = ""
+	}
+
+	switch data.Type {
+	case "role":
+		info.Role = value
+	case "serial":
+		if serial, err := strconv.ParseUint(value, 10, 32); err == nil {
+			s := uint32(serial)
+			info.Serial = &s
+		}
+	case "transaction":
+		info.Transaction = value
+	case "freeze":
+		info.Freeze = value
+	case "catalog":
+		if catalog := parseCatalogMembership(data.Data); catalog != "" {
+			info.Catalog = catalog + "."
+		}
+	default:
+		if value == "" {
+			return
+		}
+		seconds := c.convertStateTime(value)
+		if seconds == nil {
+			return
+		}
+		info.Events = append(info.Events, ZoneEvent{Name: data.Type, InSeconds: *seconds, State: value})
+	}
+}
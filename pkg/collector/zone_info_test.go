@@ -0,0 +1,132 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot/scripted"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// zoneInfoServer reports two zones, one of them a catalog member
+func zoneInfoServer() *scripted.Server {
+	return &scripted.Server{Responses: map[string][]scripted.Record{
+		"zone-status": {
+			{Zone: "example.com.", Type: "role", Data: "master"},
+			{Unit: "extra", Type: "serial", Data: "2024010101"},
+			{Unit: "extra", Type: "transaction", Data: "-"},
+			{Unit: "extra", Type: "freeze", Data: "-"},
+			{Unit: "extra", Type: "catalog", Data: "-"},
+			{Unit: "extra", Type: "refresh", Data: "-"},
+			{Unit: "extra", Type: "journal-flush", Data: "pending"},
+			{Unit: "extra", Type: "DNSSEC re-sign", Data: "+1h"},
+			{Zone: "Member.example.", Type: "role", Data: "slave"},
+			{Unit: "extra", Type: "serial", Data: "7"},
+			{Unit: "extra", Type: "catalog", Data: "catz.example.#group"},
+			{Unit: "extra", Type: "refresh", Data: "+30m"},
+			{Unit: "extra", Type: "expiration", Data: "+2D"},
+			{Unit: "extra", Type: "notify", Data: "not scheduled"},
+		},
+		"zone-read": {
+			{Zone: "example.com.", Type: "SOA", Data: "ns1.example.com. admin.example.com. 2024010101 3600 900 604800 300"},
+			{Zone: "member.example.", Type: "SOA", Data: "broken"},
+			{Zone: "unknown.example.", Type: "SOA", Data: "ns. admin. 1 2 3 4 5"},
+		},
+	}}
+}
+
+// TestZones tests building zone status from zone-status and zone-read
+func TestZones(t *testing.T) {
+	filter, err := NewZoneFilter([]string{"nothing.example"}, nil)
+	require.NoError(t, err)
+	collector := NewKnotCollector("/test", 1000, false, false, false, false, false, false,
+		WithZoneFilter(filter))
+	collector.newCtl = ctlFactory(zoneInfoServer())
+
+	// The zone filter of metrics doesn't apply
+	zones, err := collector.Zones(nil)
+	require.NoError(t, err)
+	require.Len(t, zones, 2)
+
+	example := zones[0]
+	assert.Equal(t, "example.com.", example.Name)
+	assert.Equal(t, "master", example.Role)
+	require.NotNil(t, example.Serial)
+	assert.Equal(t, uint32(2024010101), *example.Serial)
+	assert.Empty(t, example.Catalog)
+	assert.Equal(t, []ZoneEvent{
+		{Name: "journal-flush", InSeconds: 0, State: "pending"},
+		{Name: "DNSSEC re-sign", InSeconds: 3600, State: "+1h"},
+	}, example.Events)
+	assert.Equal(t, &SOATimers{
+		Primary:    "ns1.example.com.",
+		Admin:      "admin.example.com.",
+		Serial:     2024010101,
+		Refresh:    3600,
+		Retry:      900,
+		Expiration: 604800,
+		Minimum:    300,
+	}, example.SOA)
+
+	member := zones[1]
+	assert.Equal(t, "member.example.", member.Name)
+	assert.Equal(t, "slave", member.Role)
+	assert.Equal(t, "catz.example.", member.Catalog)
+	assert.Equal(t, []ZoneEvent{
+		{Name: "refresh", InSeconds: 1800, State: "+30m"},
+		{Name: "expiration", InSeconds: 172800, State: "+2D"},
+	}, member.Events)
+	assert.Nil(t, member.SOA)
+}
+
+// TestZonesBatches tests that zones are queried in batches, and that the zone
+// list is reused by Zones and ZoneList while fresh
+func TestZonesBatches(t *testing.T) {
+	server := zoneInfoServer()
+	collector := NewKnotCollector("/test", 1000, false, false, false, false, false, false,
+		WithZoneBatchSize(1), WithZoneListTTL(time.Hour))
+	collector.newCtl = ctlFactory(server)
+
+	entries, err := collector.ZoneList()
+	require.NoError(t, err)
+	assert.Equal(t, []ZoneEntry{
+		{Name: "example.com.", Role: "master"},
+		{Name: "member.example.", Role: "slave", Catalog: "catz.example."},
+	}, entries)
+
+	zones, err := collector.Zones(nil)
+	require.NoError(t, err)
+	require.Len(t, zones, 2)
+	assert.Equal(t, "example.com.", zones[0].Name)
+	assert.NotNil(t, zones[0].SOA)
+	assert.Equal(t, "member.example.", zones[1].Name)
+
+	assert.Equal(t, []scripted.Command{
+		{Cmd: "zone-status"},
+		{Cmd: "zone-status", Zones: []string{"example.com."}},
+		{Cmd: "zone-read", Type: "SOA", Zones: []string{"example.com."}},
+		{Cmd: "zone-status", Zones: []string{"member.example."}},
+		{Cmd: "zone-read", Type: "SOA", Zones: []string{"member.example."}},
+	}, server.Sent(""))
+	assert.Equal(t, 0, server.Active(), "Every connection should be closed")
+}
+
+// TestZonesConnectionError tests that connection failures are reported
+func TestZonesConnectionError(t *testing.T) {
+	collector := NewKnotCollector("/nonexistent", 1000, false, false, false, false, false, false)
+	_, err := collector.Zones(nil)
+	assert.Error(t, err)
+}
+
+// TestParseSOA tests parsing of SOA record data
+func TestParseSOA(t *testing.T) {
+	soa, err := parseSOA("ns. admin. 1 2 3 4 5")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), soa.Expiration)
+
+	for _, data := range []string{"", "ns. admin. 1 2 3 4", "ns admin. 1 2 3 4 5", "ns. admin. 1 2 x 4 5"} {
+		_, err := parseSOA(data)
+		assert.Error(t, err, data)
+	}
+}
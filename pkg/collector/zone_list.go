@@ -0,0 +1,206 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ZoneEntry is a zone of the zone list with the properties zones are
+// selected by
+type ZoneEntry struct {
+	Name    string // Zone name with the trailing dot
+	Role    string
+	Catalog string // Catalog zone the zone is a member of, if any
+}
+
+// loadZoneList enumerates the zones known to Knot DNS together with their
+// role and catalog membership from zone-status
+func (c *KnotCollector) loadZoneList(ctl KnotCtlInterface, ch chan<- prometheus.Metric) error {
+	logger := c.logger.With("command", "zone-status")
+	logger.Debug("Loading zone list")
+	if err := ctl.SendCommand("zone-status"); err != nil {
+		return err
+	}
+
+	var zones []string
+	catalogs := make(map[string]string)
+	roles := make(map[string]string)
+	currentZone := ""
+
+	for {
+		dataType, data, err := ctl.ReceiveResponse()
+		if err != nil {
+			return err
+		}
+
+		// Break on BLOCK (end of response) or END (end of connection)
+		if dataType == libknot.CtlTypeBlock || dataType == libknot.CtlTypeEnd {
+			break
+		}
+
+		if data.Zone != "" && normalizeZoneName(data.Zone) != currentZone {
+			currentZone = normalizeZoneName(data.Zone)
+			zones = append(zones, data.Zone)
+		}
+		if currentZone == "" {
+			continue
+		}
+		switch data.Type {
+		case "catalog":
+			if catalog := parseCatalogMembership(data.Data); catalog != "" {
+				catalogs[currentZone] = catalog
+			}
+		case "role":
+			if data.Data != "-" {
+				roles[currentZone] = data.Data
+			}
+		}
+	}
+
+	logger.Debug("Loaded zone list", "zones", len(zones), "catalog_members", len(catalogs))
+	c.zoneListMu.Lock()
+	defer c.zoneListMu.Unlock()
+	c.zoneList = zones
+	c.zoneCatalogs = catalogs
+	c.zoneRoles = roles
+	c.zoneListFetched = time.Now()
+	return nil
+}
+
+// minZoneListAPITTL is the least time ZoneList reuses the zone list for, so
+// that paging through the zones API doesn't enumerate all zones per request
+// when the zone list TTL is zero
+const minZoneListAPITTL = 30 * time.Second
+
+// zoneListFresh reports whether the cached zone list is younger than ttl and
+// can be used without enumerating the zones again
+func (c *KnotCollector) zoneListFresh(ttl time.Duration) bool {
+	c.zoneListMu.Lock()
+	defer c.zoneListMu.Unlock()
+	if ttl > 0 && !c.zoneListFetched.IsZero() && time.Since(c.zoneListFetched) < ttl {
+		c.logger.Debug("Using cached zone list", "fetched", c.zoneListFetched)
+		return true
+	}
+	return false
+}
+
+// cachedZones returns the zones of the cached zone list
+func (c *KnotCollector) cachedZones() []string {
+	c.zoneListMu.Lock()
+	defer c.zoneListMu.Unlock()
+	return c.zoneList
+}
+
+// zoneCatalog returns the catalog zone the zone is a member of, as of the
+// cached zone list
+func (c *KnotCollector) zoneCatalog(zone string) string {
+	c.zoneListMu.Lock()
+	defer c.zoneListMu.Unlock()
+	return c.zoneCatalogs[normalizeZoneName(zone)]
+}
+
+// refreshZoneList reloads the zone list unless the cached one is still
+// fresh. It reports whether a fresh zone list is available.
+func (c *KnotCollector) refreshZoneList(ch chan<- prometheus.Metric) bool {
+	if c.zoneListFresh(c.zoneListTTL) {
+		return true
+	}
+	loaded := false
+	c.runTasks([]collectTask{{name: "zone list", run: func(ctl KnotCtlInterface, ch chan<- prometheus.Metric) error {
+		err := c.loadZoneList(ctl, ch)
+		loaded = err == nil
+		return err
+	}}}, ch)
+	return loaded
+}
+
+// ZoneList returns the zones known to Knot DNS sorted by name, from the
+// cached zone list while it's fresh, for at least minZoneListAPITTL. Unlike
+// metrics, the zone filter doesn't apply.
+func (c *KnotCollector) ZoneList() ([]ZoneEntry, error) {
+	if !c.zoneListFresh(max(c.zoneListTTL, minZoneListAPITTL)) {
+		ctl, err := c.connect()
+		if err != nil {
+			return nil, err
+		}
+		defer ctl.Close()
+		if err := c.loadZoneList(ctl, nil); err != nil {
+			return nil, fmt.Errorf("zone-status: %v", err)
+		}
+	}
+
+	c.zoneListMu.Lock()
+	defer c.zoneListMu.Unlock()
+	entries := make([]ZoneEntry, 0, len(c.zoneList))
+	for _, zone := range c.zoneList {
+		name := normalizeZoneName(zone)
+		entry := ZoneEntry{Name: name + ".", Role: c.zoneRoles[name]}
+		if catalog := c.zoneCatalogs[name]; catalog != "" {
+			entry.Catalog = catalog + "."
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// selectedZones returns the zones per-zone metrics are collected for. Zones
+// named exactly by the filter are used as they are, otherwise the zones known
+// to Knot DNS are enumerated and filtered. It reports false when the zones
+// couldn't be enumerated.
+func (c *KnotCollector) selectedZones(ch chan<- prometheus.Metric) ([]string, bool) {
+	candidates, ok := c.zoneFilter.ExactZones()
+	if !ok || c.zoneFilter.NeedsCatalogs() {
+		if !c.refreshZoneList(ch) {
+			return nil, false
+		}
+		candidates = c.cachedZones()
+	}
+
+	var zones []string
+	for _, zone := range candidates {
+		if c.zoneSelected(zone) {
+			zones = append(zones, zone)
+		}
+	}
+	return zones, true
+}
+
+// zoneBatches splits zones into batches of at most zoneBatchSize zones
+func (c *KnotCollector) zoneBatches(zones []string) [][]string {
+	var batches [][]string
+	for len(zones) > 0 {
+		n := min(c.zoneBatchSize, len(zones))
+		batches = append(batches, zones[:n])
+		zones = zones[n:]
+	}
+	return batches
+}
+
+// zoneTasks creates the collection tasks of a per-zone collector. Unless
+// batched, a single task covers all zones.
+func (c *KnotCollector) zoneTasks(name string, batched bool, batches [][]string,
+	run func(ctl KnotCtlInterface, ch chan<- prometheus.Metric, zones []string) error) []collectTask {
+
+	if !batched {
+		return []collectTask{{name: name, run: func(ctl KnotCtlInterface, ch chan<- prometheus.Metric) error {
+			return run(ctl, ch, nil)
+		}}}
+	}
+
+	tasks := make([]collectTask, 0, len(batches))
+	for i, batch := range batches {
+		tasks = append(tasks, collectTask{
+			name:  fmt.Sprintf("%s (batch %d/%d)", name, i+1, len(batches)),
+			group: name,
+			run: func(ctl KnotCtlInterface, ch chan<- prometheus.Metric) error {
+				return run(ctl, ch, batch)
+			},
+		})
+	}
+	return tasks
+}
@@ -0,0 +1,167 @@
+package collector
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot/scripted"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newZoneServer creates a scripted server with the given zones, each having a
+// zone-status record and one zone statistic
+func newZoneServer(zones ...string) *scripted.Server {
+	server := &scripted.Server{Responses: make(map[string][]scripted.Record)}
+	for _, zone := range zones {
+		server.Responses["zone-status"] = append(server.Responses["zone-status"],
+			scripted.Record{Zone: zone, Type: "role", Data: "master"})
+		server.Responses["zone-stats"] = append(server.Responses["zone-stats"],
+			scripted.Record{Zone: zone, Section: "mod-stats", Item: "query-type", ID: "A", Data: "1"})
+	}
+	return server
+}
+
+// collectMetrics runs a collection and returns the number of emitted metrics
+func collectMetrics(collector *KnotCollector) int {
+	ch := make(chan prometheus.Metric, 1000)
+	collector.Collect(ch)
+	close(ch)
+	return len(ch)
+}
+
+// TestZoneBatches tests splitting zones into batches
+func TestZoneBatches(t *testing.T) {
+	collector := NewKnotCollector("/test", 1000, false, false, true, false, false, false, WithZoneBatchSize(2))
+
+	assert.Nil(t, collector.zoneBatches(nil))
+	assert.Equal(t, [][]string{{"a."}}, collector.zoneBatches([]string{"a."}))
+	assert.Equal(t, [][]string{{"a.", "b."}, {"c."}}, collector.zoneBatches([]string{"a.", "b.", "c."}))
+}
+
+// TestCollectZoneBatches tests that zones are enumerated once and then queried in batches
+func TestCollectZoneBatches(t *testing.T) {
+	server := newZoneServer("a.example.", "b.example.", "c.example.", "d.example.", "e.example.")
+	collector := NewKnotCollector("/test", 1000, false, false, true, false, false, false,
+		WithZoneBatchSize(2), WithMaxConcurrency(3))
+	collector.newCtl = ctlFactory(server)
+
+	// Build info, deduplication counter, knot_up and 5 zones
+	assert.Equal(t, 8, collectMetrics(collector))
+
+	enumerations := server.Sent("zone-status")
+	require.Len(t, enumerations, 1)
+	assert.Empty(t, enumerations[0].Zones)
+
+	var batched []string
+	for _, sent := range server.Sent("zone-stats") {
+		assert.LessOrEqual(t, len(sent.Zones), 2)
+		batched = append(batched, sent.Zones...)
+	}
+	assert.Len(t, server.Sent("zone-stats"), 3)
+	assert.ElementsMatch(t, []string{"a.example.", "b.example.", "c.example.", "d.example.", "e.example."}, batched)
+}
+
+// TestCollectZoneBatchesConfiguredSubset tests that exact zone rules are used
+// as the zone list without enumerating zones
+func TestCollectZoneBatchesConfiguredSubset(t *testing.T) {
+	server := newZoneServer("a.example.", "b.example.", "c.example.")
+	f, err := NewZoneFilter([]string{"a.example", "c.example"}, nil)
+	require.NoError(t, err)
+	collector := NewKnotCollector("/test", 1000, false, false, true, false, false, false,
+		WithZoneBatchSize(1), WithZoneFilter(f))
+	collector.newCtl = ctlFactory(server)
+
+	assert.Equal(t, 5, collectMetrics(collector))
+	assert.Empty(t, server.Sent("zone-status"))
+
+	sent := server.Sent("zone-stats")
+	require.Len(t, sent, 2)
+	assert.Equal(t, []string{"a.example."}, sent[0].Zones)
+	assert.Equal(t, []string{"c.example."}, sent[1].Zones)
+}
+
+// TestCollectZoneBatchesFiltered tests that the enumerated zone list is filtered
+func TestCollectZoneBatchesFiltered(t *testing.T) {
+	server := newZoneServer("a.example.", "b.test.", "c.example.")
+	f, err := NewZoneFilter([]string{"suffix:example"}, nil)
+	require.NoError(t, err)
+	collector := NewKnotCollector("/test", 1000, false, false, true, false, false, false,
+		WithZoneBatchSize(10), WithZoneFilter(f))
+	collector.newCtl = ctlFactory(server)
+
+	assert.Equal(t, 5, collectMetrics(collector))
+
+	sent := server.Sent("zone-stats")
+	require.Len(t, sent, 1)
+	assert.Equal(t, []string{"a.example.", "c.example."}, sent[0].Zones)
+}
+
+// TestCollectZoneListTTL tests that the zone list is reused while fresh
+func TestCollectZoneListTTL(t *testing.T) {
+	server := newZoneServer("a.example.")
+	collector := NewKnotCollector("/test", 1000, false, false, true, false, false, false,
+		WithZoneBatchSize(10), WithZoneListTTL(time.Hour))
+	collector.newCtl = ctlFactory(server)
+
+	collectMetrics(collector)
+	collectMetrics(collector)
+	assert.Len(t, server.Sent("zone-status"), 1)
+	assert.Len(t, server.Sent("zone-stats"), 2)
+
+	// An expired list is loaded again
+	collector.zoneListFetched = time.Now().Add(-2 * time.Hour)
+	collectMetrics(collector)
+	assert.Len(t, server.Sent("zone-status"), 2)
+}
+
+// TestCollectZoneListWithoutTTL tests that zones are enumerated on every collection by default
+func TestCollectZoneListWithoutTTL(t *testing.T) {
+	server := newZoneServer("a.example.")
+	collector := NewKnotCollector("/test", 1000, false, false, true, false, false, false,
+		WithZoneBatchSize(10))
+	collector.newCtl = ctlFactory(server)
+
+	collectMetrics(collector)
+	collectMetrics(collector)
+	assert.Len(t, server.Sent("zone-status"), 2)
+}
+
+// TestZoneListAPITTL tests that the zones API reuses the zone list even
+// without a TTL, while collections still enumerate the zones
+func TestZoneListAPITTL(t *testing.T) {
+	server := newZoneServer("a.example.")
+	collector := NewKnotCollector("/test", 1000, false, false, true, false, false, false,
+		WithZoneBatchSize(10))
+	collector.newCtl = ctlFactory(server)
+
+	for i := 0; i < 3; i++ {
+		entries, err := collector.ZoneList()
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	}
+	assert.Len(t, server.Sent("zone-status"), 1)
+
+	collectMetrics(collector)
+	assert.Len(t, server.Sent("zone-status"), 2)
+}
+
+// TestCollectZoneBatchesEnumerationFailure tests that per-zone metrics are
+// collected without batches when the zones can't be enumerated
+func TestCollectZoneBatchesEnumerationFailure(t *testing.T) {
+	server := newZoneServer("a.example.", "b.example.")
+	server.Errors = map[string]error{"zone-status": errors.New("timeout")}
+	collector := NewKnotCollector("/test", 1000, false, false, true, false, false, false,
+		WithZoneBatchSize(1))
+	collector.newCtl = ctlFactory(server)
+
+	// Build info, deduplication counter, knot_up and 2 zones
+	assert.Equal(t, 5, collectMetrics(collector))
+	assert.ErrorContains(t, collector.LastError(), "zone list: timeout")
+
+	sent := server.Sent("zone-stats")
+	require.Len(t, sent, 1)
+	assert.Empty(t, sent[0].Zones)
+}
@@ -0,0 +1,424 @@
+// Package dashboard generates Grafana dashboards from the metric catalog of
+// the collector
+package dashboard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/collector"
+)
+
+// Options configures a generated dashboard
+type Options struct {
+	Title string
+	UID   string
+}
+
+// Dashboard is a Grafana dashboard in the JSON model of Grafana
+type Dashboard struct {
+	UID           string     `json:"uid"`
+	Title         string     `json:"title"`
+	Tags          []string   `json:"tags"`
+	Timezone      string     `json:"timezone"`
+	Editable      bool       `json:"editable"`
+	SchemaVersion int        `json:"schemaVersion"`
+	Version       int        `json:"version"`
+	Refresh       string     `json:"refresh"`
+	Time          TimeRange  `json:"time"`
+	Templating    Templating `json:"templating"`
+	Panels        []Panel    `json:"panels"`
+}
+
+// TimeRange is the default time range of a dashboard
+type TimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Templating holds the template variables of a dashboard
+type Templating struct {
+	List []Variable `json:"list"`
+}
+
+// Variable is a template variable
+type Variable struct {
+	Name       string         `json:"name"`
+	Label      string         `json:"label"`
+	Type       string         `json:"type"`
+	Query      string         `json:"query"`
+	Definition string         `json:"definition,omitempty"`
+	Datasource *DatasourceRef `json:"datasource,omitempty"`
+	Refresh    int            `json:"refresh,omitempty"` // 2 refreshes on time range change
+	Multi      bool           `json:"multi"`
+	IncludeAll bool           `json:"includeAll"`
+	AllValue   string         `json:"allValue,omitempty"`
+	Sort       int            `json:"sort,omitempty"`
+}
+
+// DatasourceRef references a data source
+type DatasourceRef struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+// Panel is a dashboard panel or a row
+type Panel struct {
+	ID              int              `json:"id"`
+	Type            string           `json:"type"`
+	Title           string           `json:"title"`
+	Description     string           `json:"description,omitempty"`
+	GridPos         GridPos          `json:"gridPos"`
+	Datasource      *DatasourceRef   `json:"datasource,omitempty"`
+	Targets         []Target         `json:"targets,omitempty"`
+	FieldConfig     *FieldConfig     `json:"fieldConfig,omitempty"`
+	Options         map[string]any   `json:"options,omitempty"`
+	Transformations []Transformation `json:"transformations,omitempty"`
+	Collapsed       bool             `json:"collapsed,omitempty"`
+}
+
+// GridPos is the position of a panel on the 24 columns wide grid
+type GridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Target is a query of a panel
+type Target struct {
+	RefID        string         `json:"refId"`
+	Datasource   *DatasourceRef `json:"datasource"`
+	Expr         string         `json:"expr"`
+	LegendFormat string         `json:"legendFormat,omitempty"`
+	Format       string         `json:"format,omitempty"`
+	Instant      bool           `json:"instant,omitempty"`
+	Range        bool           `json:"range,omitempty"`
+}
+
+// FieldConfig holds the display settings of the values of a panel
+type FieldConfig struct {
+	Defaults  FieldDefaults `json:"defaults"`
+	Overrides []any         `json:"overrides"`
+}
+
+// FieldDefaults are the display settings of all values of a panel
+type FieldDefaults struct {
+	Unit     string `json:"unit,omitempty"`
+	Mappings []any  `json:"mappings,omitempty"`
+}
+
+// Transformation transforms the query results of a panel
+type Transformation struct {
+	ID      string         `json:"id"`
+	Options map[string]any `json:"options"`
+}
+
+// datasource is the data source of all queries, chosen by a variable
+var datasource = &DatasourceRef{Type: "prometheus", UID: "${datasource}"}
+
+// section is a row of panels for the metrics of some collectors
+type section struct {
+	title      string
+	collectors []string
+}
+
+// sections order the metrics by collector, "" is the exporter itself
+var sections = []section{
+	{"Overview", []string{""}},
+	{"Memory", []string{"meminfo"}},
+	{"Global statistics", []string{"global-stats"}},
+	{"Zones", []string{"zone-serial", "zone-status", "zone-timers"}},
+	{"Zone statistics", []string{"zone-stats"}},
+}
+
+// Grid layout
+const (
+	gridWidth   = 24
+	panelWidth  = 12
+	panelHeight = 8
+	statWidth   = 6
+	statHeight  = 4
+)
+
+// Generate returns a dashboard with panels for the metrics of the catalog.
+// Counters are shown as rates, histograms as the 90th percentile, per-zone
+// gauges are also listed in a table.
+func Generate(infos []collector.MetricInfo, opts Options) *Dashboard {
+	d := &Dashboard{
+		UID:           opts.UID,
+		Title:         opts.Title,
+		Tags:          []string{"knot", "dns"},
+		Timezone:      "browser",
+		Editable:      true,
+		SchemaVersion: 39,
+		Version:       1,
+		Refresh:       "1m",
+		Time:          TimeRange{From: "now-6h", To: "now"},
+		Templating:    Templating{List: variables(infos)},
+		Panels:        []Panel{},
+	}
+
+	// Drop the %s_total counters of legacy mode that duplicate a gauge
+	names := make(map[string]bool, len(infos))
+	for _, info := range infos {
+		names[info.Name] = true
+	}
+	byCollector := make(map[string][]collector.MetricInfo)
+	var others []string
+	for _, info := range infos {
+		if gauge, found := strings.CutSuffix(info.Name, "_total"); found && info.Type == "counter" && names[gauge] {
+			continue
+		}
+		if _, exists := byCollector[info.Collector]; !exists && !knownCollector(info.Collector) {
+			others = append(others, info.Collector)
+		}
+		byCollector[info.Collector] = append(byCollector[info.Collector], info)
+	}
+	all := append([]section{}, sections...)
+	for _, name := range others {
+		all = append(all, section{name, []string{name}})
+	}
+
+	l := &layout{dashboard: d}
+	for _, s := range all {
+		var metrics []collector.MetricInfo
+		for _, name := range s.collectors {
+			metrics = append(metrics, byCollector[name]...)
+		}
+		if len(metrics) == 0 {
+			continue
+		}
+		sort.SliceStable(metrics, func(i, j int) bool {
+			return metrics[i].Name == collector.UpMetric && metrics[j].Name != collector.UpMetric
+		})
+		l.row(s.title)
+		if table, ok := zoneTable(metrics); ok {
+			l.add(table, gridWidth, panelHeight)
+		}
+		for _, info := range metrics {
+			switch {
+			case info.Name == collector.UpMetric:
+				l.add(upPanel(info), statWidth, statHeight)
+			case strings.HasSuffix(info.Name, "_info"):
+				l.add(infoPanel(info), gridWidth, statHeight+1)
+			default:
+				l.add(metricPanel(info), panelWidth, panelHeight)
+			}
+		}
+	}
+	return d
+}
+
+// knownCollector reports whether a collector has a section
+func knownCollector(name string) bool {
+	for _, s := range sections {
+		for _, c := range s.collectors {
+			if c == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// variables returns the data source, instance and zone template variables.
+// Zones are listed from the first per-zone metric of the catalog.
+func variables(infos []collector.MetricInfo) []Variable {
+	instances := "label_values(" + collector.UpMetric + ", instance)"
+	vars := []Variable{
+		{Name: "datasource", Label: "Data source", Type: "datasource", Query: "prometheus"},
+		{
+			Name: "instance", Label: "Instance", Type: "query", Query: instances, Definition: instances,
+			Datasource: datasource, Refresh: 2, Multi: true, IncludeAll: true, AllValue: ".*", Sort: 1,
+		},
+	}
+	for _, info := range infos {
+		if hasLabel(info, "zone") {
+			zones := fmt.Sprintf(`label_values(%s{instance=~"$instance"}, zone)`, seriesName(info))
+			vars = append(vars, Variable{
+				Name: "zone", Label: "Zone", Type: "query", Query: zones, Definition: zones,
+				Datasource: datasource, Refresh: 2, Multi: true, IncludeAll: true, AllValue: ".*", Sort: 1,
+			})
+			break
+		}
+	}
+	return vars
+}
+
+// layout places panels on the grid, left to right and top to bottom
+type layout struct {
+	dashboard *Dashboard
+	x, y      int
+	rowHeight int
+}
+
+// row starts a new row of panels
+func (l *layout) row(title string) {
+	l.newLine()
+	l.place(Panel{Type: "row", Title: title}, gridWidth, 1)
+	l.newLine()
+}
+
+// add places a panel of the given size
+func (l *layout) add(p Panel, w, h int) {
+	if l.x+w > gridWidth {
+		l.newLine()
+	}
+	l.place(p, w, h)
+}
+
+func (l *layout) place(p Panel, w, h int) {
+	p.ID = len(l.dashboard.Panels) + 1
+	p.GridPos = GridPos{H: h, W: w, X: l.x, Y: l.y}
+	l.dashboard.Panels = append(l.dashboard.Panels, p)
+	l.x += w
+	l.rowHeight = max(l.rowHeight, h)
+}
+
+func (l *layout) newLine() {
+	if l.x > 0 {
+		l.y += l.rowHeight
+	}
+	l.x, l.rowHeight = 0, 0
+}
+
+// metricPanel shows a metric as a time series
+func metricPanel(info collector.MetricInfo) Panel {
+	expr := seriesName(info) + selector(info)
+	legend := legendFormat(info.Labels)
+	title := info.Name
+	switch info.Type {
+	case "counter":
+		expr = fmt.Sprintf("rate(%s[$__rate_interval])", expr)
+		title += " (rate)"
+	case "histogram":
+		by := append([]string{"instance", "le"}, info.Labels...)
+		expr = fmt.Sprintf("histogram_quantile(0.9, sum by (%s) (rate(%s_bucket%s[$__rate_interval])))",
+			strings.Join(by, ", "), info.Name, selector(info))
+		title += " (p90)"
+	}
+	return Panel{
+		Type:        "timeseries",
+		Title:       title,
+		Description: info.Help,
+		Datasource:  datasource,
+		Targets:     []Target{{RefID: "A", Datasource: datasource, Expr: expr, LegendFormat: legend, Range: true}},
+		FieldConfig: &FieldConfig{Defaults: FieldDefaults{Unit: unit(info)}, Overrides: []any{}},
+	}
+}
+
+// upPanel shows whether Knot DNS is reachable
+func upPanel(info collector.MetricInfo) Panel {
+	return Panel{
+		Type:        "stat",
+		Title:       "Knot DNS",
+		Description: info.Help,
+		Datasource:  datasource,
+		Targets: []Target{{RefID: "A", Datasource: datasource, Expr: "min(" + info.Name + selector(info) + ")",
+			Instant: true}},
+		FieldConfig: &FieldConfig{Defaults: FieldDefaults{Mappings: []any{
+			map[string]any{"type": "value", "options": map[string]any{
+				"0": map[string]any{"text": "Down", "color": "red", "index": 0},
+				"1": map[string]any{"text": "Up", "color": "green", "index": 1},
+			}},
+		}}, Overrides: []any{}},
+		Options: map[string]any{"colorMode": "background", "graphMode": "none"},
+	}
+}
+
+// infoPanel lists the labels of an info metric
+func infoPanel(info collector.MetricInfo) Panel {
+	return Panel{
+		Type:        "table",
+		Title:       info.Name,
+		Description: info.Help,
+		Datasource:  datasource,
+		Targets: []Target{{RefID: "A", Datasource: datasource, Expr: info.Name + selector(info),
+			Format: "table", Instant: true}},
+		Transformations: []Transformation{{ID: "organize", Options: map[string]any{
+			"excludeByName": map[string]any{"Time": true, "Value": true, "__name__": true},
+		}}},
+	}
+}
+
+// zoneTable lists the gauges that have only a zone label in a table with a
+// row per zone
+func zoneTable(infos []collector.MetricInfo) (Panel, bool) {
+	p := Panel{
+		Type:       "table",
+		Title:      "Zones",
+		Datasource: datasource,
+	}
+	rename := make(map[string]any)
+	exclude := map[string]any{"Time": true}
+	for _, info := range infos {
+		if info.Type != "gauge" || len(info.Labels) != 1 || info.Labels[0] != "zone" {
+			continue
+		}
+		refID := string(rune('A' + len(p.Targets)))
+		p.Targets = append(p.Targets, Target{RefID: refID, Datasource: datasource,
+			Expr: fmt.Sprintf("max by (zone) (%s%s)", info.Name, selector(info)), Format: "table", Instant: true})
+		rename["Value #"+refID] = info.Name
+	}
+	if len(p.Targets) == 0 {
+		return Panel{}, false
+	}
+	p.Transformations = []Transformation{
+		{ID: "merge", Options: map[string]any{}},
+		{ID: "organize", Options: map[string]any{"excludeByName": exclude, "renameByName": rename}},
+	}
+	return p, true
+}
+
+// seriesName returns the name of the series of a metric to query
+func seriesName(info collector.MetricInfo) string {
+	if info.Type == "histogram" {
+		return info.Name + "_count"
+	}
+	return info.Name
+}
+
+// selector returns the label matchers of the template variables
+func selector(info collector.MetricInfo) string {
+	matchers := []string{`instance=~"$instance"`}
+	if hasLabel(info, "zone") {
+		matchers = append(matchers, `zone=~"$zone"`)
+	}
+	return "{" + strings.Join(matchers, ", ") + "}"
+}
+
+// legendFormat names series by instance and the labels of the metric
+func legendFormat(labels []string) string {
+	parts := []string{"{{instance}}"}
+	for _, label := range labels {
+		parts = append(parts, "{{"+label+"}}")
+	}
+	return strings.Join(parts, " ")
+}
+
+// unit returns the Grafana unit of a metric, from its name suffix
+func unit(info collector.MetricInfo) string {
+	name := strings.TrimSuffix(info.Name, "_total")
+	switch {
+	case strings.HasSuffix(name, "_timestamp_seconds"):
+		return "dateTimeFromNow"
+	case strings.HasSuffix(name, "_seconds"):
+		return "s"
+	case strings.HasSuffix(name, "_bytes"):
+		return "bytes"
+	case info.Type == "counter":
+		return "ops"
+	}
+	return ""
+}
+
+func hasLabel(info collector.MetricInfo, label string) bool {
+	for _, l := range info.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
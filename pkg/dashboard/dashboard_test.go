@@ -0,0 +1,139 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/collector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCatalog returns the static catalog of a collector with every collector
+// enabled and some discovered metrics
+func testCatalog(t *testing.T, legacy bool) []collector.MetricInfo {
+	var opts []collector.Option
+	if legacy {
+		opts = append(opts, collector.WithLegacyMetricTypes(true))
+	}
+	c := collector.NewKnotCollector("/test", 1000, true, true, true, true, true, true, opts...)
+	static, err := c.StaticCatalog()
+	require.NoError(t, err)
+
+	discovered := []collector.MetricInfo{
+		{Name: "knot_stats_query_size", Type: "histogram", Help: "Global statistic: query-size", Labels: []string{"module", "protocol"}, Collector: "global-stats", Dynamic: true},
+		{Name: "knot_zone_stats_request_protocol_total", Type: "counter", Help: "Zone statistic: request-protocol", Labels: []string{"module", "protocol", "zone"}, Collector: "zone-stats", Dynamic: true},
+		{Name: "knot_custom", Type: "gauge", Help: "Metric of an unknown collector", Labels: []string{}, Collector: "custom", Dynamic: true},
+	}
+	return collector.MergeCatalogs(static, discovered)
+}
+
+// panelsByTitle indexes the panels that aren't rows by title
+func panelsByTitle(d *Dashboard) map[string]Panel {
+	panels := make(map[string]Panel)
+	for _, p := range d.Panels {
+		if p.Type != "row" {
+			panels[p.Title] = p
+		}
+	}
+	return panels
+}
+
+// TestGenerate tests the panels and variables of a generated dashboard
+func TestGenerate(t *testing.T) {
+	infos := testCatalog(t, false)
+	d := Generate(infos, Options{Title: "Knot", UID: "knot"})
+	assert.Equal(t, "Knot", d.Title)
+	assert.Equal(t, "knot", d.UID)
+
+	require.Len(t, d.Templating.List, 3)
+	assert.Equal(t, "label_values(knot_up, instance)", d.Templating.List[1].Query)
+	assert.Equal(t, `label_values(knot_zone_expiration_seconds{instance=~"$instance"}, zone)`, d.Templating.List[2].Query)
+
+	var rows []string
+	for _, p := range d.Panels {
+		if p.Type == "row" {
+			rows = append(rows, p.Title)
+		}
+	}
+	assert.Equal(t, []string{"Overview", "Memory", "Global statistics", "Zones", "Zone statistics", "custom"}, rows)
+
+	panels := panelsByTitle(d)
+	assert.Equal(t, "min(knot_up{instance=~\"$instance\"})", panels["Knot DNS"].Targets[0].Expr)
+	assert.Equal(t, `histogram_quantile(0.9, sum by (instance, le, module, protocol) (rate(knot_stats_query_size_bucket{instance=~"$instance"}[$__rate_interval])))`,
+		panels["knot_stats_query_size (p90)"].Targets[0].Expr)
+	assert.Equal(t, `rate(knot_zone_stats_request_protocol_total{instance=~"$instance", zone=~"$zone"}[$__rate_interval])`,
+		panels["knot_zone_stats_request_protocol_total (rate)"].Targets[0].Expr)
+	assert.Equal(t, "s", panels["knot_zone_status_refresh_seconds"].FieldConfig.Defaults.Unit)
+	assert.Equal(t, "bytes", panels["knot_memory_usage_bytes"].FieldConfig.Defaults.Unit)
+
+	// The zone table lists every per-zone gauge, by collector
+	var tableExprs []string
+	for _, target := range panels["Zones"].Targets {
+		tableExprs = append(tableExprs, target.Expr)
+	}
+	assert.Equal(t, []string{
+		`max by (zone) (knot_zone_serial{instance=~"$instance", zone=~"$zone"})`,
+		`max by (zone) (knot_zone_status_expiration_seconds{instance=~"$instance", zone=~"$zone"})`,
+		`max by (zone) (knot_zone_status_refresh_seconds{instance=~"$instance", zone=~"$zone"})`,
+		`max by (zone) (knot_zone_status_resign_seconds{instance=~"$instance", zone=~"$zone"})`,
+		`max by (zone) (knot_zone_expiration_seconds{instance=~"$instance", zone=~"$zone"})`,
+		`max by (zone) (knot_zone_refresh_seconds{instance=~"$instance", zone=~"$zone"})`,
+		`max by (zone) (knot_zone_retry_seconds{instance=~"$instance", zone=~"$zone"})`,
+	}, tableExprs)
+
+	// Every metric of the catalog is shown
+	names := make(map[string]bool)
+	for _, info := range infos {
+		names[info.Name] = true
+	}
+	for _, p := range d.Panels {
+		for _, target := range p.Targets {
+			for name := range names {
+				if strings.Contains(target.Expr, name) {
+					delete(names, name)
+				}
+			}
+		}
+	}
+	assert.Empty(t, names)
+}
+
+// TestGenerateLegacy tests that the counters of legacy mode duplicating a
+// gauge are left out
+func TestGenerateLegacy(t *testing.T) {
+	d := Generate(testCatalog(t, true), Options{})
+	panels := panelsByTitle(d)
+	assert.Contains(t, panels, "knot_zone_serial")
+	assert.NotContains(t, panels, "knot_zone_serial_total (rate)")
+	assert.Contains(t, panels, "knot_zone_stats_request_protocol_total (rate)")
+}
+
+// TestGenerateLayout tests that panels have unique IDs and don't overlap
+func TestGenerateLayout(t *testing.T) {
+	d := Generate(testCatalog(t, false), Options{})
+	ids := make(map[int]bool)
+	for i, p := range d.Panels {
+		assert.False(t, ids[p.ID], "duplicate panel ID %d", p.ID)
+		ids[p.ID] = true
+		assert.LessOrEqual(t, p.GridPos.X+p.GridPos.W, gridWidth, p.Title)
+		for _, other := range d.Panels[:i] {
+			a, b := p.GridPos, other.GridPos
+			overlap := a.X < b.X+b.W && b.X < a.X+a.W && a.Y < b.Y+b.H && b.Y < a.Y+a.H
+			assert.False(t, overlap, "%s overlaps %s", p.Title, other.Title)
+		}
+	}
+
+	// The dashboard is valid JSON
+	_, err := json.Marshal(d)
+	require.NoError(t, err)
+}
+
+// TestGenerateEmpty tests a dashboard without per-zone metrics
+func TestGenerateEmpty(t *testing.T) {
+	d := Generate([]collector.MetricInfo{{Name: collector.UpMetric, Type: "gauge", Labels: []string{}}}, Options{})
+	assert.Len(t, d.Templating.List, 2)
+	require.Len(t, d.Panels, 2)
+	assert.Equal(t, "stat", d.Panels[1].Type)
+}
@@ -82,10 +82,32 @@ int send_command_with_type(knot_ctl_t *ctl, const char *cmd, const char *rtype)
     return knot_ctl_send(ctl, KNOT_CTL_TYPE_BLOCK, NULL);
 }
 
+// Send a single command data unit targeted at a zone, without closing the block
+int send_zone_data(knot_ctl_t *ctl, const char *cmd, const char *rtype, const char *zone) {
+    knot_ctl_data_t data;
+    memset(data, 0, sizeof(data));
+
+    data[KNOT_CTL_IDX_CMD] = cmd;
+    if (rtype && strlen(rtype) > 0) {
+        data[KNOT_CTL_IDX_TYPE] = rtype;
+    }
+    if (zone && strlen(zone) > 0) {
+        data[KNOT_CTL_IDX_ZONE] = zone;
+    }
+
+    return knot_ctl_send(ctl, KNOT_CTL_TYPE_DATA, &data);
+}
+
+// Close the command block
+int send_block(knot_ctl_t *ctl) {
+    return knot_ctl_send(ctl, KNOT_CTL_TYPE_BLOCK, NULL);
+}
+
 // Receive response and extract key fields
 int receive_simple_response(knot_ctl_t *ctl, knot_ctl_type_t *type,
-                           char *section, char *id, char *item, char *zone, char *data_value,
-                           int section_size, int id_size, int item_size, int zone_size, int data_size) {
+                           char *section, char *id, char *item, char *zone, char *rtype, char *data_value,
+                           int section_size, int id_size, int item_size, int zone_size, int rtype_size,
+                           int data_size) {
     knot_ctl_data_t data;
     memset(data, 0, sizeof(data));
 
@@ -121,6 +143,13 @@ int receive_simple_response(knot_ctl_t *ctl, knot_ctl_type_t *type,
         zone[0] = '\0';
     }
 
+    if (rtype && data[KNOT_CTL_IDX_TYPE]) {
+        strncpy(rtype, data[KNOT_CTL_IDX_TYPE], rtype_size - 1);
+        rtype[rtype_size - 1] = '\0';
+    } else if (rtype) {
+        rtype[0] = '\0';
+    }
+
     if (data_value && data[KNOT_CTL_IDX_DATA]) {
         strncpy(data_value, data[KNOT_CTL_IDX_DATA], data_size - 1);
         data_value[data_size - 1] = '\0';
@@ -153,6 +182,7 @@ type CtlData struct {
 	ID      string
 	Item    string
 	Zone    string
+	Type    string
 	Data    string
 }
 
@@ -269,6 +299,42 @@ func (k *Ctl) SendCommandWithType(cmd string, rtype string) error {
 	return nil
 }
 
+// SendZoneCommand sends a command for each of the given zones in a single
+// block. Knot DNS executes the command once per zone and answers all of them
+// in one response block. With no zones the command targets all zones.
+func (k *Ctl) SendZoneCommand(cmd string, rtype string, zones []string) error {
+	if k.ctl == nil {
+		return &CtlErrorSend{CtlError{message: "control object not initialized"}}
+	}
+
+	cCmd := C.CString(cmd)
+	defer C.free(unsafe.Pointer(cCmd))
+
+	cType := C.CString(rtype)
+	defer C.free(unsafe.Pointer(cType))
+
+	if len(zones) == 0 {
+		zones = []string{""}
+	}
+
+	for _, zone := range zones {
+		cZone := C.CString(zone)
+		ret := C.send_zone_data(k.ctl, cCmd, cType, cZone)
+		C.free(unsafe.Pointer(cZone))
+		if ret != 0 {
+			err := C.GoString(C.knot_strerror(ret))
+			return &CtlErrorSend{CtlError{message: err}}
+		}
+	}
+
+	ret := C.send_block(k.ctl)
+	if ret != 0 {
+		err := C.GoString(C.knot_strerror(ret))
+		return &CtlErrorSend{CtlError{message: err}}
+	}
+	return nil
+}
+
 // ReceiveResponse receives a response from the Knot DNS server
 func (k *Ctl) ReceiveResponse() (CtlType, *CtlData, error) {
 	if k.ctl == nil {
@@ -283,11 +349,13 @@ func (k *Ctl) ReceiveResponse() (CtlType, *CtlData, error) {
 	idBuf := make([]C.char, bufSize)
 	itemBuf := make([]C.char, bufSize)
 	zoneBuf := make([]C.char, bufSize)
+	typeBuf := make([]C.char, bufSize)
 	dataBuf := make([]C.char, bufSize)
 
 	ret := C.receive_simple_response(k.ctl, &dataType,
-		&sectionBuf[0], &idBuf[0], &itemBuf[0], &zoneBuf[0], &dataBuf[0],
-		C.int(bufSize), C.int(bufSize), C.int(bufSize), C.int(bufSize), C.int(bufSize))
+		&sectionBuf[0], &idBuf[0], &itemBuf[0], &zoneBuf[0], &typeBuf[0], &dataBuf[0],
+		C.int(bufSize), C.int(bufSize), C.int(bufSize), C.int(bufSize), C.int(bufSize),
+		C.int(bufSize))
 
 	if ret != 0 {
 		err := C.GoString(C.knot_strerror(ret))
@@ -299,6 +367,7 @@ func (k *Ctl) ReceiveResponse() (CtlType, *CtlData, error) {
 		ID:      C.GoString(&idBuf[0]),
 		Item:    C.GoString(&itemBuf[0]),
 		Zone:    C.GoString(&zoneBuf[0]),
+		Type:    C.GoString(&typeBuf[0]),
 		Data:    C.GoString(&dataBuf[0]),
 	}
 
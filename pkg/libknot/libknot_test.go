@@ -62,6 +62,7 @@ func TestCtlData(t *testing.T) {
 		ID:      "test-id",
 		Item:    "test-item",
 		Zone:    "example.com",
+		Type:    "test-type",
 		Data:    "test-data",
 	}
 
@@ -70,6 +71,7 @@ func TestCtlData(t *testing.T) {
 	assert.Equal(t, "test-id", data.ID)
 	assert.Equal(t, "test-item", data.Item)
 	assert.Equal(t, "example.com", data.Zone)
+	assert.Equal(t, "test-type", data.Type)
 	assert.Equal(t, "test-data", data.Data)
 }
 
@@ -164,6 +166,22 @@ func TestCtlSendCommandWithTypeBeforeConnect(t *testing.T) {
 	assert.IsType(t, &CtlErrorSend{}, err)
 }
 
+// TestCtlSendZoneCommandBeforeConnect tests SendZoneCommand before connecting
+func TestCtlSendZoneCommandBeforeConnect(t *testing.T) {
+	ctl := New()
+	if ctl == nil {
+		t.Skip("libknot not available")
+	}
+	defer ctl.Close()
+
+	// Try to send zone-targeted command without connecting
+	err := ctl.SendZoneCommand("zone-status", "", []string{"example.com."})
+
+	// Should return an error
+	assert.Error(t, err)
+	assert.IsType(t, &CtlErrorSend{}, err)
+}
+
 // TestCtlReceiveResponseBeforeConnect tests ReceiveResponse before connecting
 func TestCtlReceiveResponseBeforeConnect(t *testing.T) {
 	ctl := New()
@@ -0,0 +1,199 @@
+// Package scripted provides control connections that answer commands with
+// scripted records instead of talking to Knot DNS, for tests and for
+// replaying recorded responses
+package scripted
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot"
+)
+
+// Record is a response record. A DATA record with a zone starts the records
+// of that zone, the EXTRA records after it belong to the same zone.
+type Record struct {
+	Unit    string `json:"unit"` // "data" (default) or "extra"
+	Zone    string `json:"zone"`
+	Section string `json:"section"`
+	Item    string `json:"item"`
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Data    string `json:"data"`
+}
+
+// Command is a command received by a Server
+type Command struct {
+	Cmd   string
+	Type  string
+	Zones []string
+}
+
+// Server answers the commands of its connections with the records scripted
+// for the command. Zone commands only get the records of the requested
+// zones. Like knotd, it takes a single command per connection. It's safe for
+// concurrent connections.
+type Server struct {
+	Responses  map[string][]Record // Records per command
+	Errors     map[string]error    // Errors of sending a command, per command
+	ConnectErr error               // Error of every connection attempt, if set
+	Release    chan struct{}       // Blocks commands until closed, if set
+
+	mu     sync.Mutex
+	sent   []Command
+	active int
+	peak   int
+}
+
+// Load returns a server answering with the records of a JSON file holding a
+// map of commands to their records
+func Load(path string) (*Server, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{}
+	if err := json.Unmarshal(content, &s.Responses); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// NewCtl returns a new connection to the server
+func (s *Server) NewCtl() *Ctl {
+	return &Ctl{server: s}
+}
+
+// Sent returns the received commands named cmd, all of them if cmd is empty
+func (s *Server) Sent(cmd string) []Command {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Command
+	for _, sent := range s.sent {
+		if cmd == "" || sent.Cmd == cmd {
+			out = append(out, sent)
+		}
+	}
+	return out
+}
+
+// Active returns the number of open connections
+func (s *Server) Active() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active
+}
+
+// Peak returns the highest number of connections open at once
+func (s *Server) Peak() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.peak
+}
+
+// Ctl is a single connection to a Server
+type Ctl struct {
+	server    *Server
+	connected bool
+	used      bool     // A command was sent
+	pending   []Record // Response to the last command
+}
+
+func (c *Ctl) Connect(path string) error {
+	s := c.server
+	if s.ConnectErr != nil {
+		return s.ConnectErr
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.connected = true
+	s.active++
+	s.peak = max(s.peak, s.active)
+	return nil
+}
+
+func (c *Ctl) Close() {
+	if !c.connected {
+		return
+	}
+	c.connected = false
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	c.server.active--
+}
+
+func (c *Ctl) SetTimeout(timeout int) {}
+
+func (c *Ctl) SendCommand(cmd string) error {
+	return c.SendZoneCommand(cmd, "", nil)
+}
+
+func (c *Ctl) SendCommandWithType(cmd string, rtype string) error {
+	return c.SendZoneCommand(cmd, rtype, nil)
+}
+
+func (c *Ctl) SendZoneCommand(cmd string, rtype string, zones []string) error {
+	if c.used {
+		return fmt.Errorf("%s sent on a used connection", cmd)
+	}
+	c.used = true
+
+	s := c.server
+	s.mu.Lock()
+	s.sent = append(s.sent, Command{Cmd: cmd, Type: rtype, Zones: zones})
+	err := s.Errors[cmd]
+	records := s.Responses[cmd]
+	s.mu.Unlock()
+
+	if s.Release != nil {
+		<-s.Release
+	}
+	if err != nil {
+		return err
+	}
+
+	selected := make(map[string]bool, len(zones))
+	for _, zone := range zones {
+		selected[zoneKey(zone)] = true
+	}
+	c.pending = nil
+	currentZone := ""
+	for _, record := range records {
+		if record.Unit != "extra" && record.Zone != "" {
+			currentZone = zoneKey(record.Zone)
+		}
+		if len(zones) == 0 || selected[currentZone] {
+			c.pending = append(c.pending, record)
+		}
+	}
+	return nil
+}
+
+func (c *Ctl) ReceiveResponse() (libknot.CtlType, *libknot.CtlData, error) {
+	if len(c.pending) == 0 {
+		return libknot.CtlTypeBlock, &libknot.CtlData{}, nil
+	}
+	record := c.pending[0]
+	c.pending = c.pending[1:]
+
+	dataType := libknot.CtlTypeData
+	if record.Unit == "extra" {
+		dataType = libknot.CtlTypeExtra
+	}
+	return dataType, &libknot.CtlData{
+		Zone:    record.Zone,
+		Section: record.Section,
+		Item:    record.Item,
+		ID:      record.ID,
+		Type:    record.Type,
+		Data:    record.Data,
+	}, nil
+}
+
+// zoneKey compares zone names ignoring case and the trailing dot
+func zoneKey(zone string) string {
+	return strings.TrimSuffix(strings.ToLower(zone), ".")
+}
@@ -0,0 +1,99 @@
+package scripted
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiveAll returns the records of a response up to the block
+func receiveAll(t *testing.T, ctl *Ctl) []*libknot.CtlData {
+	var out []*libknot.CtlData
+	for {
+		dataType, data, err := ctl.ReceiveResponse()
+		require.NoError(t, err)
+		require.NotNil(t, data)
+		if dataType == libknot.CtlTypeBlock {
+			return out
+		}
+		out = append(out, data)
+	}
+}
+
+// TestZoneCommand tests restricting a response to the requested zones
+func TestZoneCommand(t *testing.T) {
+	server := &Server{Responses: map[string][]Record{
+		"zone-status": {
+			{Zone: "example.com.", Type: "role", Data: "master"},
+			{Unit: "extra", Type: "serial", Data: "1"},
+			{Zone: "example.org.", Type: "role", Data: "slave"},
+			{Unit: "extra", Type: "serial", Data: "2"},
+		},
+	}}
+	ctl := server.NewCtl()
+	require.NoError(t, ctl.SendZoneCommand("zone-status", "", []string{"Example.ORG"}))
+	dataType, data, err := ctl.ReceiveResponse()
+	require.NoError(t, err)
+	assert.Equal(t, libknot.CtlTypeData, dataType)
+	assert.Equal(t, "example.org.", data.Zone)
+	dataType, data, err = ctl.ReceiveResponse()
+	require.NoError(t, err)
+	assert.Equal(t, libknot.CtlTypeExtra, dataType)
+	assert.Equal(t, &libknot.CtlData{Type: "serial", Data: "2"}, data)
+	assert.Empty(t, receiveAll(t, ctl))
+
+	assert.EqualError(t, ctl.SendCommand("zone-status"), "zone-status sent on a used connection")
+
+	ctl = server.NewCtl()
+	require.NoError(t, ctl.SendCommand("zone-status"))
+	assert.Len(t, receiveAll(t, ctl), 4)
+	ctl = server.NewCtl()
+	require.NoError(t, ctl.SendCommandWithType("zone-read", "SOA"))
+	assert.Empty(t, receiveAll(t, ctl))
+
+	assert.Equal(t, []Command{
+		{Cmd: "zone-status", Zones: []string{"Example.ORG"}},
+		{Cmd: "zone-status"},
+		{Cmd: "zone-read", Type: "SOA"},
+	}, server.Sent(""))
+	assert.Len(t, server.Sent("zone-read"), 1)
+}
+
+// TestConnections tests counting of open connections and scripted errors
+func TestConnections(t *testing.T) {
+	server := &Server{Errors: map[string]error{"stats": errors.New("timeout")}}
+	first, second := server.NewCtl(), server.NewCtl()
+	require.NoError(t, first.Connect("/test"))
+	require.NoError(t, second.Connect("/test"))
+	first.Close()
+	first.Close()
+	assert.Equal(t, 1, server.Active())
+	assert.Equal(t, 2, server.Peak())
+	assert.EqualError(t, second.SendCommand("stats"), "timeout")
+
+	server.ConnectErr = errors.New("refused")
+	assert.EqualError(t, server.NewCtl().Connect("/test"), "refused")
+	assert.Equal(t, 1, server.Active())
+}
+
+// TestLoad tests loading scripted responses from a file
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "responses.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"stats": [{"unit": "extra", "section": "server", "item": "zone-count", "data": "2"}]}`), 0o644))
+	server, err := Load(path)
+	require.NoError(t, err)
+	ctl := server.NewCtl()
+	require.NoError(t, ctl.SendCommand("stats"))
+	assert.Equal(t, []*libknot.CtlData{{Section: "server", Item: "zone-count", Data: "2"}}, receiveAll(t, ctl))
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+	_, err = Load(path)
+	assert.ErrorContains(t, err, "failed to parse")
+	_, err = Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// maxLimiterKeys bounds the keys a Limiter remembers, expired keys are
+// dropped when it's exceeded
+const maxLimiterKeys = 1024
+
+// Limiter passes at most one record per key and interval, so that a problem
+// repeated on every record of every scrape is logged once in a while
+type Limiter struct {
+	interval time.Duration
+	now      func() time.Time
+
+	mu   sync.Mutex
+	keys map[string]*limiterKey
+}
+
+type limiterKey struct {
+	logged     time.Time // When a record of the key was last logged
+	suppressed int       // Records suppressed since
+}
+
+// NewLimiter returns a limiter passing one record per key and interval
+func NewLimiter(interval time.Duration) *Limiter {
+	return &Limiter{
+		interval: interval,
+		now:      time.Now,
+		keys:     make(map[string]*limiterKey),
+	}
+}
+
+// allow reports whether a record of the key may be logged and how many were
+// suppressed before it
+func (l *Limiter) allow(key string) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	k, exists := l.keys[key]
+	if exists && now.Sub(k.logged) < l.interval {
+		k.suppressed++
+		return false, 0
+	}
+
+	if !exists {
+		if len(l.keys) >= maxLimiterKeys {
+			l.prune(now)
+		}
+		k = &limiterKey{}
+		l.keys[key] = k
+	}
+	suppressed := k.suppressed
+	k.logged, k.suppressed = now, 0
+	return true, suppressed
+}
+
+// prune forgets keys whose interval is over
+func (l *Limiter) prune(now time.Time) {
+	for key, k := range l.keys {
+		if now.Sub(k.logged) >= l.interval {
+			delete(l.keys, key)
+		}
+	}
+}
+
+// Log logs a record unless another one of the key was logged within the
+// interval. The number of records suppressed since is added as the
+// "suppressed" attribute.
+func (l *Limiter) Log(ctx context.Context, logger *slog.Logger, level slog.Level, key, msg string, args ...any) {
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	ok, suppressed := l.allow(key)
+	if !ok {
+		return
+	}
+	if suppressed > 0 {
+		args = append(args, "suppressed", suppressed)
+	}
+	logger.Log(ctx, level, msg, args...)
+}
@@ -0,0 +1,41 @@
+// Package logging sets up structured logging of the exporter
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// Output formats
+const (
+	FormatLogfmt = "logfmt"
+	FormatJSON   = "json"
+)
+
+// Level is the level of the default logger, it may be changed at any time
+var Level = new(slog.LevelVar)
+
+// NewHandler returns a handler writing records of at least the given level
+// to w in the given format
+func NewHandler(w io.Writer, format string, level slog.Leveler) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch format {
+	case FormatLogfmt:
+		return slog.NewTextHandler(w, opts), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(w, opts), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q (expected %q or %q)", format, FormatLogfmt, FormatJSON)
+}
+
+// Setup makes a logger writing to w in the given format at Level the default
+// logger. Output of the log package goes to it as well.
+func Setup(w io.Writer, format string) error {
+	h, err := NewHandler(w, format, Level)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewHandler tests the output formats
+func TestNewHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := NewHandler(&buf, FormatJSON, slog.LevelInfo)
+	require.NoError(t, err)
+	logger := slog.New(h)
+	logger.Debug("hidden")
+	logger.Info("Collection failed", "command", "zone-stats", "zone", "example.com.")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, "Collection failed", record["msg"])
+	assert.Equal(t, "zone-stats", record["command"])
+	assert.Equal(t, "example.com.", record["zone"])
+
+	buf.Reset()
+	h, err = NewHandler(&buf, FormatLogfmt, slog.LevelDebug)
+	require.NoError(t, err)
+	slog.New(h).Debug("Received response", "zone", "example.com.")
+	assert.Contains(t, buf.String(), `level=DEBUG msg="Received response" zone=example.com.`)
+
+	_, err = NewHandler(&buf, "xml", slog.LevelInfo)
+	assert.ErrorContains(t, err, "unknown log format")
+}
+
+// TestLimiter tests rate limiting of records per key
+func TestLimiter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	now := time.Unix(1700000000, 0)
+	l := NewLimiter(time.Minute)
+	l.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	logf := func(key string) {
+		l.Log(ctx, logger, slog.LevelWarn, key, "Failed to parse", "key", key)
+	}
+
+	logf("a")
+	logf("a")
+	logf("a")
+	logf("b")
+	// Disabled records don't count
+	l.Log(ctx, logger, slog.LevelInfo, "a", "hidden")
+	now = now.Add(time.Minute)
+	logf("a")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], "key=a")
+	assert.NotContains(t, lines[0], "suppressed")
+	assert.Contains(t, lines[1], "key=b")
+	assert.Contains(t, lines[2], "key=a suppressed=2")
+}
+
+// TestLimiterPrune tests that expired keys are forgotten
+func TestLimiterPrune(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewLimiter(time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < maxLimiterKeys; i++ {
+		ok, _ := l.allow(string(rune('a' + i)))
+		require.True(t, ok)
+	}
+	now = now.Add(time.Minute)
+	ok, _ := l.allow("new")
+	assert.True(t, ok)
+	assert.Len(t, l.keys, 1)
+}
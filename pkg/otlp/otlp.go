@@ -0,0 +1,110 @@
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// OTLP transport protocols
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// serviceName is the service.name resource attribute, the monitored service
+// rather than the exporter
+const serviceName = "knotd"
+
+// Config configures the OTLP metrics export
+type Config struct {
+	// Endpoint is the URL of the OTLP receiver, e.g. http://collector:4317
+	// for gRPC or http://collector:4318/v1/metrics for HTTP. The http scheme
+	// disables TLS.
+	Endpoint string
+	Protocol string        // ProtocolGRPC or ProtocolHTTP
+	Interval time.Duration // Interval between exports
+	Timeout  time.Duration // Timeout of a single export
+
+	HostName   string // host.name resource attribute
+	Instance   string // service.instance.id resource attribute identifying the knotd instance
+	SocketPath string // knot.control_socket resource attribute
+	Version    string // Exporter version, used as instrumentation scope version
+}
+
+// Exporter periodically exports the metrics of a gatherer over OTLP
+type Exporter struct {
+	provider *sdkmetric.MeterProvider
+}
+
+// Start creates an exporter sending the metrics of gatherer every
+// cfg.Interval until Shutdown is called
+func Start(ctx context.Context, cfg Config, gatherer prometheus.Gatherer) (*Exporter, error) {
+	exporter, err := newMetricExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := sdkmetric.NewPeriodicReader(exporter,
+		sdkmetric.WithInterval(cfg.Interval),
+		sdkmetric.WithTimeout(cfg.Timeout),
+		sdkmetric.WithProducer(newProducer(gatherer, cfg.Version)),
+	)
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(newResource(cfg)),
+		sdkmetric.WithReader(reader),
+	)
+
+	return &Exporter{provider: provider}, nil
+}
+
+// Shutdown exports the current metrics a last time and stops the exporter
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	return e.provider.Shutdown(ctx)
+}
+
+func newMetricExporter(ctx context.Context, cfg Config) (sdkmetric.Exporter, error) {
+	// The exporters silently fall back to localhost on invalid URLs
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q (expected http:// or https:// URL)", cfg.Endpoint)
+	}
+
+	switch strings.ToLower(cfg.Protocol) {
+	case ProtocolGRPC:
+		return otlpmetricgrpc.New(ctx,
+			otlpmetricgrpc.WithEndpointURL(cfg.Endpoint),
+			otlpmetricgrpc.WithTimeout(cfg.Timeout),
+		)
+	case ProtocolHTTP:
+		return otlpmetrichttp.New(ctx,
+			otlpmetrichttp.WithEndpointURL(cfg.Endpoint),
+			otlpmetrichttp.WithTimeout(cfg.Timeout),
+		)
+	}
+	return nil, fmt.Errorf("unknown OTLP protocol %q (expected %q or %q)", cfg.Protocol, ProtocolGRPC, ProtocolHTTP)
+}
+
+// newResource describes the monitored host and knotd instance
+func newResource(cfg Config) *resource.Resource {
+	attrs := []attribute.KeyValue{attribute.String("service.name", serviceName)}
+	if cfg.HostName != "" {
+		attrs = append(attrs, attribute.String("host.name", cfg.HostName))
+	}
+	if cfg.Instance != "" {
+		attrs = append(attrs, attribute.String("service.instance.id", cfg.Instance))
+	}
+	if cfg.SocketPath != "" {
+		attrs = append(attrs, attribute.String("knot.control_socket", cfg.SocketPath))
+	}
+	return resource.NewSchemaless(attrs...)
+}
@@ -0,0 +1,137 @@
+package otlp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// grpcReceiver is a stand-in OTLP/gRPC metrics receiver
+type grpcReceiver struct {
+	collectorpb.UnimplementedMetricsServiceServer
+	requests chan *collectorpb.ExportMetricsServiceRequest
+}
+
+func (r *grpcReceiver) Export(ctx context.Context, req *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error) {
+	r.requests <- req
+	return &collectorpb.ExportMetricsServiceResponse{}, nil
+}
+
+// resourceAttributes returns the string resource attributes of a request
+func resourceAttributes(req *collectorpb.ExportMetricsServiceRequest) map[string]string {
+	attrs := make(map[string]string)
+	for _, rm := range req.ResourceMetrics {
+		for _, kv := range rm.GetResource().GetAttributes() {
+			attrs[kv.GetKey()] = kv.GetValue().GetStringValue()
+		}
+	}
+	return attrs
+}
+
+// metricNames returns the names of the metrics of a request
+func metricNames(req *collectorpb.ExportMetricsServiceRequest) []string {
+	var names []string
+	for _, rm := range req.ResourceMetrics {
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				names = append(names, m.GetName())
+			}
+		}
+	}
+	return names
+}
+
+func testConfig(endpoint, protocol string) Config {
+	return Config{
+		Endpoint:   endpoint,
+		Protocol:   protocol,
+		Interval:   time.Hour,
+		Timeout:    5 * time.Second,
+		HostName:   "ns1.example.com",
+		Instance:   "ns1",
+		SocketPath: "/run/knot/knot.sock",
+		Version:    "1.2.3",
+	}
+}
+
+// assertExport checks the request sent on shutdown
+func assertExport(t *testing.T, req *collectorpb.ExportMetricsServiceRequest) {
+	assert.Equal(t, map[string]string{
+		"service.name":        "knotd",
+		"service.instance.id": "ns1",
+		"host.name":           "ns1.example.com",
+		"knot.control_socket": "/run/knot/knot.sock",
+	}, resourceAttributes(req))
+	assert.ElementsMatch(t, []string{"knot_requests_total", "knot_zone_serial", "knot_size"}, metricNames(req))
+}
+
+// TestExportHTTP tests exporting to an OTLP/HTTP stand-in receiver
+func TestExportHTTP(t *testing.T) {
+	requests := make(chan *collectorpb.ExportMetricsServiceRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/metrics", r.URL.Path)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		req := &collectorpb.ExportMetricsServiceRequest{}
+		require.NoError(t, proto.Unmarshal(body, req))
+		requests <- req
+
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	exporter, err := Start(context.Background(), testConfig(server.URL+"/v1/metrics", ProtocolHTTP), testRegistry())
+	require.NoError(t, err)
+	require.NoError(t, exporter.Shutdown(context.Background()))
+
+	select {
+	case req := <-requests:
+		assertExport(t, req)
+	case <-time.After(5 * time.Second):
+		t.Fatal("No export received")
+	}
+}
+
+// TestExportGRPC tests exporting to an OTLP/gRPC stand-in receiver
+func TestExportGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	receiver := &grpcReceiver{requests: make(chan *collectorpb.ExportMetricsServiceRequest, 1)}
+	server := grpc.NewServer()
+	collectorpb.RegisterMetricsServiceServer(server, receiver)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	exporter, err := Start(context.Background(), testConfig("http://"+listener.Addr().String(), ProtocolGRPC), testRegistry())
+	require.NoError(t, err)
+	require.NoError(t, exporter.Shutdown(context.Background()))
+
+	select {
+	case req := <-receiver.requests:
+		assertExport(t, req)
+	case <-time.After(5 * time.Second):
+		t.Fatal("No export received")
+	}
+}
+
+// TestStartErrors tests validation of the export configuration
+func TestStartErrors(t *testing.T) {
+	_, err := Start(context.Background(), testConfig("http://localhost:4317", "carrier-pigeon"), testRegistry())
+	assert.Error(t, err)
+
+	_, err = Start(context.Background(), testConfig("localhost:4317", ProtocolGRPC), testRegistry())
+	assert.Error(t, err)
+}
@@ -0,0 +1,205 @@
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// scopeName is the instrumentation scope of the exported metrics
+const scopeName = "github.com/CZ-NIC/knot-exporter"
+
+// producer is a metric.Producer converting the metrics of a Prometheus
+// gatherer into OpenTelemetry data points on every export
+type producer struct {
+	gatherer prometheus.Gatherer
+	scope    instrumentation.Scope
+	now      func() time.Time
+
+	mu     sync.Mutex
+	starts map[string]*seriesStart // Cumulative series by name and labels
+}
+
+// seriesStart is the start of a cumulative series. The counters are those of
+// knotd, which restarts them from zero when it restarts.
+type seriesStart struct {
+	start time.Time
+	last  float64   // Value, or count of histograms and summaries
+	seen  time.Time // Time of the last export
+}
+
+func newProducer(gatherer prometheus.Gatherer, version string) *producer {
+	return &producer{
+		gatherer: gatherer,
+		scope:    instrumentation.Scope{Name: scopeName, Version: version},
+		now:      time.Now,
+		starts:   make(map[string]*seriesStart),
+	}
+}
+
+// startTime returns the start of a cumulative series with the given value.
+// A series starts when it's first exported, and again after the export
+// before a decreasing value, as its counter was reset in between.
+func (p *producer) startTime(name string, pm *dto.Metric, value float64, now time.Time) time.Time {
+	key := name
+	for _, pair := range pm.Label {
+		key += "\xff" + pair.GetName() + "\xff" + pair.GetValue()
+	}
+
+	s, exists := p.starts[key]
+	switch {
+	case !exists:
+		s = &seriesStart{start: now}
+		p.starts[key] = s
+	case value < s.last:
+		s.start = s.seen
+	}
+	s.last = value
+	s.seen = now
+	return s.start
+}
+
+// Produce implements metric.Producer interface
+func (p *producer) Produce(ctx context.Context) ([]metricdata.ScopeMetrics, error) {
+	families, err := p.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return nil, fmt.Errorf("failed to gather metrics: %v", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	metrics := make([]metricdata.Metrics, 0, len(families))
+	for _, family := range families {
+		if m, ok := p.convert(family, now); ok {
+			metrics = append(metrics, m)
+		}
+	}
+
+	// Series that are gone start anew if they come back
+	for key, s := range p.starts {
+		if !s.seen.Equal(now) {
+			delete(p.starts, key)
+		}
+	}
+
+	return []metricdata.ScopeMetrics{{Scope: p.scope, Metrics: metrics}}, nil
+}
+
+// convert maps a metric family onto the OpenTelemetry data model. Counters
+// become monotonic cumulative sums, gauges and untyped metrics gauges,
+// histograms explicit bucket histograms and summaries summaries.
+func (p *producer) convert(family *dto.MetricFamily, now time.Time) (metricdata.Metrics, bool) {
+	m := metricdata.Metrics{
+		Name:        family.GetName(),
+		Description: family.GetHelp(),
+	}
+
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		sum := metricdata.Sum[float64]{Temporality: metricdata.CumulativeTemporality, IsMonotonic: true}
+		for _, pm := range family.Metric {
+			value := pm.GetCounter().GetValue()
+			sum.DataPoints = append(sum.DataPoints, metricdata.DataPoint[float64]{
+				Attributes: attributes(pm.Label),
+				StartTime:  p.startTime(family.GetName(), pm, value, now),
+				Time:       now,
+				Value:      value,
+			})
+		}
+		m.Data = sum
+	case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+		gauge := metricdata.Gauge[float64]{}
+		for _, pm := range family.Metric {
+			value := pm.GetGauge().GetValue()
+			if family.GetType() == dto.MetricType_UNTYPED {
+				value = pm.GetUntyped().GetValue()
+			}
+			gauge.DataPoints = append(gauge.DataPoints, metricdata.DataPoint[float64]{
+				Attributes: attributes(pm.Label),
+				Time:       now,
+				Value:      value,
+			})
+		}
+		m.Data = gauge
+	case dto.MetricType_HISTOGRAM:
+		hist := metricdata.Histogram[float64]{Temporality: metricdata.CumulativeTemporality}
+		for _, pm := range family.Metric {
+			hist.DataPoints = append(hist.DataPoints, p.histogramPoint(family.GetName(), pm, now))
+		}
+		m.Data = hist
+	case dto.MetricType_SUMMARY:
+		summary := metricdata.Summary{}
+		for _, pm := range family.Metric {
+			s := pm.GetSummary()
+			point := metricdata.SummaryDataPoint{
+				Attributes: attributes(pm.Label),
+				StartTime:  p.startTime(family.GetName(), pm, float64(s.GetSampleCount()), now),
+				Time:       now,
+				Count:      s.GetSampleCount(),
+				Sum:        s.GetSampleSum(),
+			}
+			for _, q := range s.Quantile {
+				point.QuantileValues = append(point.QuantileValues, metricdata.QuantileValue{
+					Quantile: q.GetQuantile(),
+					Value:    q.GetValue(),
+				})
+			}
+			summary.DataPoints = append(summary.DataPoints, point)
+		}
+		m.Data = summary
+	default:
+		return m, false
+	}
+
+	return m, true
+}
+
+// histogramPoint converts the classic buckets of a Prometheus histogram, whose
+// counts are cumulative, into per-bucket counts. Knot DNS histograms have no
+// sum, their NaN sum is sent as 0 because OTLP data points of the SDK always
+// carry one.
+func (p *producer) histogramPoint(name string, pm *dto.Metric, now time.Time) metricdata.HistogramDataPoint[float64] {
+	h := pm.GetHistogram()
+	point := metricdata.HistogramDataPoint[float64]{
+		Attributes: attributes(pm.Label),
+		StartTime:  p.startTime(name, pm, float64(h.GetSampleCount()), now),
+		Time:       now,
+		Count:      h.GetSampleCount(),
+	}
+	if sum := h.GetSampleSum(); !math.IsNaN(sum) {
+		point.Sum = sum
+	}
+
+	var previous uint64
+	for _, b := range h.Bucket {
+		if math.IsInf(b.GetUpperBound(), +1) {
+			break
+		}
+		point.Bounds = append(point.Bounds, b.GetUpperBound())
+		point.BucketCounts = append(point.BucketCounts, b.GetCumulativeCount()-previous)
+		previous = b.GetCumulativeCount()
+	}
+	// The implicit +Inf bucket
+	point.BucketCounts = append(point.BucketCounts, h.GetSampleCount()-previous)
+
+	return point
+}
+
+// attributes converts metric labels into an attribute set
+func attributes(pairs []*dto.LabelPair) attribute.Set {
+	kvs := make([]attribute.KeyValue, 0, len(pairs))
+	for _, pair := range pairs {
+		kvs = append(kvs, attribute.String(pair.GetName(), pair.GetValue()))
+	}
+	return attribute.NewSet(kvs...)
+}
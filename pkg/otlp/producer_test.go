@@ -0,0 +1,160 @@
+package otlp
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/protobuf/proto"
+)
+
+func testRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "knot_requests_total", Help: "Requests"},
+		[]string{"protocol"})
+	counter.WithLabelValues("udp4").Add(3)
+
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "knot_zone_serial", Help: "Zone serial"},
+		[]string{"zone"})
+	gauge.WithLabelValues("example.com.").Set(2024010101)
+
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "knot_size", Help: "Sizes",
+		Buckets: []float64{16, 32}})
+	histogram.Observe(10)
+	histogram.Observe(20)
+	histogram.Observe(50)
+
+	registry.MustRegister(counter, gauge, histogram)
+	return registry
+}
+
+// producedMetrics returns the metrics of a produced scope by name
+func producedMetrics(t *testing.T, p *producer) map[string]metricdata.Metrics {
+	scopes, err := p.Produce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, scopes, 1)
+	assert.Equal(t, scopeName, scopes[0].Scope.Name)
+
+	metrics := make(map[string]metricdata.Metrics)
+	for _, m := range scopes[0].Metrics {
+		metrics[m.Name] = m
+	}
+	return metrics
+}
+
+// TestProducer tests the mapping of Prometheus metrics onto OpenTelemetry data
+func TestProducer(t *testing.T) {
+	p := newProducer(testRegistry(), "1.2.3")
+	now := time.Unix(1700000000, 0)
+	p.now = func() time.Time { return now }
+
+	metrics := producedMetrics(t, p)
+	require.Len(t, metrics, 3)
+
+	sum, ok := metrics["knot_requests_total"].Data.(metricdata.Sum[float64])
+	require.True(t, ok)
+	assert.True(t, sum.IsMonotonic)
+	assert.Equal(t, metricdata.CumulativeTemporality, sum.Temporality)
+	require.Len(t, sum.DataPoints, 1)
+	assert.Equal(t, float64(3), sum.DataPoints[0].Value)
+	assert.Equal(t, now, sum.DataPoints[0].Time)
+	assert.Equal(t, now, sum.DataPoints[0].StartTime)
+	assert.Equal(t, attribute.NewSet(attribute.String("protocol", "udp4")), sum.DataPoints[0].Attributes)
+	assert.Equal(t, "Requests", metrics["knot_requests_total"].Description)
+
+	gauge, ok := metrics["knot_zone_serial"].Data.(metricdata.Gauge[float64])
+	require.True(t, ok)
+	require.Len(t, gauge.DataPoints, 1)
+	assert.Equal(t, float64(2024010101), gauge.DataPoints[0].Value)
+
+	hist, ok := metrics["knot_size"].Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Len(t, hist.DataPoints, 1)
+	point := hist.DataPoints[0]
+	assert.Equal(t, []float64{16, 32}, point.Bounds)
+	assert.Equal(t, []uint64{1, 1, 1}, point.BucketCounts)
+	assert.Equal(t, uint64(3), point.Count)
+	assert.Equal(t, float64(80), point.Sum)
+}
+
+// TestProducerSummary tests the mapping of summaries
+func TestProducerSummary(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	summary := prometheus.NewSummary(prometheus.SummaryOpts{Name: "knot_latency", Help: "Latency",
+		Objectives: map[float64]float64{0.5: 0.05}})
+	summary.Observe(1)
+	registry.MustRegister(summary)
+
+	metrics := producedMetrics(t, newProducer(registry, ""))
+	data, ok := metrics["knot_latency"].Data.(metricdata.Summary)
+	require.True(t, ok)
+	require.Len(t, data.DataPoints, 1)
+	assert.Equal(t, uint64(1), data.DataPoints[0].Count)
+	assert.Equal(t, []metricdata.QuantileValue{{Quantile: 0.5, Value: 1}}, data.DataPoints[0].QuantileValues)
+}
+
+// counterFamily returns a gatherer of a single counter series with the values
+// of consecutive gatherings
+func counterFamily(values ...float64) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		value := values[0]
+		if len(values) > 1 {
+			values = values[1:]
+		}
+		return []*dto.MetricFamily{{
+			Name: proto.String("knot_requests_total"),
+			Type: dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{{
+				Label:   []*dto.LabelPair{{Name: proto.String("protocol"), Value: proto.String("udp4")}},
+				Counter: &dto.Counter{Value: proto.Float64(value)},
+			}},
+		}}, nil
+	})
+}
+
+// TestProducerCounterReset tests that series start again when knotd resets
+// its counters
+func TestProducerCounterReset(t *testing.T) {
+	p := newProducer(counterFamily(5, 7, 2, 4), "")
+	var now time.Time
+	p.now = func() time.Time { return now }
+
+	var starts []time.Time
+	for i := 1; i <= 4; i++ {
+		now = time.Unix(int64(1700000000+60*i), 0)
+		sum := producedMetrics(t, p)["knot_requests_total"].Data.(metricdata.Sum[float64])
+		starts = append(starts, sum.DataPoints[0].StartTime)
+	}
+
+	first, beforeReset := time.Unix(1700000060, 0), time.Unix(1700000120, 0)
+	assert.Equal(t, []time.Time{first, first, beforeReset, beforeReset}, starts)
+}
+
+// TestProducerHistogramWithoutSum tests that the NaN sum of Knot DNS
+// histograms isn't exported
+func TestProducerHistogramWithoutSum(t *testing.T) {
+	gatherer := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return []*dto.MetricFamily{{
+			Name: proto.String("knot_stats_hist_query_size"),
+			Type: dto.MetricType_HISTOGRAM.Enum(),
+			Metric: []*dto.Metric{{Histogram: &dto.Histogram{
+				SampleCount: proto.Uint64(3),
+				SampleSum:   proto.Float64(math.NaN()),
+				Bucket:      []*dto.Bucket{{UpperBound: proto.Float64(16), CumulativeCount: proto.Uint64(3)}},
+			}}},
+		}}, nil
+	})
+
+	hist := producedMetrics(t, newProducer(gatherer, ""))["knot_stats_hist_query_size"].Data.(metricdata.Histogram[float64])
+	require.Len(t, hist.DataPoints, 1)
+	assert.Equal(t, uint64(3), hist.DataPoints[0].Count)
+	assert.Equal(t, float64(0), hist.DataPoints[0].Sum)
+}
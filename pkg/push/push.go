@@ -0,0 +1,71 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// maxRetryBackoff caps the exponential backoff between push attempts
+const maxRetryBackoff = time.Minute
+
+// Pusher sends gathered metrics to a remote endpoint
+type Pusher interface {
+	// Prepare gathers and encodes the metrics once. The returned function
+	// sends them and is called again to retry.
+	Prepare() (func(ctx context.Context) error, error)
+}
+
+// Retry configures how failed pushes are repeated
+type Retry struct {
+	Retries int           // Number of retries after the first failed attempt
+	Backoff time.Duration // Delay before the first retry, doubled for every further retry
+}
+
+// permanentError marks failures that cannot succeed when retried, such as
+// rejected requests
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// PushWithRetry gathers metrics and pushes them, retrying failed attempts
+// with exponential backoff until the retries are used up or ctx is done.
+// Retries send the same metrics, without collecting again.
+func PushWithRetry(ctx context.Context, p Pusher, retry Retry) error {
+	send, err := p.Prepare()
+	if err != nil {
+		return err
+	}
+	backoff := retry.Backoff
+
+	for attempt := 0; ; attempt++ {
+		err := send(ctx)
+		if err == nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt >= retry.Retries {
+			return err
+		}
+
+		slog.Debug("Push failed, retrying", "attempt", attempt+1, "backoff", backoff, "err", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%v (retries cancelled: %v)", err, ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
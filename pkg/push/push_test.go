@@ -0,0 +1,87 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingPusher fails a given number of pushes before succeeding
+type failingPusher struct {
+	failures int
+	err      error
+	prepared int
+	calls    int
+}
+
+func (p *failingPusher) Prepare() (func(ctx context.Context) error, error) {
+	p.prepared++
+	return func(ctx context.Context) error {
+		p.calls++
+		if p.calls <= p.failures {
+			return p.err
+		}
+		return nil
+	}, nil
+}
+
+// TestPushWithRetry tests retrying failed pushes
+func TestPushWithRetry(t *testing.T) {
+	p := &failingPusher{failures: 2, err: errors.New("unavailable")}
+	require.NoError(t, PushWithRetry(context.Background(), p, Retry{Retries: 2, Backoff: time.Millisecond}))
+	assert.Equal(t, 3, p.calls)
+	assert.Equal(t, 1, p.prepared, "Retries should not gather again")
+
+	p = &failingPusher{failures: 5, err: errors.New("unavailable")}
+	assert.Error(t, PushWithRetry(context.Background(), p, Retry{Retries: 2, Backoff: time.Millisecond}))
+	assert.Equal(t, 3, p.calls)
+}
+
+// TestPushWithRetryPermanent tests that permanent failures are not retried
+func TestPushWithRetryPermanent(t *testing.T) {
+	p := &failingPusher{failures: 5, err: &permanentError{err: errors.New("bad request")}}
+	assert.Error(t, PushWithRetry(context.Background(), p, Retry{Retries: 3, Backoff: time.Millisecond}))
+	assert.Equal(t, 1, p.calls)
+}
+
+// TestPushWithRetryCancel tests that cancellation interrupts the backoff
+func TestPushWithRetryCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p := &failingPusher{failures: 5, err: errors.New("unavailable")}
+	assert.Error(t, PushWithRetry(ctx, p, Retry{Retries: 3, Backoff: time.Hour}))
+	assert.Equal(t, 1, p.calls)
+}
+
+// TestPushgateway tests pushing to a Pushgateway stand-in
+func TestPushgateway(t *testing.T) {
+	var method, path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(content)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "knot_test", Help: "Test gauge"})
+	gauge.Set(42)
+	registry.MustRegister(gauge)
+
+	p := NewPushgateway(server.URL, "knot", "ns1", registry, server.Client())
+	require.NoError(t, PushWithRetry(context.Background(), p, Retry{}))
+
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/metrics/job/knot/instance/ns1", path)
+	assert.True(t, strings.Contains(body, "knot_test"), "Pushed body lacks metric: %q", body)
+}
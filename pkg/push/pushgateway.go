@@ -0,0 +1,42 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Pushgateway pushes metrics to a Prometheus Pushgateway, replacing all
+// metrics of its grouping key on every push
+type Pushgateway struct {
+	url      string
+	job      string
+	instance string
+	gatherer prometheus.Gatherer
+	client   *http.Client
+}
+
+// NewPushgateway creates a Pushgateway pusher for the gateway at url. The
+// metrics are grouped by job and, if not empty, instance.
+func NewPushgateway(url, job, instance string, gatherer prometheus.Gatherer, client *http.Client) *Pushgateway {
+	return &Pushgateway{url: url, job: job, instance: instance, gatherer: gatherer, client: client}
+}
+
+// Prepare implements Pusher interface
+func (p *Pushgateway) Prepare() (func(ctx context.Context) error, error) {
+	families, err := p.gatherer.Gather()
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather metrics: %v", err)
+	}
+
+	gathered := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) { return families, nil })
+	pusher := push.New(p.url, p.job).Gatherer(gathered).Client(p.client)
+	if p.instance != "" {
+		pusher = pusher.Grouping("instance", p.instance)
+	}
+	return pusher.PushContext, nil
+}
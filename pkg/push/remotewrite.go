@@ -0,0 +1,223 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// remoteWriteVersion is the version of the remote-write protocol sent
+const remoteWriteVersion = "0.1.0"
+
+// maxErrorBody limits how much of a rejected request's response is reported
+const maxErrorBody = 512
+
+// RemoteWrite sends metrics to a Prometheus remote-write endpoint
+type RemoteWrite struct {
+	url      string
+	gatherer prometheus.Gatherer
+	labels   map[string]string
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewRemoteWrite creates a remote-write pusher for the endpoint at url. The
+// labels, typically job and instance, are added to every series that doesn't
+// have them already.
+func NewRemoteWrite(url string, labels map[string]string, gatherer prometheus.Gatherer, client *http.Client) *RemoteWrite {
+	return &RemoteWrite{
+		url:      url,
+		gatherer: gatherer,
+		labels:   labels,
+		client:   client,
+		now:      time.Now,
+	}
+}
+
+// Prepare implements Pusher interface
+func (r *RemoteWrite) Prepare() (func(ctx context.Context) error, error) {
+	families, err := r.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return nil, fmt.Errorf("failed to gather metrics: %v", err)
+	}
+
+	series := toTimeSeries(families, r.labels, r.now().UnixMilli())
+	body := snappy.Encode(nil, encodeWriteRequest(series))
+	return func(ctx context.Context) error { return r.send(ctx, body) }, nil
+}
+
+// send posts an encoded write request
+func (r *RemoteWrite) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{err: err}
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", remoteWriteVersion)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	err = fmt.Errorf("remote write to %s failed with status %s: %s", r.url, resp.Status, bytes.TrimSpace(msg))
+	// Rejected requests fail again, the receiver may recover from anything else
+	if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests {
+		return &permanentError{err: err}
+	}
+	return err
+}
+
+// label is a name and value pair of a time series
+type label struct {
+	name, value string
+}
+
+// timeSeries is a single sample with its labels, including the metric name
+type timeSeries struct {
+	labels    []label
+	value     float64
+	timestamp int64 // Milliseconds since the epoch
+}
+
+// toTimeSeries flattens metric families into time series. Histograms and
+// summaries are sent as their classic _bucket, _sum and _count series.
+func toTimeSeries(families []*dto.MetricFamily, extra map[string]string, timestamp int64) []timeSeries {
+	var series []timeSeries
+
+	for _, family := range families {
+		name := family.GetName()
+		for _, m := range family.Metric {
+			add := func(suffix string, value float64, more ...label) {
+				series = append(series, timeSeries{
+					labels:    seriesLabels(name+suffix, m.Label, extra, more...),
+					value:     value,
+					timestamp: timestamp,
+				})
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				add("", m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add("", m.GetGauge().GetValue())
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				h := m.GetHistogram()
+				for _, b := range h.Bucket {
+					add("_bucket", float64(b.GetCumulativeCount()), label{"le", formatFloat(b.GetUpperBound())})
+				}
+				add("_bucket", float64(h.GetSampleCount()), label{"le", "+Inf"})
+				// Knot DNS histograms have no sum
+				if !math.IsNaN(h.GetSampleSum()) {
+					add("_sum", h.GetSampleSum())
+				}
+				add("_count", float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.Quantile {
+					add("", q.GetValue(), label{"quantile", formatFloat(q.GetQuantile())})
+				}
+				add("_sum", s.GetSampleSum())
+				add("_count", float64(s.GetSampleCount()))
+			default:
+				add("", m.GetUntyped().GetValue())
+			}
+		}
+	}
+
+	return series
+}
+
+// seriesLabels builds the sorted label set of a series. Labels of the metric
+// take precedence over the extra labels.
+func seriesLabels(name string, pairs []*dto.LabelPair, extra map[string]string, more ...label) []label {
+	set := make(map[string]string, len(pairs)+len(extra)+len(more)+1)
+	for n, v := range extra {
+		set[n] = v
+	}
+	for _, pair := range pairs {
+		set[pair.GetName()] = pair.GetValue()
+	}
+	for _, l := range more {
+		set[l.name] = l.value
+	}
+	set["__name__"] = name
+
+	labels := make([]label, 0, len(set))
+	for n, v := range set {
+		if v != "" {
+			labels = append(labels, label{n, v})
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+	return labels
+}
+
+// formatFloat formats bucket bounds and quantiles the way the text format does
+func formatFloat(f float64) string {
+	if math.IsInf(f, +1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Field numbers of the remote-write protobuf messages
+const (
+	writeRequestTimeseries = 1 // WriteRequest.timeseries
+	timeSeriesLabels       = 1 // TimeSeries.labels
+	timeSeriesSamples      = 2 // TimeSeries.samples
+	labelName              = 1 // Label.name
+	labelValue             = 2 // Label.value
+	sampleValue            = 1 // Sample.value
+	sampleTimestamp        = 2 // Sample.timestamp
+)
+
+// encodeWriteRequest encodes time series as a prometheus.WriteRequest
+// protobuf message
+func encodeWriteRequest(series []timeSeries) []byte {
+	var buf, ts, msg []byte
+
+	for _, s := range series {
+		ts = ts[:0]
+		for _, l := range s.labels {
+			msg = msg[:0]
+			msg = protowire.AppendTag(msg, labelName, protowire.BytesType)
+			msg = protowire.AppendString(msg, l.name)
+			msg = protowire.AppendTag(msg, labelValue, protowire.BytesType)
+			msg = protowire.AppendString(msg, l.value)
+			ts = protowire.AppendTag(ts, timeSeriesLabels, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, msg)
+		}
+
+		msg = msg[:0]
+		msg = protowire.AppendTag(msg, sampleValue, protowire.Fixed64Type)
+		msg = protowire.AppendFixed64(msg, math.Float64bits(s.value))
+		msg = protowire.AppendTag(msg, sampleTimestamp, protowire.VarintType)
+		msg = protowire.AppendVarint(msg, uint64(s.timestamp))
+		ts = protowire.AppendTag(ts, timeSeriesSamples, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, msg)
+
+		buf = protowire.AppendTag(buf, writeRequestTimeseries, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, ts)
+	}
+
+	return buf
+}
@@ -0,0 +1,223 @@
+package push
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// decodedSeries is a time series decoded by the remote-write stand-in
+type decodedSeries struct {
+	labels    map[string]string
+	value     float64
+	timestamp int64
+}
+
+// forEachField calls fn for every field of a protobuf message
+func forEachField(t *testing.T, b []byte, fn func(num protowire.Number, typ protowire.Type, field []byte)) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0, "Invalid tag")
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		require.GreaterOrEqual(t, n, 0, "Invalid field %d", num)
+		fn(num, typ, b[:n])
+		b = b[n:]
+	}
+}
+
+// bytesField returns the content of a length-delimited field
+func bytesField(t *testing.T, field []byte) []byte {
+	v, n := protowire.ConsumeBytes(field)
+	require.GreaterOrEqual(t, n, 0)
+	return v
+}
+
+// decodeWriteRequest decodes a snappy compressed WriteRequest
+func decodeWriteRequest(t *testing.T, body []byte) []decodedSeries {
+	raw, err := snappy.Decode(nil, body)
+	require.NoError(t, err)
+
+	var series []decodedSeries
+	forEachField(t, raw, func(num protowire.Number, typ protowire.Type, field []byte) {
+		require.Equal(t, protowire.Number(writeRequestTimeseries), num)
+		s := decodedSeries{labels: make(map[string]string)}
+		forEachField(t, bytesField(t, field), func(num protowire.Number, typ protowire.Type, field []byte) {
+			switch num {
+			case timeSeriesLabels:
+				var name, value string
+				forEachField(t, bytesField(t, field), func(num protowire.Number, typ protowire.Type, field []byte) {
+					if num == labelName {
+						name = string(bytesField(t, field))
+					} else {
+						value = string(bytesField(t, field))
+					}
+				})
+				s.labels[name] = value
+			case timeSeriesSamples:
+				forEachField(t, bytesField(t, field), func(num protowire.Number, typ protowire.Type, field []byte) {
+					if num == sampleValue {
+						v, _ := protowire.ConsumeFixed64(field)
+						s.value = math.Float64frombits(v)
+					} else {
+						v, _ := protowire.ConsumeVarint(field)
+						s.timestamp = int64(v)
+					}
+				})
+			}
+		})
+		series = append(series, s)
+	})
+	return series
+}
+
+// remoteWriteReceiver is a stand-in remote-write endpoint
+func remoteWriteReceiver(t *testing.T, status int, received *[]decodedSeries) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, remoteWriteVersion, r.Header.Get("X-Prometheus-Remote-Write-Version"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		*received = decodeWriteRequest(t, body)
+		w.WriteHeader(status)
+	}))
+}
+
+func testRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "knot_requests_total", Help: "Requests"},
+		[]string{"protocol"})
+	counter.WithLabelValues("udp4").Add(3)
+
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "knot_size", Help: "Sizes",
+		Buckets: []float64{16, 32}})
+	histogram.Observe(10)
+	histogram.Observe(20)
+
+	registry.MustRegister(counter, histogram)
+	return registry
+}
+
+// TestRemoteWrite tests sending metrics to a remote-write stand-in
+func TestRemoteWrite(t *testing.T) {
+	var received []decodedSeries
+	server := remoteWriteReceiver(t, http.StatusNoContent, &received)
+	defer server.Close()
+
+	rw := NewRemoteWrite(server.URL, map[string]string{"job": "knot", "instance": "ns1", "protocol": "ignored"},
+		testRegistry(), server.Client())
+	rw.now = func() time.Time { return time.UnixMilli(1700000000000) }
+	require.NoError(t, PushWithRetry(context.Background(), rw, Retry{}))
+
+	values := make(map[string]float64)
+	for _, s := range received {
+		assert.Equal(t, int64(1700000000000), s.timestamp)
+		assert.Equal(t, "knot", s.labels["job"])
+		assert.Equal(t, "ns1", s.labels["instance"])
+		values[s.labels["__name__"]+"/"+s.labels["le"]] = s.value
+	}
+
+	assert.Equal(t, map[string]float64{
+		"knot_requests_total/":  3,
+		"knot_size_bucket/16":   1,
+		"knot_size_bucket/32":   2,
+		"knot_size_bucket/+Inf": 2,
+		"knot_size_sum/":        30,
+		"knot_size_count/":      2,
+	}, values)
+
+	// Labels of the metric take precedence
+	for _, s := range received {
+		if s.labels["__name__"] == "knot_requests_total" {
+			assert.Equal(t, "udp4", s.labels["protocol"])
+		}
+	}
+}
+
+// TestRemoteWriteErrors tests classification of rejected requests
+func TestRemoteWriteErrors(t *testing.T) {
+	var received []decodedSeries
+	rejecting := remoteWriteReceiver(t, http.StatusBadRequest, &received)
+	defer rejecting.Close()
+
+	err := PushWithRetry(context.Background(), NewRemoteWrite(rejecting.URL, nil, testRegistry(), rejecting.Client()), Retry{})
+	var permanent *permanentError
+	assert.ErrorAs(t, err, &permanent)
+
+	failing := remoteWriteReceiver(t, http.StatusServiceUnavailable, &received)
+	defer failing.Close()
+
+	err = PushWithRetry(context.Background(), NewRemoteWrite(failing.URL, nil, testRegistry(), failing.Client()), Retry{})
+	require.Error(t, err)
+	assert.NotErrorAs(t, err, &permanent)
+}
+
+// countingGatherer counts the gatherings of a gatherer
+type countingGatherer struct {
+	prometheus.Gatherer
+	count int
+}
+
+func (g *countingGatherer) Gather() ([]*dto.MetricFamily, error) {
+	g.count++
+	return g.Gatherer.Gather()
+}
+
+// TestRemoteWriteRetry tests that retries resend the gathered metrics
+func TestRemoteWriteRetry(t *testing.T) {
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	gatherer := &countingGatherer{Gatherer: testRegistry()}
+	rw := NewRemoteWrite(server.URL, nil, gatherer, server.Client())
+	require.NoError(t, PushWithRetry(context.Background(), rw, Retry{Retries: 1, Backoff: time.Millisecond}))
+
+	assert.Equal(t, 1, gatherer.count)
+	require.Len(t, bodies, 2)
+	assert.Equal(t, bodies[0], bodies[1])
+}
+
+// TestToTimeSeriesHistogramWithoutSum tests that the NaN sum of Knot DNS
+// histograms isn't sent
+func TestToTimeSeriesHistogramWithoutSum(t *testing.T) {
+	families := []*dto.MetricFamily{{
+		Name: proto.String("knot_stats_hist_query_size"),
+		Type: dto.MetricType_HISTOGRAM.Enum(),
+		Metric: []*dto.Metric{{Histogram: &dto.Histogram{
+			SampleCount: proto.Uint64(3),
+			SampleSum:   proto.Float64(math.NaN()),
+			Bucket:      []*dto.Bucket{{UpperBound: proto.Float64(16), CumulativeCount: proto.Uint64(3)}},
+		}}},
+	}}
+
+	var names []string
+	for _, s := range toTimeSeries(families, nil, 0) {
+		names = append(names, s.labels[0].value)
+	}
+	assert.Equal(t, []string{"knot_stats_hist_query_size_bucket", "knot_stats_hist_query_size_bucket",
+		"knot_stats_hist_query_size_count"}, names)
+}
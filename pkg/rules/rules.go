@@ -0,0 +1,242 @@
+// Package rules generates Prometheus alerting and recording rules for the
+// metrics of the exporter
+package rules
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the thresholds of the rules
+type Config struct {
+	// Selector holds label matchers added to every selector, such as
+	// job="knot", to tell the exporter apart from other targets
+	Selector string
+	// KnotDownFor is how long Knot DNS may be unreachable
+	KnotDownFor time.Duration
+	// ZoneExpiry is the time left until a secondary zone expires below which
+	// the zone is reported as expiring
+	ZoneExpiry time.Duration
+	// SerialStaleWindow is how long the serial of a signed zone may stay the
+	// same, it changes with every re-sign
+	SerialStaleWindow time.Duration
+	// ResignOverdueFor is how long the DNSSEC re-sign of a zone may be
+	// overdue before its signatures are reported as expiring
+	ResignOverdueFor time.Duration
+	// ServfailRatio is the share of SERVFAIL responses reported as high
+	ServfailRatio float64
+	// ServfailFor is how long the SERVFAIL ratio may stay high
+	ServfailFor time.Duration
+	// MemoryGrowthRatio is the factor by which the memory usage of Knot DNS
+	// may grow within MemoryGrowthWindow
+	MemoryGrowthRatio float64
+	// MemoryGrowthWindow is the period memory growth is measured over
+	MemoryGrowthWindow time.Duration
+}
+
+// Default returns the default thresholds
+func Default() Config {
+	return Config{
+		KnotDownFor:        5 * time.Minute,
+		ZoneExpiry:         24 * time.Hour,
+		SerialStaleWindow:  8 * 24 * time.Hour,
+		ResignOverdueFor:   time.Hour,
+		ServfailRatio:      0.05,
+		ServfailFor:        15 * time.Minute,
+		MemoryGrowthRatio:  1.5,
+		MemoryGrowthWindow: 24 * time.Hour,
+	}
+}
+
+// selectorRegex matches a list of label matchers
+var selectorRegex = regexp.MustCompile(`^\s*[a-zA-Z_][a-zA-Z0-9_]*\s*(=|!=|=~|!~)\s*"(?:[^"\\]|\\.)*"\s*(,\s*[a-zA-Z_][a-zA-Z0-9_]*\s*(=|!=|=~|!~)\s*"(?:[^"\\]|\\.)*"\s*)*,?\s*$`)
+
+// Validate checks that the thresholds are usable
+func (c Config) Validate() error {
+	if c.Selector != "" && !selectorRegex.MatchString(c.Selector) {
+		return fmt.Errorf("invalid selector %q (expected label matchers such as job=\"knot\")", c.Selector)
+	}
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"knot down duration", c.KnotDownFor},
+		{"zone expiry", c.ZoneExpiry},
+		{"serial stale window", c.SerialStaleWindow},
+		{"re-sign overdue duration", c.ResignOverdueFor},
+		{"SERVFAIL duration", c.ServfailFor},
+		{"memory growth window", c.MemoryGrowthWindow},
+	} {
+		if d.value <= 0 || d.value%time.Second != 0 {
+			return fmt.Errorf("invalid %s %v (expected whole seconds above zero)", d.name, d.value)
+		}
+	}
+	if c.ServfailRatio <= 0 || c.ServfailRatio >= 1 {
+		return fmt.Errorf("invalid SERVFAIL ratio %v (expected between 0 and 1)", c.ServfailRatio)
+	}
+	if c.MemoryGrowthRatio <= 1 {
+		return fmt.Errorf("invalid memory growth ratio %v (expected above 1)", c.MemoryGrowthRatio)
+	}
+	return nil
+}
+
+// File is a Prometheus rule file
+type File struct {
+	Groups []Group `yaml:"groups"`
+}
+
+// Group is a group of rules evaluated together
+type Group struct {
+	Name  string `yaml:"name"`
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule is a recording rule (Record is set) or an alerting rule (Alert is set)
+type Rule struct {
+	Record      string            `yaml:"record,omitempty"`
+	Alert       string            `yaml:"alert,omitempty"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// Names of the recording rules
+const (
+	recordResponses     = "instance:knot_responses:rate5m"
+	recordServfail      = "instance:knot_servfail_responses:rate5m"
+	recordServfailRatio = "instance:knot_servfail_responses:ratio_rate5m"
+	recordMemoryUsage   = "instance:knot_memory_usage_bytes:sum"
+)
+
+// Severities of the alerts
+const (
+	severityCritical = "critical"
+	severityWarning  = "warning"
+)
+
+// Generate returns the rules for the thresholds of c. The rules use the
+// metric names of the default naming scheme.
+func Generate(c Config) (*File, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	recording := Group{Name: "knot-exporter.rules", Rules: []Rule{
+		{
+			Record: recordResponses,
+			Expr:   fmt.Sprintf("sum without (module, rcode) (rate(knot_stats_response_code_total%s[5m]))", c.selector()),
+		},
+		{
+			Record: recordServfail,
+			Expr:   fmt.Sprintf("sum without (module, rcode) (rate(knot_stats_response_code_total%s[5m]))", c.selector(`rcode="SERVFAIL"`)),
+		},
+		{
+			Record: recordServfailRatio,
+			Expr:   fmt.Sprintf("%s / %s", recordServfail, recordResponses),
+		},
+		{
+			Record: recordMemoryUsage,
+			Expr:   fmt.Sprintf("sum without (pid) (knot_memory_usage_bytes%s)", c.selector()),
+		},
+	}}
+
+	alerting := Group{Name: "knot-exporter.alerts", Rules: []Rule{
+		{
+			Alert:  "KnotDown",
+			Expr:   fmt.Sprintf("knot_up%s == 0", c.selector()),
+			For:    duration(c.KnotDownFor),
+			Labels: map[string]string{"severity": severityCritical},
+			Annotations: map[string]string{
+				"summary":     "Knot DNS is unreachable",
+				"description": "The exporter on {{ $labels.instance }} can't connect to the control socket of Knot DNS.",
+			},
+		},
+		{
+			Alert:  "KnotZoneExpiring",
+			Expr:   fmt.Sprintf("knot_zone_status_expiration_seconds%s < %d", c.selector(), int64(c.ZoneExpiry.Seconds())),
+			Labels: map[string]string{"severity": severityCritical},
+			Annotations: map[string]string{
+				"summary":     "Zone {{ $labels.zone }} is about to expire",
+				"description": "Zone {{ $labels.zone }} on {{ $labels.instance }} expires in {{ $value | humanizeDuration }} unless a refresh from the primary succeeds.",
+			},
+		},
+		{
+			Alert: "KnotZoneSerialStale",
+			Expr: fmt.Sprintf("changes(knot_zone_serial%s[%s]) == 0 and knot_zone_status_resign_seconds%s",
+				c.selector(), duration(c.SerialStaleWindow), c.selector()),
+			Labels: map[string]string{"severity": severityWarning},
+			Annotations: map[string]string{
+				"summary":     "Serial of zone {{ $labels.zone }} is not advancing",
+				"description": fmt.Sprintf("The serial of signed zone {{ $labels.zone }} on {{ $labels.instance }} hasn't changed for %s although it changes with every re-sign.", duration(c.SerialStaleWindow)),
+			},
+		},
+		{
+			Alert:  "KnotZoneSignaturesExpiring",
+			Expr:   fmt.Sprintf("knot_zone_status_resign_seconds%s <= 0", c.selector()),
+			For:    duration(c.ResignOverdueFor),
+			Labels: map[string]string{"severity": severityCritical},
+			Annotations: map[string]string{
+				"summary":     "Signatures of zone {{ $labels.zone }} are approaching expiry",
+				"description": fmt.Sprintf("The DNSSEC re-sign of zone {{ $labels.zone }} on {{ $labels.instance }} has been overdue for %s, its signatures are not being refreshed.", duration(c.ResignOverdueFor)),
+			},
+		},
+		{
+			Alert:  "KnotHighServfailRatio",
+			Expr:   fmt.Sprintf("%s > %s", recordServfailRatio, strconv.FormatFloat(c.ServfailRatio, 'g', -1, 64)),
+			For:    duration(c.ServfailFor),
+			Labels: map[string]string{"severity": severityWarning},
+			Annotations: map[string]string{
+				"summary":     "High SERVFAIL ratio",
+				"description": "{{ $value | humanizePercentage }} of the responses of Knot DNS on {{ $labels.instance }} are SERVFAIL.",
+			},
+		},
+		{
+			Alert: "KnotMemoryGrowth",
+			Expr: fmt.Sprintf("%s / %s offset %s > %s", recordMemoryUsage, recordMemoryUsage,
+				duration(c.MemoryGrowthWindow), strconv.FormatFloat(c.MemoryGrowthRatio, 'g', -1, 64)),
+			For:    "1h",
+			Labels: map[string]string{"severity": severityWarning},
+			Annotations: map[string]string{
+				"summary":     "Memory usage of Knot DNS is growing",
+				"description": fmt.Sprintf("Knot DNS on {{ $labels.instance }} uses {{ $value | humanize }} times the memory it used %s ago.", duration(c.MemoryGrowthWindow)),
+			},
+		},
+	}}
+
+	return &File{Groups: []Group{recording, alerting}}, nil
+}
+
+// selector returns a selector of the given matchers and the configured ones,
+// empty when there are none
+func (c Config) selector(matchers ...string) string {
+	if selector := strings.TrimSuffix(strings.TrimSpace(c.Selector), ","); selector != "" {
+		matchers = append(matchers, selector)
+	}
+	if len(matchers) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(matchers, ", ") + "}"
+}
+
+// duration formats a duration like Prometheus, e.g. 8d or 1h30m
+func duration(d time.Duration) string {
+	return model.Duration(d).String()
+}
+
+// Write prints the rule file as YAML
+func (f *File) Write(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(f); err != nil {
+		return err
+	}
+	return enc.Close()
+}
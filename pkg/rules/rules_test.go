@@ -0,0 +1,198 @@
+package rules
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CZ-NIC/knot-exporter/pkg/collector"
+	"github.com/CZ-NIC/knot-exporter/pkg/libknot/scripted"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// shippedRules is the rule file shipped with the exporter
+var shippedRules = filepath.Join("..", "..", "contrib", "prometheus", "knot-exporter-rules.yml")
+
+// fixtureCatalog returns the metrics the collector exports for the recorded
+// Knot DNS responses of the collector fixtures, with the static ones
+func fixtureCatalog(t *testing.T) map[string]collector.MetricInfo {
+	server, err := scripted.Load(filepath.Join("..", "collector", "testdata", "knotd", "responses.json"))
+	require.NoError(t, err)
+
+	c := collector.NewKnotCollector("/test", 1000, false, true, true, true, true, true,
+		collector.WithControlFactory(func() collector.KnotCtlInterface { return server.NewCtl() }))
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.NoError(t, c.LastError())
+
+	static, err := c.StaticCatalog()
+	require.NoError(t, err)
+	catalog := make(map[string]collector.MetricInfo)
+	for _, info := range collector.MergeCatalogs(static, collector.FamilyCatalog(families, "")) {
+		catalog[info.Name] = info
+	}
+	return catalog
+}
+
+// selectors returns the vector selectors of an expression
+func selectors(expr parser.Node) []*parser.VectorSelector {
+	var found []*parser.VectorSelector
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		if selector, ok := node.(*parser.VectorSelector); ok {
+			found = append(found, selector)
+		}
+		return nil
+	})
+	return found
+}
+
+// TestRulesMatchMetrics tests that the rules are valid PromQL and only select
+// metrics and labels the collector exports for the fixtures, and recorded
+// series defined before
+func TestRulesMatchMetrics(t *testing.T) {
+	catalog := fixtureCatalog(t)
+	file, err := Generate(Default())
+	require.NoError(t, err)
+
+	records := make(map[string]bool)
+	alerts := make(map[string]bool)
+	for _, group := range file.Groups {
+		for _, rule := range group.Rules {
+			name := rule.Record + rule.Alert
+			expr, err := parser.ParseExpr(rule.Expr)
+			if !assert.NoError(t, err, name) {
+				continue
+			}
+
+			for _, selector := range selectors(expr) {
+				switch {
+				case strings.Contains(selector.Name, ":"):
+					assert.True(t, records[selector.Name], "%s: recorded series %s is not defined before", name, selector.Name)
+				case strings.HasPrefix(selector.Name, "knot_"):
+					info, exists := catalog[selector.Name]
+					if !assert.True(t, exists, "%s: metric %s is not exported", name, selector.Name) {
+						continue
+					}
+					for _, matcher := range selector.LabelMatchers {
+						if matcher.Name != labels.MetricName {
+							assert.Contains(t, info.Labels, matcher.Name, "%s: %s has no label %s", name, selector.Name, matcher.Name)
+						}
+					}
+				default:
+					assert.Fail(t, "unexpected selector", "%s: %s", name, selector)
+				}
+			}
+
+			// Aggregations drop labels of the selected metrics
+			parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+				aggregate, ok := node.(*parser.AggregateExpr)
+				if !ok {
+					return nil
+				}
+				for _, selector := range selectors(aggregate.Expr) {
+					if info, exists := catalog[selector.Name]; exists {
+						for _, label := range aggregate.Grouping {
+							assert.Contains(t, info.Labels, label, "%s: %s has no label %s", name, selector.Name, label)
+						}
+					}
+				}
+				return nil
+			})
+
+			if rule.Record != "" {
+				records[rule.Record] = true
+			} else {
+				assert.False(t, alerts[rule.Alert], "duplicate alert %s", rule.Alert)
+				alerts[rule.Alert] = true
+				assert.NotEmpty(t, rule.Labels["severity"], rule.Alert)
+				assert.NotEmpty(t, rule.Annotations["summary"], rule.Alert)
+				assert.NotEmpty(t, rule.Annotations["description"], rule.Alert)
+			}
+		}
+	}
+	assert.Len(t, alerts, 6)
+}
+
+// TestShippedRules tests that the shipped rule file holds the default rules
+func TestShippedRules(t *testing.T) {
+	file, err := Generate(Default())
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, file.Write(&buf))
+
+	shipped, err := os.ReadFile(shippedRules)
+	require.NoError(t, err)
+	assert.Equal(t, string(shipped), buf.String(), "regenerate %s with make rules", shippedRules)
+
+	// The output is a valid rule file
+	var parsed File
+	require.NoError(t, yaml.Unmarshal(shipped, &parsed))
+	assert.Equal(t, file, &parsed)
+}
+
+// TestGenerateConfig tests that the thresholds and the selector apply
+func TestGenerateConfig(t *testing.T) {
+	c := Default()
+	c.Selector = `job="knot",`
+	c.KnotDownFor = 90 * time.Second
+	c.ZoneExpiry = 2 * time.Hour
+	c.SerialStaleWindow = 36 * time.Hour
+	c.ServfailRatio = 0.2
+	c.MemoryGrowthRatio = 2
+	c.MemoryGrowthWindow = 7 * 24 * time.Hour
+	file, err := Generate(c)
+	require.NoError(t, err)
+
+	rules := make(map[string]Rule)
+	for _, group := range file.Groups {
+		for _, rule := range group.Rules {
+			rules[rule.Record+rule.Alert] = rule
+			_, err := parser.ParseExpr(rule.Expr)
+			assert.NoError(t, err, rule.Record+rule.Alert)
+		}
+	}
+	assert.Equal(t, `sum without (module, rcode) (rate(knot_stats_response_code_total{rcode="SERVFAIL", job="knot"}[5m]))`,
+		rules["instance:knot_servfail_responses:rate5m"].Expr)
+	assert.Equal(t, `knot_up{job="knot"} == 0`, rules["KnotDown"].Expr)
+	assert.Equal(t, "1m30s", rules["KnotDown"].For)
+	assert.Equal(t, `knot_zone_status_expiration_seconds{job="knot"} < 7200`, rules["KnotZoneExpiring"].Expr)
+	assert.Equal(t, `changes(knot_zone_serial{job="knot"}[1d12h]) == 0 and knot_zone_status_resign_seconds{job="knot"}`,
+		rules["KnotZoneSerialStale"].Expr)
+	assert.Equal(t, "instance:knot_servfail_responses:ratio_rate5m > 0.2", rules["KnotHighServfailRatio"].Expr)
+	assert.Equal(t, "instance:knot_memory_usage_bytes:sum / instance:knot_memory_usage_bytes:sum offset 1w > 2",
+		rules["KnotMemoryGrowth"].Expr)
+}
+
+// TestValidate tests rejection of unusable thresholds
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Default().Validate())
+
+	for name, modify := range map[string]func(*Config){
+		"selector":        func(c *Config) { c.Selector = "job=knot" },
+		"selector braces": func(c *Config) { c.Selector = `{job="knot"}` },
+		"zero duration":   func(c *Config) { c.KnotDownFor = 0 },
+		"sub-second":      func(c *Config) { c.ServfailFor = 1500 * time.Millisecond },
+		"servfail ratio":  func(c *Config) { c.ServfailRatio = 1 },
+		"memory ratio":    func(c *Config) { c.MemoryGrowthRatio = 0.5 },
+	} {
+		c := Default()
+		modify(&c)
+		assert.Error(t, c.Validate(), name)
+		_, err := Generate(c)
+		assert.Error(t, err, name)
+	}
+
+	c := Default()
+	c.Selector = `job="knot", instance=~"ns[0-9]+\"x"`
+	assert.NoError(t, c.Validate())
+}
@@ -1,15 +1,12 @@
 package utils
 
 import (
-	"log"
+	"log/slog"
 	"regexp"
 	"strconv"
 	"strings"
 )
 
-// DebugMode is the global debug flag
-var DebugMode bool
-
 // Compile the regex pattern once at package initialization
 var durationRegex = regexp.MustCompile(`^([+-])((\d+)D)?((\d+)h)?((\d+)m)?((\d+)s)?$`)
 
@@ -44,7 +41,7 @@ func ParseDurationString(durationStr string) (float64, bool) {
 	if matches[3] != "" {
 		days, err := strconv.ParseFloat(matches[3], 64)
 		if err != nil {
-			log.Printf("Warning: failed to parse days value '%s': %v", matches[3], err)
+			slog.Warn("Failed to parse days of duration", "value", matches[3], "err", err)
 		} else {
 			totalSeconds += days * 86400 // 86400 seconds in a day
 		}
@@ -54,7 +51,7 @@ func ParseDurationString(durationStr string) (float64, bool) {
 	if matches[5] != "" {
 		hours, err := strconv.ParseFloat(matches[5], 64)
 		if err != nil {
-			log.Printf("Warning: failed to parse hours value '%s': %v", matches[5], err)
+			slog.Warn("Failed to parse hours of duration", "value", matches[5], "err", err)
 		} else {
 			totalSeconds += hours * 3600 // 3600 seconds in an hour
 		}
@@ -64,7 +61,7 @@ func ParseDurationString(durationStr string) (float64, bool) {
 	if matches[7] != "" {
 		minutes, err := strconv.ParseFloat(matches[7], 64)
 		if err != nil {
-			log.Printf("Warning: failed to parse minutes value '%s': %v", matches[7], err)
+			slog.Warn("Failed to parse minutes of duration", "value", matches[7], "err", err)
 		} else {
 			totalSeconds += minutes * 60 // 60 seconds in a minute
 		}
@@ -74,7 +71,7 @@ func ParseDurationString(durationStr string) (float64, bool) {
 	if matches[9] != "" {
 		seconds, err := strconv.ParseFloat(matches[9], 64)
 		if err != nil {
-			log.Printf("Warning: failed to parse seconds value '%s': %v", matches[9], err)
+			slog.Warn("Failed to parse seconds of duration", "value", matches[9], "err", err)
 		} else {
 			totalSeconds += seconds
 		}
@@ -86,13 +83,6 @@ func ParseDurationString(durationStr string) (float64, bool) {
 	return totalSeconds, true
 }
 
-// DebugLog logs debug messages if DebugMode is enabled
-func DebugLog(format string, args ...interface{}) {
-	if DebugMode {
-		log.Printf("[DEBUG] "+format, args...)
-	}
-}
-
 // SanitizeMetricName sanitizes metric names for Prometheus
 func SanitizeMetricName(name string) string {
 	// Replace invalid characters with underscores
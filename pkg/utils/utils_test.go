@@ -153,24 +153,6 @@ func TestParseDurationString(t *testing.T) {
 	}
 }
 
-// TestDebugLog tests the DebugLog function
-func TestDebugLog(t *testing.T) {
-	// Test with debug mode off
-	DebugMode = false
-	assert.NotPanics(t, func() {
-		DebugLog("Test message %d", 123)
-	})
-
-	// Test with debug mode on
-	DebugMode = true
-	assert.NotPanics(t, func() {
-		DebugLog("Test message %d", 123)
-	})
-
-	// Reset debug mode
-	DebugMode = false
-}
-
 // TestSanitizeMetricName tests the SanitizeMetricName function
 func TestSanitizeMetricName(t *testing.T) {
 	tests := []struct {
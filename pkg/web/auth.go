@@ -0,0 +1,83 @@
+package web
+
+import (
+	"crypto/sha256"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyHash is compared against for unknown users, so that the response time
+// doesn't reveal which users exist
+const dummyHash = "$2a$10$W5rfmBCiiTrtIkjX5dzwb.fN8FnKfgZewVcl/roNlL3ITsv7RgLzq"
+
+func validateHash(hash string) error {
+	_, err := bcrypt.Cost([]byte(hash))
+	return err
+}
+
+// authCache remembers credentials that matched a bcrypt hash, so that every
+// scrape doesn't pay for a bcrypt comparison. Only successes are cached and
+// the key covers the hash, so changed hashes invalidate the entries.
+type authCache struct {
+	mu    sync.Mutex
+	valid map[[sha256.Size]byte]bool
+}
+
+func cacheKey(hash, secret string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(hash + "\x00" + secret))
+}
+
+// compare reports whether secret matches the bcrypt hash
+func (a *authCache) compare(hash, secret string) bool {
+	key := cacheKey(hash, secret)
+
+	a.mu.Lock()
+	cached := a.valid[key]
+	a.mu.Unlock()
+	if cached {
+		return true
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(secret)) != nil {
+		return false
+	}
+	a.mu.Lock()
+	if a.valid == nil {
+		a.valid = make(map[[sha256.Size]byte]bool)
+	}
+	a.valid[key] = true
+	a.mu.Unlock()
+	return true
+}
+
+// checkBasic verifies basic authentication credentials
+func (a *authCache) checkBasic(cfg *Config, user, password string) bool {
+	hash, exists := cfg.Users[user]
+	if !exists {
+		a.compare(dummyHash, password)
+		return false
+	}
+	return a.compare(hash, password)
+}
+
+// checkBearer verifies a bearer token against every configured token
+func (a *authCache) checkBearer(cfg *Config, token string) bool {
+	for _, hash := range cfg.BearerTokens {
+		if a.compare(hash, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken extracts the token of an "Authorization: Bearer" header
+func bearerToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,220 @@
+package web
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is a web configuration file in the format of the Prometheus
+// exporter-toolkit, extended with bearer tokens
+type Config struct {
+	TLSConfig  TLSConfig         `yaml:"tls_server_config"`
+	HTTPConfig HTTPConfig        `yaml:"http_server_config"`
+	Users      map[string]string `yaml:"basic_auth_users"` // bcrypt password hashes by user name
+	// BearerTokens are bcrypt hashes of the tokens accepted in
+	// "Authorization: Bearer <token>" headers
+	BearerTokens []string `yaml:"bearer_tokens"`
+}
+
+// TLSConfig configures the TLS server
+type TLSConfig struct {
+	CertFile          string   `yaml:"cert_file"`
+	KeyFile           string   `yaml:"key_file"`
+	Cert              string   `yaml:"cert"` // Inline PEM certificate, instead of cert_file
+	Key               string   `yaml:"key"`  // Inline PEM key, instead of key_file
+	ClientAuth        string   `yaml:"client_auth_type"`
+	ClientCAFile      string   `yaml:"client_ca_file"`
+	ClientCA          string   `yaml:"client_ca"` // Inline PEM client CAs, instead of client_ca_file
+	ClientAllowedSans []string `yaml:"client_allowed_sans"`
+	CipherSuites      []string `yaml:"cipher_suites"`
+	CurvePreferences  []string `yaml:"curve_preferences"`
+	MinVersion        string   `yaml:"min_version"`
+	MaxVersion        string   `yaml:"max_version"`
+	// PreferServerCipherSuites is accepted for compatibility, Go ignores it
+	PreferServerCipherSuites bool `yaml:"prefer_server_cipher_suites"`
+}
+
+// HTTPConfig configures the HTTP server
+type HTTPConfig struct {
+	HTTP2   *bool             `yaml:"http2"`   // Enabled unless false
+	Headers map[string]string `yaml:"headers"` // Added to every response
+}
+
+// Headers the configuration can't set, they are managed by the server
+var reservedHeaders = map[string]bool{
+	"Authorization":     true,
+	"Content-Length":    true,
+	"Content-Type":      true,
+	"Date":              true,
+	"Transfer-Encoding": true,
+	"Www-Authenticate":  true,
+}
+
+// TLSEnabled reports whether the configuration enables TLS
+func (c *Config) TLSEnabled() bool {
+	t := c.TLSConfig
+	return t.CertFile != "" || t.Cert != "" || t.KeyFile != "" || t.Key != ""
+}
+
+// HTTP2Enabled reports whether HTTP/2 is offered over TLS
+func (c *Config) HTTP2Enabled() bool {
+	return c.HTTPConfig.HTTP2 == nil || *c.HTTPConfig.HTTP2
+}
+
+// AuthEnabled reports whether requests have to authenticate
+func (c *Config) AuthEnabled() bool {
+	return len(c.Users) > 0 || len(c.BearerTokens) > 0
+}
+
+// validate checks the configuration, resolving relative paths against dir
+func (c *Config) validate(dir string) error {
+	t := &c.TLSConfig
+	for _, path := range []*string{&t.CertFile, &t.KeyFile, &t.ClientCAFile} {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+
+	if c.TLSEnabled() {
+		if (t.CertFile == "") == (t.Cert == "") {
+			return errors.New("exactly one of cert_file and cert must be set")
+		}
+		if (t.KeyFile == "") == (t.Key == "") {
+			return errors.New("exactly one of key_file and key must be set")
+		}
+	} else if t.ClientAuth != "" || t.ClientCAFile != "" || t.ClientCA != "" {
+		return errors.New("client authentication requires a server certificate")
+	}
+	if t.ClientCAFile != "" && t.ClientCA != "" {
+		return errors.New("at most one of client_ca_file and client_ca must be set")
+	}
+
+	clientAuth, err := parseClientAuth(t.ClientAuth)
+	if err != nil {
+		return err
+	}
+	if clientAuth >= tls.VerifyClientCertIfGiven && t.ClientCAFile == "" && t.ClientCA == "" {
+		return fmt.Errorf("client_auth_type %s requires client CAs", t.ClientAuth)
+	}
+	// The SANs are checked on the verified chain, which the other client
+	// auth types don't build
+	if len(t.ClientAllowedSans) > 0 && clientAuth != tls.VerifyClientCertIfGiven && clientAuth != tls.RequireAndVerifyClientCert {
+		return errors.New("client_allowed_sans requires client_auth_type VerifyClientCertIfGiven or RequireAndVerifyClientCert")
+	}
+	if _, err := parseTLSVersion(t.MinVersion); err != nil {
+		return err
+	}
+	if _, err := parseTLSVersion(t.MaxVersion); err != nil {
+		return err
+	}
+	if _, err := parseCipherSuites(t.CipherSuites); err != nil {
+		return err
+	}
+	if _, err := parseCurves(t.CurvePreferences); err != nil {
+		return err
+	}
+
+	for name := range c.HTTPConfig.Headers {
+		if reservedHeaders[http.CanonicalHeaderKey(name)] {
+			return fmt.Errorf("header %s can't be configured", name)
+		}
+	}
+
+	for user, hash := range c.Users {
+		if err := validateHash(hash); err != nil {
+			return fmt.Errorf("password of user %s: %v", user, err)
+		}
+	}
+	for i, hash := range c.BearerTokens {
+		if err := validateHash(hash); err != nil {
+			return fmt.Errorf("bearer token %d: %v", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// parseConfig decodes and validates a web configuration. Unknown fields are
+// rejected like the exporter-toolkit does.
+func parseConfig(content []byte, dir string) (*Config, error) {
+	cfg := &Config{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if err := cfg.validate(dir); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadConfig reads and validates a web configuration file
+func LoadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := parseConfig(content, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("invalid web config %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// configFile is a web configuration file reloaded when it changes. A file
+// that becomes invalid keeps the last valid configuration in effect.
+type configFile struct {
+	path string
+
+	mu      sync.Mutex
+	cfg     *Config
+	modTime time.Time
+	size    int64
+}
+
+func newConfigFile(path string) (*configFile, error) {
+	f := &configFile{path: path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	f.cfg, f.modTime, f.size = cfg, info.ModTime(), info.Size()
+	return f, nil
+}
+
+// get returns the current configuration, reloading the file if it changed
+func (f *configFile) get() *Config {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil || (info.ModTime().Equal(f.modTime) && info.Size() == f.size) {
+		return f.cfg
+	}
+	f.modTime, f.size = info.ModTime(), info.Size()
+
+	cfg, err := LoadConfig(f.path)
+	if err != nil {
+		slog.Warn("Keeping previous web config", "path", f.path, "err", err)
+		return f.cfg
+	}
+	slog.Info("Reloaded web config", "path", f.path)
+	f.cfg = cfg
+	return cfg
+}
@@ -0,0 +1,114 @@
+package web
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bcrypt hashes of "secret" and "s3cr3t-token"
+const (
+	secretHash = "$2a$04$iQnKcERO.vwHX2PBwGhwpO5QU5MhCfH9d5vOzJy0TWm8P.Y0kHrDC"
+	tokenHash  = "$2a$04$9J7k7rF5olqlAJCrSJZizOGcEGF1TIJFK.R6gI7aFwdA8Hne2TM5."
+)
+
+// TestParseConfig tests parsing of a complete web config
+func TestParseConfig(t *testing.T) {
+	cfg, err := parseConfig([]byte(`
+tls_server_config:
+  cert_file: server.crt
+  key_file: /etc/knot-exporter/server.key
+  client_auth_type: RequireAndVerifyClientCert
+  client_ca_file: ca.crt
+  client_allowed_sans: [prometheus.example.com]
+  min_version: TLS13
+  curve_preferences: [X25519]
+http_server_config:
+  http2: false
+  headers:
+    Strict-Transport-Security: max-age=31536000
+basic_auth_users:
+  prometheus: `+secretHash+`
+bearer_tokens:
+  - `+tokenHash+`
+`), "/etc/knot-exporter")
+	require.NoError(t, err)
+
+	assert.Equal(t, "/etc/knot-exporter/server.crt", cfg.TLSConfig.CertFile)
+	assert.Equal(t, "/etc/knot-exporter/server.key", cfg.TLSConfig.KeyFile)
+	assert.Equal(t, "/etc/knot-exporter/ca.crt", cfg.TLSConfig.ClientCAFile)
+	assert.True(t, cfg.TLSEnabled())
+	assert.True(t, cfg.AuthEnabled())
+	assert.False(t, cfg.HTTP2Enabled())
+	assert.Equal(t, map[string]string{"Strict-Transport-Security": "max-age=31536000"}, cfg.HTTPConfig.Headers)
+}
+
+// TestParseConfigEmpty tests that an empty file configures plain HTTP
+func TestParseConfigEmpty(t *testing.T) {
+	cfg, err := parseConfig(nil, "/")
+	require.NoError(t, err)
+	assert.False(t, cfg.TLSEnabled())
+	assert.False(t, cfg.AuthEnabled())
+	assert.True(t, cfg.HTTP2Enabled())
+}
+
+// TestParseConfigInvalid tests rejection of invalid configurations
+func TestParseConfigInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":             "tls_server_config:\n  cert: x\n  key: y\n  unknown: z\n",
+		"missing key":               "tls_server_config:\n  cert_file: a.crt\n",
+		"cert and file":             "tls_server_config:\n  cert_file: a.crt\n  cert: x\n  key_file: a.key\n",
+		"client auth":               "tls_server_config:\n  client_auth_type: RequireAnyClientCert\n",
+		"missing client ca":         "tls_server_config:\n  cert_file: a.crt\n  key_file: a.key\n  client_auth_type: RequireAndVerifyClientCert\n",
+		"sans without verification": "tls_server_config:\n  cert_file: a.crt\n  key_file: a.key\n  client_ca: x\n  client_auth_type: RequireAnyClientCert\n  client_allowed_sans: [a]\n",
+		"sans without client auth":  "tls_server_config:\n  cert_file: a.crt\n  key_file: a.key\n  client_ca: x\n  client_allowed_sans: [a]\n",
+		"auth type":                 "tls_server_config:\n  cert_file: a.crt\n  key_file: a.key\n  client_auth_type: Always\n",
+		"tls version":               "tls_server_config:\n  cert_file: a.crt\n  key_file: a.key\n  min_version: SSL3\n",
+		"cipher suite":              "tls_server_config:\n  cert_file: a.crt\n  key_file: a.key\n  cipher_suites: [TLS_RSA_WITH_RC4_128_SHA]\n",
+		"curve":                     "tls_server_config:\n  cert_file: a.crt\n  key_file: a.key\n  curve_preferences: [P-128]\n",
+		"reserved header":           "http_server_config:\n  headers:\n    content-type: text/plain\n",
+		"password hash":             "basic_auth_users:\n  prometheus: secret\n",
+		"token hash":                "bearer_tokens: [token]\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseConfig([]byte(content), "/")
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestConfigFileReload tests that changes are picked up and invalid changes
+// keep the previous configuration
+func TestConfigFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "web.yml")
+	require.NoError(t, os.WriteFile(path, []byte("basic_auth_users:\n  a: "+secretHash+"\n"), 0o600))
+
+	file, err := newConfigFile(path)
+	require.NoError(t, err)
+	first := file.get()
+	assert.Contains(t, first.Users, "a")
+	assert.Same(t, first, file.get())
+
+	writeLater(t, path, "basic_auth_users:\n  b: "+secretHash+"\n")
+	second := file.get()
+	assert.NotContains(t, second.Users, "a")
+	assert.Contains(t, second.Users, "b")
+
+	writeLater(t, path, "basic_auth_users: [broken\n")
+	assert.Same(t, second, file.get())
+}
+
+// writeLater overwrites a file with a modification time distinct from its
+// previous one
+func writeLater(t *testing.T, path, content string) {
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	later := info.ModTime().Add(time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+}
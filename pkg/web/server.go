@@ -0,0 +1,117 @@
+package web
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+)
+
+// handler enforces the authentication and adds the headers of the current
+// web configuration
+type handler struct {
+	config func() *Config
+	auth   authCache
+	next   http.Handler
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config()
+	for name, value := range cfg.HTTPConfig.Headers {
+		w.Header().Set(name, value)
+	}
+
+	if cfg.AuthEnabled() && !h.authenticated(cfg, r) {
+		if len(cfg.Users) > 0 {
+			w.Header().Add("WWW-Authenticate", `Basic realm="knot-exporter"`)
+		}
+		if len(cfg.BearerTokens) > 0 {
+			w.Header().Add("WWW-Authenticate", `Bearer realm="knot-exporter"`)
+		}
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	h.next.ServeHTTP(w, r)
+}
+
+func (h *handler) authenticated(cfg *Config, r *http.Request) bool {
+	if user, password, ok := r.BasicAuth(); ok {
+		return h.auth.checkBasic(cfg, user, password)
+	}
+	if token, ok := bearerToken(r.Header.Get("Authorization")); ok {
+		return h.auth.checkBearer(cfg, token)
+	}
+	return false
+}
+
+// Server is an HTTP server protected according to a web configuration file
+type Server struct {
+	*http.Server
+	tls bool
+}
+
+// NewServer configures server according to the web configuration file at
+// path, in the Prometheus exporter-toolkit format, or serves plain HTTP if
+// path is empty. The handler of the server gets wrapped to enforce
+// authentication, and TLS is set up if the file configures it. The file and
+// the certificates are reloaded when they change on disk; switching TLS on or
+// off and HTTP/2 changes require a restart.
+func NewServer(server *http.Server, path string) (*Server, error) {
+	if path == "" {
+		return &Server{Server: server}, nil
+	}
+
+	file, err := newConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	next := server.Handler
+	if next == nil {
+		next = http.DefaultServeMux
+	}
+	server.Handler = &handler{config: file.get, next: next}
+
+	cfg := file.cfg
+	if !cfg.TLSEnabled() {
+		return &Server{Server: server}, nil
+	}
+
+	loader := &tlsLoader{config: file.get, http2: cfg.HTTP2Enabled()}
+	if _, err := loader.configForClient(nil); err != nil {
+		return nil, err
+	}
+	server.TLSConfig = &tls.Config{GetConfigForClient: loader.configForClient}
+	if !loader.http2 {
+		// A non-nil empty map disables HTTP/2
+		server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+	return &Server{Server: server, tls: true}, nil
+}
+
+// TLS reports whether the server uses TLS
+func (s *Server) TLS() bool {
+	return s.tls
+}
+
+// Serve accepts connections on l, using TLS if configured. It may be called
+// for several listeners at once.
+func (s *Server) Serve(l net.Listener) error {
+	if s.tls {
+		return s.Server.ServeTLS(l, "", "")
+	}
+	return s.Server.Serve(l)
+}
+
+// ListenAndServe listens on the TCP address of the server and serves it
+func (s *Server) ListenAndServe() error {
+	addr := s.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
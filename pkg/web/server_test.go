@@ -0,0 +1,275 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCert is a certificate with its PEM encoding
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert issues a certificate for the names, self-signed if parent is nil
+func newTestCert(t *testing.T, parent *testCert, isCA bool, names ...string) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: names[0]},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		DNSNames:              names,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	cert, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	require.NoError(t, err)
+	return cert
+}
+
+// startServer serves a handler answering "ok" with the web config content,
+// returning its address
+func startServer(t *testing.T, dir, content string) string {
+	path := filepath.Join(dir, "web.yml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	server, err := NewServer(&http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})}, path)
+	require.NoError(t, err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(l)
+	t.Cleanup(func() { server.Close() })
+	return l.Addr().String()
+}
+
+func get(t *testing.T, client *http.Client, url string, setup func(*http.Request)) *http.Response {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	if setup != nil {
+		setup(req)
+	}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp
+}
+
+// TestServerAuth tests basic and bearer authentication over plain HTTP
+func TestServerAuth(t *testing.T) {
+	addr := startServer(t, t.TempDir(), `
+basic_auth_users:
+  prometheus: `+secretHash+`
+bearer_tokens: [`+tokenHash+`]
+http_server_config:
+  headers:
+    X-Frame-Options: deny
+`)
+	url := "http://" + addr + "/metrics"
+	client := http.DefaultClient
+
+	resp := get(t, client, url, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, []string{`Basic realm="knot-exporter"`, `Bearer realm="knot-exporter"`},
+		resp.Header.Values("WWW-Authenticate"))
+	assert.Equal(t, "deny", resp.Header.Get("X-Frame-Options"))
+
+	tests := map[string]struct {
+		setup func(*http.Request)
+		code  int
+	}{
+		"basic":          {func(r *http.Request) { r.SetBasicAuth("prometheus", "secret") }, http.StatusOK},
+		"wrong password": {func(r *http.Request) { r.SetBasicAuth("prometheus", "guess") }, http.StatusUnauthorized},
+		"unknown user":   {func(r *http.Request) { r.SetBasicAuth("root", "secret") }, http.StatusUnauthorized},
+		"bearer":         {func(r *http.Request) { r.Header.Set("Authorization", "Bearer s3cr3t-token") }, http.StatusOK},
+		"wrong token":    {func(r *http.Request) { r.Header.Set("Authorization", "Bearer secret") }, http.StatusUnauthorized},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Twice to go through the cache of verified credentials
+			for range 2 {
+				resp := get(t, client, url, test.setup)
+				assert.Equal(t, test.code, resp.StatusCode)
+				assert.Equal(t, "deny", resp.Header.Get("X-Frame-Options"))
+			}
+		})
+	}
+}
+
+// TestServerTLS tests serving TLS and reloading the certificate on change
+func TestServerTLS(t *testing.T) {
+	dir := t.TempDir()
+	first := newTestCert(t, nil, true, "localhost")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "server.crt"), first.certPEM, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "server.key"), first.keyPEM, 0o600))
+
+	addr := startServer(t, dir, "tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n")
+
+	roots := x509.NewCertPool()
+	roots.AddCert(first.cert)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+		ForceAttemptHTTP2: true,
+	}}
+	resp := get(t, client, "https://"+addr+"/", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, resp.ProtoMajor)
+
+	resp = get(t, http.DefaultClient, "http://"+addr+"/", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// The new certificate is served to new connections
+	second := newTestCert(t, nil, true, "localhost")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "server.key"), second.keyPEM, 0o600))
+	writeLater(t, filepath.Join(dir, "server.crt"), string(second.certPEM))
+
+	// Every request makes a new connection, so that each sees the certificate
+	// in use
+	var served *x509.Certificate
+	client = &http.Client{Transport: &http.Transport{DisableKeepAlives: true, TLSClientConfig: &tls.Config{
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			served = cs.PeerCertificates[0]
+			return nil
+		},
+	}}}
+	get(t, client, "https://"+addr+"/", nil)
+	require.NotNil(t, served)
+	assert.Equal(t, second.cert.SerialNumber, served.SerialNumber)
+
+	// A broken certificate keeps the previous one in use
+	writeLater(t, filepath.Join(dir, "server.crt"), "broken")
+	served = nil
+	get(t, client, "https://"+addr+"/", nil)
+	require.NotNil(t, served)
+	assert.Equal(t, second.cert.SerialNumber, served.SerialNumber)
+}
+
+// TestServerMutualTLS tests verification of client certificates
+func TestServerMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, nil, true, "Test CA")
+	server := newTestCert(t, ca, false, "localhost")
+	allowed := newTestCert(t, ca, false, "prometheus.example.com")
+	other := newTestCert(t, ca, false, "other.example.com")
+	untrusted := newTestCert(t, nil, true, "prometheus.example.com")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.crt"), ca.certPEM, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "server.crt"), server.certPEM, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "server.key"), server.keyPEM, 0o600))
+	addr := startServer(t, dir, `
+tls_server_config:
+  cert_file: server.crt
+  key_file: server.key
+  client_auth_type: RequireAndVerifyClientCert
+  client_ca_file: ca.crt
+  client_allowed_sans: [prometheus.example.com]
+http_server_config:
+  http2: false
+`)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	request := func(client *testCert) (*http.Response, error) {
+		config := &tls.Config{RootCAs: roots}
+		if client != nil {
+			config.Certificates = []tls.Certificate{client.tlsCertificate(t)}
+		}
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: config, ForceAttemptHTTP2: true}}
+		resp, err := c.Get("https://" + addr + "/")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	resp, err := request(allowed)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, resp.ProtoMajor)
+
+	for name, client := range map[string]*testCert{"none": nil, "other SAN": other, "untrusted": untrusted} {
+		t.Run(name, func(t *testing.T) {
+			_, err := request(client)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestNewServerInvalid tests that unusable configurations fail at startup
+func TestNewServerInvalid(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewServer(&http.Server{}, filepath.Join(dir, "missing.yml"))
+	assert.Error(t, err)
+
+	path := filepath.Join(dir, "web.yml")
+	require.NoError(t, os.WriteFile(path, []byte("tls_server_config:\n  cert_file: missing.crt\n  key_file: missing.key\n"), 0o600))
+	_, err = NewServer(&http.Server{}, path)
+	assert.Error(t, err)
+}
+
+// TestNewServerPlain tests serving without a web config on several listeners
+func TestNewServerPlain(t *testing.T) {
+	server, err := NewServer(&http.Server{Handler: http.NotFoundHandler()}, "")
+	require.NoError(t, err)
+	assert.False(t, server.TLS())
+	defer server.Close()
+
+	for range 2 {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		go server.Serve(l)
+
+		resp := get(t, http.DefaultClient, "http://"+l.Addr().String()+"/", nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}
+}
@@ -0,0 +1,259 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                           tls.NoClientCert,
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"RequireClientCert":          tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+var tlsVersions = map[string]uint16{
+	"TLS10": tls.VersionTLS10,
+	"TLS11": tls.VersionTLS11,
+	"TLS12": tls.VersionTLS12,
+	"TLS13": tls.VersionTLS13,
+}
+
+var curves = map[string]tls.CurveID{
+	"CurveP256": tls.CurveP256,
+	"CurveP384": tls.CurveP384,
+	"CurveP521": tls.CurveP521,
+	"X25519":    tls.X25519,
+}
+
+func parseClientAuth(name string) (tls.ClientAuthType, error) {
+	auth, ok := clientAuthTypes[name]
+	if !ok {
+		return 0, fmt.Errorf("invalid client_auth_type %q", name)
+	}
+	return auth, nil
+}
+
+// parseTLSVersion returns the TLS version by its name, or 0 for the default
+func parseTLSVersion(name string) (uint16, error) {
+	if name == "" {
+		return 0, nil
+	}
+	version, ok := tlsVersions[name]
+	if !ok {
+		return 0, fmt.Errorf("invalid TLS version %q", name)
+	}
+	return version, nil
+}
+
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	byName := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		byName[suite.Name] = suite.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func parseCurves(names []string) ([]tls.CurveID, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	ids := make([]tls.CurveID, 0, len(names))
+	for _, name := range names {
+		id, ok := curves[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown curve %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// fileStamp identifies a version of a file on disk
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stampFile(path string) fileStamp {
+	if path == "" {
+		return fileStamp{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{info.ModTime(), info.Size()}
+}
+
+// tlsState is the server TLS configuration built from a Config. It is rebuilt
+// when the configuration or any of the certificate files change.
+type tlsState struct {
+	cfg                 *Config
+	cert, key, clientCA fileStamp
+	tlsConfig           *tls.Config
+}
+
+// tlsLoader builds TLS configurations for new connections
+type tlsLoader struct {
+	config func() *Config
+	http2  bool // Fixed when the server starts
+
+	mu    sync.Mutex
+	state *tlsState
+}
+
+// configForClient is the tls.Config GetConfigForClient hook. Certificates
+// that fail to load keep the previous ones in use.
+func (l *tlsLoader) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	cfg := l.config()
+	t := cfg.TLSConfig
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state := &tlsState{
+		cfg:      cfg,
+		cert:     stampFile(t.CertFile),
+		key:      stampFile(t.KeyFile),
+		clientCA: stampFile(t.ClientCAFile),
+	}
+	if l.state != nil && l.state.cfg == state.cfg && l.state.cert == state.cert &&
+		l.state.key == state.key && l.state.clientCA == state.clientCA {
+		return l.state.tlsConfig, nil
+	}
+
+	tlsConfig, err := newTLSConfig(cfg, l.http2)
+	if err != nil {
+		if l.state == nil {
+			return nil, err
+		}
+		slog.Warn("Keeping previous TLS configuration", "err", err)
+		return l.state.tlsConfig, nil
+	}
+	state.tlsConfig = tlsConfig
+	l.state = state
+	return tlsConfig, nil
+}
+
+// newTLSConfig builds the TLS configuration of a Config, loading the
+// certificates from disk
+func newTLSConfig(cfg *Config, http2 bool) (*tls.Config, error) {
+	t := cfg.TLSConfig
+	if !cfg.TLSEnabled() {
+		return nil, errors.New("TLS is not configured")
+	}
+
+	certPEM, keyPEM := []byte(t.Cert), []byte(t.Key)
+	var err error
+	if t.CertFile != "" {
+		if certPEM, err = os.ReadFile(t.CertFile); err != nil {
+			return nil, fmt.Errorf("failed to read certificate: %v", err)
+		}
+	}
+	if t.KeyFile != "" {
+		if keyPEM, err = os.ReadFile(t.KeyFile); err != nil {
+			return nil, fmt.Errorf("failed to read key: %v", err)
+		}
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %v", err)
+	}
+
+	// The configuration was validated when loaded
+	clientAuth, _ := parseClientAuth(t.ClientAuth)
+	minVersion, _ := parseTLSVersion(t.MinVersion)
+	maxVersion, _ := parseTLSVersion(t.MaxVersion)
+	cipherSuites, _ := parseCipherSuites(t.CipherSuites)
+	curvePreferences, _ := parseCurves(t.CurvePreferences)
+	if minVersion == 0 {
+		minVersion = tls.VersionTLS12
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates:     []tls.Certificate{cert},
+		ClientAuth:       clientAuth,
+		MinVersion:       minVersion,
+		MaxVersion:       maxVersion,
+		CipherSuites:     cipherSuites,
+		CurvePreferences: curvePreferences,
+	}
+	if http2 {
+		tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+	} else {
+		tlsConfig.NextProtos = []string{"http/1.1"}
+	}
+
+	if t.ClientCAFile != "" || t.ClientCA != "" {
+		caPEM := []byte(t.ClientCA)
+		if t.ClientCAFile != "" {
+			if caPEM, err = os.ReadFile(t.ClientCAFile); err != nil {
+				return nil, fmt.Errorf("failed to read client CAs: %v", err)
+			}
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("no client CA certificates found")
+		}
+		tlsConfig.ClientCAs = pool
+	}
+
+	if len(t.ClientAllowedSans) > 0 {
+		allowed := t.ClientAllowedSans
+		tlsConfig.VerifyPeerCertificate = func(_ [][]byte, chains [][]*x509.Certificate) error {
+			if len(chains) == 0 {
+				return nil // No client certificate, handled by ClientAuth
+			}
+			if hasAllowedSAN(chains[0][0], allowed) {
+				return nil
+			}
+			return errors.New("client certificate SAN not allowed")
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// hasAllowedSAN reports whether any Subject Alternative Name of the
+// certificate is in the allowed list
+func hasAllowedSAN(cert *x509.Certificate, allowed []string) bool {
+	var sans []string
+	sans = append(sans, cert.DNSNames...)
+	sans = append(sans, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+
+	for _, san := range sans {
+		for _, a := range allowed {
+			if san == a {
+				return true
+			}
+		}
+	}
+	return false
+}